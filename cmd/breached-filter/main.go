@@ -0,0 +1,132 @@
+// Command breached-filter builds the breached password filter the user service screens passwords with.
+//
+// The input has one breached password per line, either as the hex SHA-1 hash with an optional
+// ":count" suffix, the form Have I Been Pwned publishes its corpus in, or in plain text with -plain:
+//
+//	breached-filter -in pwned-passwords-sha1.txt -out breached.bin -fp 0.001
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/breached"
+	"os"
+	"strings"
+)
+
+func main() {
+	in := flag.String("in", "", "breached password file")
+	out := flag.String("out", "breached.bin", "filter file to write")
+	fpRate := flag.Float64("fp", 0.001, "false positive rate of the filter")
+	plain := flag.Bool("plain", false, "the input has plain text passwords instead of SHA-1 hashes")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := run(*in, *out, *fpRate, *plain)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "breached-filter:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string, fpRate float64, plain bool) error {
+	n, err := countLines(in)
+	if err != nil {
+		return err
+	}
+
+	filter, err := breached.NewFilter(n, fpRate)
+	if err != nil {
+		return err
+	}
+
+	err = eachLine(in, func(line string) error {
+		if plain {
+			filter.Add(line)
+
+			return nil
+		}
+
+		digest, err := parseSHA1(line)
+		if err != nil {
+			return err
+		}
+		filter.AddSHA1(digest)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	_, err = filter.WriteTo(w)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func countLines(path string) (uint64, error) {
+	var n uint64
+	err := eachLine(path, func(string) error {
+		n++
+
+		return nil
+	})
+
+	return n, err
+}
+
+// eachLine calls fn with every non-empty line of the file
+func eachLine(path string, fn func(line string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		err = fn(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseSHA1 parses a "HASH" or "HASH:count" line
+func parseSHA1(line string) ([sha1.Size]byte, error) {
+	var digest [sha1.Size]byte
+
+	hash, _, _ := strings.Cut(line, ":")
+
+	b, err := hex.DecodeString(hash)
+	if err != nil || len(b) != sha1.Size {
+		return digest, fmt.Errorf("invalid sha-1 hash %q", hash)
+	}
+	copy(digest[:], b)
+
+	return digest, nil
+}
@@ -0,0 +1,62 @@
+package dto
+
+import (
+	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+func FromCreateApiKeyRequest(req *authsvc.CreateApiKeyRequest) model.APIKeyCreateData {
+	return model.APIKeyCreateData{
+		Name:         req.Name,
+		UserID:       req.UserId,
+		Organization: req.Organization,
+		Scopes:       req.Scopes,
+		ExpiresAt:    fromOptionalTimestamp(req.ExpiresAt),
+	}
+}
+
+// FromUpdateApiKeyRequest leaves the scopes unchanged when none are given
+func FromUpdateApiKeyRequest(req *authsvc.UpdateApiKeyRequest) model.APIKeyUpdateData {
+	data := model.APIKeyUpdateData{
+		ExpiresAt: fromOptionalTimestamp(req.ExpiresAt),
+	}
+	if len(req.Scopes) > 0 {
+		data.Scopes = &req.Scopes
+	}
+
+	return data
+}
+
+func ToApiKeyProto(key model.APIKey) *authsvc.ApiKey {
+	return &authsvc.ApiKey{
+		Id:           key.ID,
+		Name:         key.Name,
+		Prefix:       key.Prefix,
+		UserId:       key.UserID,
+		Organization: key.Organization,
+		Scopes:       key.Scopes,
+		ExpiresAt:    toOptionalTimestamp(key.ExpiresAt),
+		RevokedAt:    toOptionalTimestamp(key.RevokedAt),
+		CreatedAt:    timestamppb.New(key.CreatedAt),
+	}
+}
+
+func fromOptionalTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+
+	t := ts.AsTime()
+
+	return &t
+}
+
+func toOptionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
@@ -1,8 +1,11 @@
 package dto
 
 import (
+	"context"
 	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"google.golang.org/grpc/metadata"
+	"strings"
 	"time"
 )
 
@@ -38,3 +41,102 @@ func FromLoginRequest(req *authsvc.LoginRequest) model.Credentials {
 
 	return cred
 }
+
+func FromLoginWithCodeRequest(req *authsvc.LoginWithCodeRequest) model.LoginCodeCredentials {
+	var cred model.LoginCodeCredentials
+	if req.Email != nil {
+		cred.Email = *req.Email
+	}
+	if req.Code != nil {
+		cred.Code = *req.Code
+	}
+	if req.LinkToken != nil {
+		cred.LinkToken = *req.LinkToken
+	}
+
+	return cred
+}
+
+func FromReauthenticateRequest(req *authsvc.ReauthenticateRequest) model.ReauthenticationData {
+	var data model.ReauthenticationData
+	if req.Password != nil {
+		data.Password = *req.Password
+	}
+	if req.Code != nil {
+		data.Code = *req.Code
+	}
+
+	return data
+}
+
+func FromChangePasswordRequest(req *authsvc.ChangePasswordRequest) model.PasswordChangeData {
+	return model.PasswordChangeData{
+		CurrentPassword:      req.CurrentPassword,
+		Password:             req.Password,
+		PasswordConfirmation: req.PasswordConfirmation,
+	}
+}
+
+// ToLoginResponse returns either the tokens, the MFA challenge or the password change challenge of the login
+func ToLoginResponse(result model.LoginResult) *authsvc.LoginResponse {
+	if result.MFAChallenge != nil {
+		return &authsvc.LoginResponse{
+			MfaToken:              &result.MFAChallenge.Token,
+			MfaTokenExpiresIn:     &result.MFAChallenge.ExpiresIn,
+			MfaEnrollmentRequired: &result.MFAChallenge.EnrollmentRequired,
+		}
+	}
+	if result.PasswordChangeChallenge != nil {
+		return &authsvc.LoginResponse{
+			PasswordChangeToken:          &result.PasswordChangeChallenge.Token,
+			PasswordChangeTokenExpiresIn: &result.PasswordChangeChallenge.ExpiresIn,
+		}
+	}
+
+	return &authsvc.LoginResponse{
+		AccessToken:           &result.Token.AccessToken,
+		AccessTokenExpiresIn:  &result.Token.AccessTokenExpiresIn,
+		RefreshToken:          &result.Token.RefreshToken,
+		RefreshTokenExpiresIn: &result.Token.RefreshTokenExpiresIn,
+	}
+}
+
+// ToVerifyMfaResponse returns either the tokens or the password change challenge of the login
+func ToVerifyMfaResponse(result model.LoginResult) *authsvc.VerifyMfaResponse {
+	if result.PasswordChangeChallenge != nil {
+		return &authsvc.VerifyMfaResponse{
+			PasswordChangeToken:          &result.PasswordChangeChallenge.Token,
+			PasswordChangeTokenExpiresIn: &result.PasswordChangeChallenge.ExpiresIn,
+		}
+	}
+
+	return &authsvc.VerifyMfaResponse{
+		AccessToken:           &result.Token.AccessToken,
+		AccessTokenExpiresIn:  &result.Token.AccessTokenExpiresIn,
+		RefreshToken:          &result.Token.RefreshToken,
+		RefreshTokenExpiresIn: &result.Token.RefreshTokenExpiresIn,
+	}
+}
+
+func ToJWKProto(key model.JSONWebKey) *authsvc.JWK {
+	return &authsvc.JWK{
+		Kid: key.KeyID,
+		Kty: key.KeyType,
+		Alg: key.Algorithm,
+		Use: key.Use,
+		Crv: key.Curve,
+		X:   key.X,
+		N:   key.N,
+		E:   key.E,
+	}
+}
+
+// AccessTokenFromContext returns the bearer token of the request or an empty string
+func AccessTokenFromContext(ctx context.Context) string {
+	authorization := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(authorization) < 1 {
+		return ""
+	}
+
+	return strings.TrimPrefix(authorization[0], "Bearer ")
+}
@@ -0,0 +1,18 @@
+package dto
+
+import (
+	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func ToKnownDeviceProto(device model.KnownDevice) *authsvc.KnownDevice {
+	return &authsvc.KnownDevice{
+		Id:         device.ID,
+		DeviceName: device.DeviceName,
+		UserAgent:  device.UserAgent,
+		IpRange:    device.IPRange,
+		CreatedAt:  timestamppb.New(device.CreatedAt),
+		LastSeenAt: timestamppb.New(device.LastSeenAt),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func validationError(ve model.ValidationErrors) error {
@@ -26,26 +27,88 @@ func validationError(ve model.ValidationErrors) error {
 	return st.Err()
 }
 
+func lockoutError(le model.LockoutError) error {
+	st := status.New(codes.ResourceExhausted, le.Error())
+
+	st, _ = st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(le.RetryAfter),
+	})
+
+	return st.Err()
+}
+
+// reauthenticationError tells clients apart from other authentication failures
+// that the token is valid but the user has to reauthenticate first
+func reauthenticationError(err error) error {
+	st := status.New(codes.Unauthenticated, err.Error())
+
+	st, _ = st.WithDetails(&errdetails.ErrorInfo{
+		Reason: "REAUTHENTICATION_REQUIRED",
+		Domain: "user-service",
+	})
+
+	return st.Err()
+}
+
 func ToStatusCodeError(err error) error {
 	var ve model.ValidationErrors
+	var le model.LockoutError
 
 	switch {
 	case errors.Is(err, model.ErrMissingMetadata):
 		return status.Error(codes.InvalidArgument, err.Error())
 	case errors.Is(err, model.ErrInvalidToken):
 		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, model.ErrRevokedToken):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, model.ErrInvalidClientCredentials):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, model.ErrInvalidAPIKey):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, model.ErrExpiredAPIKey):
+		return status.Error(codes.Unauthenticated, err.Error())
 	case errors.Is(err, model.ErrInsufficientPermissions):
 		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, model.ErrImpersonationNotAllowed):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, model.ErrImpersonating):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, model.ErrPasswordChangeRequired):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, model.ErrReauthenticationRequired):
+		return reauthenticationError(err)
 	case errors.Is(err, model.ErrNotFound):
 		return status.Error(codes.NotFound, err.Error())
 	case errors.Is(err, model.ErrDuplicateEmail):
 		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, model.ErrDuplicatePhoneNumber):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, model.ErrDuplicateRole):
+		return status.Error(codes.AlreadyExists, err.Error())
 	case errors.Is(err, model.ErrNoUpdateFields):
 		return status.Error(codes.InvalidArgument, err.Error())
 	case errors.Is(err, model.ErrInvalidActivationCode):
 		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, model.ErrInvalidResetToken):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, model.ErrNoPendingEmailChange):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, model.ErrNoPhoneNumber):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, model.ErrPhoneNumberVerified):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, model.ErrNoPendingPhoneCode):
+		return status.Error(codes.FailedPrecondition, err.Error())
 	case errors.Is(err, model.ErrActivatedUser):
 		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, model.ErrMFAAlreadyEnabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, model.ErrMFANotEnabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, model.ErrPasswordlessDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &le):
+		return lockoutError(le)
 	case errors.As(err, &ve):
 		return validationError(ve)
 	default:
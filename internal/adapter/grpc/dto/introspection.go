@@ -0,0 +1,44 @@
+package dto
+
+import (
+	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ToIntrospectResponse leaves every field but active and the cache TTL unset for inactive tokens
+func ToIntrospectResponse(introspection model.TokenIntrospection) *authsvc.IntrospectResponse {
+	res := &authsvc.IntrospectResponse{
+		Active:          introspection.Active,
+		CacheTtlSeconds: int64(introspection.CacheTTL.Seconds()),
+	}
+	if !introspection.Active {
+		return res
+	}
+
+	subjectType := string(introspection.SubjectType)
+	tokenType := string(introspection.Type)
+
+	res.SubjectType = &subjectType
+	res.TokenType = &tokenType
+	res.Roles = introspection.Roles
+	res.Scopes = introspection.Scopes
+	res.IssuedAt = timestamppb.New(introspection.IssuedAt)
+	res.ExpiresAt = timestamppb.New(introspection.ExpiresAt)
+
+	if introspection.SubjectType == model.SubjectTypeClient {
+		res.ClientId = &introspection.ClientID
+
+		return res
+	}
+
+	res.UserId = &introspection.UserID
+	res.SessionId = &introspection.SessionID
+	res.IsUserActive = &introspection.IsUserActive
+	res.IsUserConfirmed = &introspection.IsUserConfirmed
+	if introspection.ActorID != 0 {
+		res.ActorId = &introspection.ActorID
+	}
+
+	return res
+}
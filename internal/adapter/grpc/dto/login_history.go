@@ -0,0 +1,40 @@
+package dto
+
+import (
+	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func FromGetLoginHistoryRequest(req *authsvc.GetLoginHistoryRequest) model.LoginHistoryFilter {
+	filter := model.LoginHistoryFilter{
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
+	}
+	if req.UserId != nil {
+		filter.UserID = *req.UserId
+	}
+
+	return filter
+}
+
+func ToGetLoginHistoryResponse(page model.LoginHistoryPage) *authsvc.GetLoginHistoryResponse {
+	events := make([]*authsvc.LoginEvent, len(page.Events))
+	for i, event := range page.Events {
+		events[i] = &authsvc.LoginEvent{
+			Id:        event.ID,
+			UserId:    event.UserID,
+			Method:    string(event.Method),
+			Outcome:   string(event.Outcome),
+			ClientIp:  event.ClientIP,
+			UserAgent: event.UserAgent,
+			RequestId: event.RequestID,
+			CreatedAt: timestamppb.New(event.CreatedAt),
+		}
+	}
+
+	return &authsvc.GetLoginHistoryResponse{
+		Events:        events,
+		NextPageToken: page.NextPageToken,
+	}
+}
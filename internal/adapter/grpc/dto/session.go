@@ -0,0 +1,19 @@
+package dto
+
+import (
+	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func ToSessionProto(session model.Session) *authsvc.Session {
+	return &authsvc.Session{
+		Id:         session.ID,
+		DeviceName: session.DeviceName,
+		UserAgent:  session.UserAgent,
+		ClientIp:   session.ClientIP,
+		CreatedAt:  timestamppb.New(session.CreatedAt),
+		LastUsedAt: timestamppb.New(session.LastUsedAt),
+		IsCurrent:  session.IsCurrent,
+	}
+}
@@ -18,7 +18,6 @@ func FromCreateUserRequest(req *usersvc.CreateRequest) (model.UserCreateData, er
 
 	data := model.UserCreateData{
 		Email:                req.Email,
-		PhoneNumber:          *req.PhoneNumber,
 		Password:             req.Password,
 		PasswordConfirmation: req.PasswordConfirmation,
 		FirstName:            req.FirstName,
@@ -28,6 +27,10 @@ func FromCreateUserRequest(req *usersvc.CreateRequest) (model.UserCreateData, er
 		IsConfirmed:          &req.IsConfirmed,
 	}
 
+	if req.PhoneNumber != nil {
+		data.PhoneNumber = *req.PhoneNumber
+	}
+
 	if len(req.Roles) > 0 {
 		roles := make([]model.Role, len(req.Roles))
 
@@ -54,18 +57,19 @@ func ToUserProto(user model.User) *base.User {
 	}
 
 	return &base.User{
-		ID:           user.ID,
-		Email:        user.Email,
-		PhoneNumber:  user.PhoneNumber,
-		FirstName:    user.FirstName,
-		LastName:     user.LastName,
-		BirthDate:    user.BirthDate.Format("2006-01-02"),
-		PasswordHash: user.PasswordHash,
-		Roles:        roles,
-		CreatedAt:    timestamppb.New(user.CreatedAt),
-		UpdatedAt:    timestamppb.New(user.UpdatedAt),
-		IsActive:     user.IsActive,
-		IsConfirmed:  user.IsConfirmed,
+		ID:              user.ID,
+		Email:           user.Email,
+		PhoneNumber:     user.PhoneNumber,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		BirthDate:       user.BirthDate.Format("2006-01-02"),
+		PasswordHash:    user.PasswordHash,
+		Roles:           roles,
+		CreatedAt:       timestamppb.New(user.CreatedAt),
+		UpdatedAt:       timestamppb.New(user.UpdatedAt),
+		IsActive:        user.IsActive,
+		IsConfirmed:     user.IsConfirmed,
+		IsPhoneVerified: user.IsPhoneVerified,
 	}
 }
 
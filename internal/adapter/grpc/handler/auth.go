@@ -4,19 +4,38 @@ import (
 	"context"
 	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
 	"github.com/sorawaslocked/car-rental-user-service/internal/adapter/grpc/dto"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
 	"log/slog"
 )
 
 type AuthHandler struct {
-	log         *slog.Logger
-	authService AuthService
+	log                  *slog.Logger
+	authService          AuthService
+	mfaService           MFAService
+	loginHistoryService  LoginHistoryService
+	serviceClientService ServiceClientService
+	apiKeyService        APIKeyService
+	roleService          RoleService
 	authsvc.UnimplementedAuthServiceServer
 }
 
-func NewAuthHandler(log *slog.Logger, authService AuthService) *AuthHandler {
+func NewAuthHandler(
+	log *slog.Logger,
+	authService AuthService,
+	mfaService MFAService,
+	loginHistoryService LoginHistoryService,
+	serviceClientService ServiceClientService,
+	apiKeyService APIKeyService,
+	roleService RoleService,
+) *AuthHandler {
 	return &AuthHandler{
-		log:         log,
-		authService: authService,
+		log:                  log,
+		authService:          authService,
+		mfaService:           mfaService,
+		loginHistoryService:  loginHistoryService,
+		serviceClientService: serviceClientService,
+		apiKeyService:        apiKeyService,
+		roleService:          roleService,
 	}
 }
 
@@ -37,19 +56,131 @@ func (h *AuthHandler) Register(ctx context.Context, req *authsvc.RegisterRequest
 func (h *AuthHandler) Login(ctx context.Context, req *authsvc.LoginRequest) (*authsvc.LoginResponse, error) {
 	cred := dto.FromLoginRequest(req)
 
-	token, err := h.authService.Login(ctx, cred)
+	result, err := h.authService.Login(ctx, cred)
 	if err != nil {
 		return &authsvc.LoginResponse{}, dto.ToStatusCodeError(err)
 	}
 
-	return &authsvc.LoginResponse{
-		AccessToken:           &token.AccessToken,
-		AccessTokenExpiresIn:  &token.AccessTokenExpiresIn,
-		RefreshToken:          &token.RefreshToken,
-		RefreshTokenExpiresIn: &token.RefreshTokenExpiresIn,
+	return dto.ToLoginResponse(result), nil
+}
+
+func (h *AuthHandler) RequestLoginCode(ctx context.Context, req *authsvc.RequestLoginCodeRequest) (*authsvc.RequestLoginCodeResponse, error) {
+	err := h.authService.RequestLoginCode(ctx, req.Email)
+	if err != nil {
+		return &authsvc.RequestLoginCodeResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RequestLoginCodeResponse{}, nil
+}
+
+// LoginWithCode answers like Login, so clients handle the MFA and password change challenges the same way
+func (h *AuthHandler) LoginWithCode(ctx context.Context, req *authsvc.LoginWithCodeRequest) (*authsvc.LoginResponse, error) {
+	cred := dto.FromLoginWithCodeRequest(req)
+
+	result, err := h.authService.LoginWithCode(ctx, cred)
+	if err != nil {
+		return &authsvc.LoginResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return dto.ToLoginResponse(result), nil
+}
+
+func (h *AuthHandler) VerifyMfa(ctx context.Context, req *authsvc.VerifyMfaRequest) (*authsvc.VerifyMfaResponse, error) {
+	result, err := h.authService.VerifyMFA(ctx, req.MfaToken, req.Code)
+	if err != nil {
+		return &authsvc.VerifyMfaResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return dto.ToVerifyMfaResponse(result), nil
+}
+
+func (h *AuthHandler) EnrollTotp(ctx context.Context, _ *authsvc.EnrollTotpRequest) (*authsvc.EnrollTotpResponse, error) {
+	enrollment, err := h.mfaService.EnrollTOTP(ctx)
+	if err != nil {
+		return &authsvc.EnrollTotpResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.EnrollTotpResponse{
+		Secret: &enrollment.Secret,
+		Uri:    &enrollment.URI,
+	}, nil
+}
+
+func (h *AuthHandler) ConfirmTotp(ctx context.Context, req *authsvc.ConfirmTotpRequest) (*authsvc.ConfirmTotpResponse, error) {
+	recoveryCodes, err := h.mfaService.ConfirmTOTP(ctx, req.Code)
+	if err != nil {
+		return &authsvc.ConfirmTotpResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.ConfirmTotpResponse{
+		RecoveryCodes: recoveryCodes,
 	}, nil
 }
 
+func (h *AuthHandler) SetRoleMfaRequirement(ctx context.Context, req *authsvc.SetRoleMfaRequirementRequest) (*authsvc.SetRoleMfaRequirementResponse, error) {
+	role, err := model.FromStringToRole(req.Role)
+	if err != nil {
+		return &authsvc.SetRoleMfaRequirementResponse{}, dto.ToStatusCodeError(model.ValidationErrors{
+			"role": err,
+		})
+	}
+
+	err = h.mfaService.SetRoleMFARequirement(ctx, role, req.Required)
+	if err != nil {
+		return &authsvc.SetRoleMfaRequirementResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.SetRoleMfaRequirementResponse{}, nil
+}
+
+func (h *AuthHandler) CreateRole(ctx context.Context, req *authsvc.CreateRoleRequest) (*authsvc.CreateRoleResponse, error) {
+	role, err := model.FromStringToRole(req.Role)
+	if err != nil {
+		return &authsvc.CreateRoleResponse{}, dto.ToStatusCodeError(model.ValidationErrors{
+			"role": err,
+		})
+	}
+
+	err = h.roleService.CreateRole(ctx, role)
+	if err != nil {
+		return &authsvc.CreateRoleResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.CreateRoleResponse{}, nil
+}
+
+func (h *AuthHandler) GrantRolePermission(ctx context.Context, req *authsvc.GrantRolePermissionRequest) (*authsvc.GrantRolePermissionResponse, error) {
+	role, err := model.FromStringToRole(req.Role)
+	if err != nil {
+		return &authsvc.GrantRolePermissionResponse{}, dto.ToStatusCodeError(model.ValidationErrors{
+			"role": err,
+		})
+	}
+
+	err = h.roleService.GrantPermission(ctx, role, model.Permission(req.Permission))
+	if err != nil {
+		return &authsvc.GrantRolePermissionResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.GrantRolePermissionResponse{}, nil
+}
+
+func (h *AuthHandler) RevokeRolePermission(ctx context.Context, req *authsvc.RevokeRolePermissionRequest) (*authsvc.RevokeRolePermissionResponse, error) {
+	role, err := model.FromStringToRole(req.Role)
+	if err != nil {
+		return &authsvc.RevokeRolePermissionResponse{}, dto.ToStatusCodeError(model.ValidationErrors{
+			"role": err,
+		})
+	}
+
+	err = h.roleService.RevokePermission(ctx, role, model.Permission(req.Permission))
+	if err != nil {
+		return &authsvc.RevokeRolePermissionResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RevokeRolePermissionResponse{}, nil
+}
+
 func (h *AuthHandler) RefreshToken(ctx context.Context, req *authsvc.RefreshTokenRequest) (*authsvc.RefreshTokenResponse, error) {
 	token, err := h.authService.RefreshToken(ctx, req.RefreshToken)
 	if err != nil {
@@ -65,10 +196,244 @@ func (h *AuthHandler) RefreshToken(ctx context.Context, req *authsvc.RefreshToke
 }
 
 func (h *AuthHandler) Logout(ctx context.Context, req *authsvc.LogoutRequest) (*authsvc.LogoutResponse, error) {
-	err := h.authService.Logout(ctx, req.RefreshToken)
+	err := h.authService.Logout(ctx, req.RefreshToken, dto.AccessTokenFromContext(ctx))
 	if err != nil {
 		return &authsvc.LogoutResponse{}, dto.ToStatusCodeError(err)
 	}
 
 	return &authsvc.LogoutResponse{}, nil
 }
+
+func (h *AuthHandler) RequestPasswordReset(ctx context.Context, req *authsvc.RequestPasswordResetRequest) (*authsvc.RequestPasswordResetResponse, error) {
+	err := h.authService.RequestPasswordReset(ctx, req.Email)
+	if err != nil {
+		return &authsvc.RequestPasswordResetResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RequestPasswordResetResponse{}, nil
+}
+
+func (h *AuthHandler) ResetPassword(ctx context.Context, req *authsvc.ResetPasswordRequest) (*authsvc.ResetPasswordResponse, error) {
+	err := h.authService.ResetPassword(ctx, req.Token, req.Password)
+	if err != nil {
+		return &authsvc.ResetPasswordResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.ResetPasswordResponse{}, nil
+}
+
+func (h *AuthHandler) ChangePassword(ctx context.Context, req *authsvc.ChangePasswordRequest) (*authsvc.ChangePasswordResponse, error) {
+	token, err := h.authService.ChangePassword(ctx, dto.FromChangePasswordRequest(req))
+	if err != nil {
+		return &authsvc.ChangePasswordResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.ChangePasswordResponse{
+		AccessToken:           &token.AccessToken,
+		AccessTokenExpiresIn:  &token.AccessTokenExpiresIn,
+		RefreshToken:          &token.RefreshToken,
+		RefreshTokenExpiresIn: &token.RefreshTokenExpiresIn,
+	}, nil
+}
+
+func (h *AuthHandler) Reauthenticate(ctx context.Context, req *authsvc.ReauthenticateRequest) (*authsvc.ReauthenticateResponse, error) {
+	token, err := h.authService.Reauthenticate(ctx, dto.FromReauthenticateRequest(req))
+	if err != nil {
+		return &authsvc.ReauthenticateResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.ReauthenticateResponse{
+		AccessToken:          &token.AccessToken,
+		AccessTokenExpiresIn: &token.AccessTokenExpiresIn,
+	}, nil
+}
+
+func (h *AuthHandler) GetJWKS(ctx context.Context, _ *authsvc.GetJWKSRequest) (*authsvc.GetJWKSResponse, error) {
+	keys := h.authService.JWKS(ctx)
+
+	keysProto := make([]*authsvc.JWK, len(keys))
+	for i, key := range keys {
+		keysProto[i] = dto.ToJWKProto(key)
+	}
+
+	return &authsvc.GetJWKSResponse{
+		Keys: keysProto,
+	}, nil
+}
+
+func (h *AuthHandler) Impersonate(ctx context.Context, req *authsvc.ImpersonateRequest) (*authsvc.ImpersonateResponse, error) {
+	token, err := h.authService.Impersonate(ctx, req.UserId, req.Reason)
+	if err != nil {
+		return &authsvc.ImpersonateResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.ImpersonateResponse{
+		AccessToken:          &token.AccessToken,
+		AccessTokenExpiresIn: &token.AccessTokenExpiresIn,
+	}, nil
+}
+
+func (h *AuthHandler) ListSessions(ctx context.Context, _ *authsvc.ListSessionsRequest) (*authsvc.ListSessionsResponse, error) {
+	sessions, err := h.authService.ListSessions(ctx)
+	if err != nil {
+		return &authsvc.ListSessionsResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	protoSessions := make([]*authsvc.Session, len(sessions))
+	for i, session := range sessions {
+		protoSessions[i] = dto.ToSessionProto(session)
+	}
+
+	return &authsvc.ListSessionsResponse{Sessions: protoSessions}, nil
+}
+
+func (h *AuthHandler) RevokeSession(ctx context.Context, req *authsvc.RevokeSessionRequest) (*authsvc.RevokeSessionResponse, error) {
+	err := h.authService.RevokeSession(ctx, req.SessionId)
+	if err != nil {
+		return &authsvc.RevokeSessionResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RevokeSessionResponse{}, nil
+}
+
+func (h *AuthHandler) RevokeAllSessions(ctx context.Context, _ *authsvc.RevokeAllSessionsRequest) (*authsvc.RevokeAllSessionsResponse, error) {
+	err := h.authService.RevokeAllSessions(ctx)
+	if err != nil {
+		return &authsvc.RevokeAllSessionsResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RevokeAllSessionsResponse{}, nil
+}
+
+func (h *AuthHandler) ListKnownDevices(ctx context.Context, _ *authsvc.ListKnownDevicesRequest) (*authsvc.ListKnownDevicesResponse, error) {
+	devices, err := h.authService.ListKnownDevices(ctx)
+	if err != nil {
+		return &authsvc.ListKnownDevicesResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	protoDevices := make([]*authsvc.KnownDevice, len(devices))
+	for i, device := range devices {
+		protoDevices[i] = dto.ToKnownDeviceProto(device)
+	}
+
+	return &authsvc.ListKnownDevicesResponse{Devices: protoDevices}, nil
+}
+
+func (h *AuthHandler) RemoveKnownDevice(ctx context.Context, req *authsvc.RemoveKnownDeviceRequest) (*authsvc.RemoveKnownDeviceResponse, error) {
+	err := h.authService.RemoveKnownDevice(ctx, req.DeviceId)
+	if err != nil {
+		return &authsvc.RemoveKnownDeviceResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RemoveKnownDeviceResponse{}, nil
+}
+
+func (h *AuthHandler) ReportUnrecognizedLogin(ctx context.Context, req *authsvc.ReportUnrecognizedLoginRequest) (*authsvc.ReportUnrecognizedLoginResponse, error) {
+	err := h.authService.ReportUnrecognizedLogin(ctx, req.Token)
+	if err != nil {
+		return &authsvc.ReportUnrecognizedLoginResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.ReportUnrecognizedLoginResponse{}, nil
+}
+
+func (h *AuthHandler) GetLoginHistory(ctx context.Context, req *authsvc.GetLoginHistoryRequest) (*authsvc.GetLoginHistoryResponse, error) {
+	page, err := h.loginHistoryService.GetLoginHistory(ctx, dto.FromGetLoginHistoryRequest(req))
+	if err != nil {
+		return &authsvc.GetLoginHistoryResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return dto.ToGetLoginHistoryResponse(page), nil
+}
+
+func (h *AuthHandler) Introspect(ctx context.Context, req *authsvc.IntrospectRequest) (*authsvc.IntrospectResponse, error) {
+	introspection, err := h.authService.Introspect(ctx, req.Token)
+	if err != nil {
+		return &authsvc.IntrospectResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return dto.ToIntrospectResponse(introspection), nil
+}
+
+func (h *AuthHandler) ClientToken(ctx context.Context, req *authsvc.ClientTokenRequest) (*authsvc.ClientTokenResponse, error) {
+	token, err := h.serviceClientService.IssueToken(ctx, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return &authsvc.ClientTokenResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.ClientTokenResponse{
+		AccessToken:          &token.AccessToken,
+		AccessTokenExpiresIn: &token.AccessTokenExpiresIn,
+	}, nil
+}
+
+func (h *AuthHandler) RegisterServiceClient(ctx context.Context, req *authsvc.RegisterServiceClientRequest) (*authsvc.RegisterServiceClientResponse, error) {
+	credentials, err := h.serviceClientService.Register(ctx, model.ServiceClientCreateData{
+		Name:           req.Name,
+		AllowedMethods: req.AllowedMethods,
+	})
+	if err != nil {
+		return &authsvc.RegisterServiceClientResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RegisterServiceClientResponse{
+		ClientId:     &credentials.ClientID,
+		ClientSecret: &credentials.ClientSecret,
+	}, nil
+}
+
+func (h *AuthHandler) DeactivateServiceClient(ctx context.Context, req *authsvc.DeactivateServiceClientRequest) (*authsvc.DeactivateServiceClientResponse, error) {
+	err := h.serviceClientService.Deactivate(ctx, req.ClientId)
+	if err != nil {
+		return &authsvc.DeactivateServiceClientResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.DeactivateServiceClientResponse{}, nil
+}
+
+func (h *AuthHandler) CreateApiKey(ctx context.Context, req *authsvc.CreateApiKeyRequest) (*authsvc.CreateApiKeyResponse, error) {
+	issued, err := h.apiKeyService.Issue(ctx, dto.FromCreateApiKeyRequest(req))
+	if err != nil {
+		return &authsvc.CreateApiKeyResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.CreateApiKeyResponse{
+		ApiKey: dto.ToApiKeyProto(issued.APIKey),
+		Key:    &issued.Key,
+	}, nil
+}
+
+func (h *AuthHandler) ListApiKeys(ctx context.Context, req *authsvc.ListApiKeysRequest) (*authsvc.ListApiKeysResponse, error) {
+	keys, err := h.apiKeyService.List(ctx, model.APIKeyFilter{
+		UserID:       req.UserId,
+		Organization: req.Organization,
+	})
+	if err != nil {
+		return &authsvc.ListApiKeysResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	protoKeys := make([]*authsvc.ApiKey, len(keys))
+	for i, key := range keys {
+		protoKeys[i] = dto.ToApiKeyProto(key)
+	}
+
+	return &authsvc.ListApiKeysResponse{ApiKeys: protoKeys}, nil
+}
+
+func (h *AuthHandler) UpdateApiKey(ctx context.Context, req *authsvc.UpdateApiKeyRequest) (*authsvc.UpdateApiKeyResponse, error) {
+	err := h.apiKeyService.Update(ctx, req.Id, dto.FromUpdateApiKeyRequest(req))
+	if err != nil {
+		return &authsvc.UpdateApiKeyResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.UpdateApiKeyResponse{}, nil
+}
+
+func (h *AuthHandler) RevokeApiKey(ctx context.Context, req *authsvc.RevokeApiKeyRequest) (*authsvc.RevokeApiKeyResponse, error) {
+	err := h.apiKeyService.Revoke(ctx, req.Id)
+	if err != nil {
+		return &authsvc.RevokeApiKeyResponse{}, dto.ToStatusCodeError(err)
+	}
+
+	return &authsvc.RevokeApiKeyResponse{}, nil
+}
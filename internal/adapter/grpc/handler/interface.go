@@ -7,9 +7,54 @@ import (
 
 type AuthService interface {
 	Register(ctx context.Context, data model.UserCreateData) (uint64, error)
-	Login(ctx context.Context, cred model.Credentials) (model.Token, error)
+	Login(ctx context.Context, cred model.Credentials) (model.LoginResult, error)
+	VerifyMFA(ctx context.Context, mfaToken, code string) (model.LoginResult, error)
+	RequestLoginCode(ctx context.Context, email string) error
+	LoginWithCode(ctx context.Context, cred model.LoginCodeCredentials) (model.LoginResult, error)
 	RefreshToken(ctx context.Context, refreshToken string) (model.Token, error)
-	Logout(ctx context.Context, refreshToken string) error
+	Logout(ctx context.Context, refreshToken, accessToken string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	ChangePassword(ctx context.Context, data model.PasswordChangeData) (model.Token, error)
+	Reauthenticate(ctx context.Context, data model.ReauthenticationData) (model.Token, error)
+	Impersonate(ctx context.Context, userID uint64, reason string) (model.Token, error)
+	ListSessions(ctx context.Context) ([]model.Session, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	RevokeAllSessions(ctx context.Context) error
+	ListKnownDevices(ctx context.Context) ([]model.KnownDevice, error)
+	RemoveKnownDevice(ctx context.Context, deviceID uint64) error
+	ReportUnrecognizedLogin(ctx context.Context, token string) error
+	Introspect(ctx context.Context, token string) (model.TokenIntrospection, error)
+	JWKS(ctx context.Context) []model.JSONWebKey
+}
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context) (model.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, code string) ([]string, error)
+	SetRoleMFARequirement(ctx context.Context, role model.Role, required bool) error
+}
+
+type LoginHistoryService interface {
+	GetLoginHistory(ctx context.Context, filter model.LoginHistoryFilter) (model.LoginHistoryPage, error)
+}
+
+type RoleService interface {
+	CreateRole(ctx context.Context, role model.Role) error
+	GrantPermission(ctx context.Context, role model.Role, permission model.Permission) error
+	RevokePermission(ctx context.Context, role model.Role, permission model.Permission) error
+}
+
+type ServiceClientService interface {
+	Register(ctx context.Context, data model.ServiceClientCreateData) (model.ServiceClientCredentials, error)
+	Deactivate(ctx context.Context, clientID string) error
+	IssueToken(ctx context.Context, clientID, clientSecret string) (model.Token, error)
+}
+
+type APIKeyService interface {
+	Issue(ctx context.Context, data model.APIKeyCreateData) (model.IssuedAPIKey, error)
+	List(ctx context.Context, filter model.APIKeyFilter) ([]model.APIKey, error)
+	Update(ctx context.Context, id uint64, data model.APIKeyUpdateData) error
+	Revoke(ctx context.Context, id uint64) error
 }
 
 type UserService interface {
@@ -18,7 +63,16 @@ type UserService interface {
 	Find(ctx context.Context, filter model.UserFilter) ([]model.User, error)
 	Update(ctx context.Context, filter model.UserFilter, data model.UserUpdateData) error
 	Delete(ctx context.Context, filter model.UserFilter) error
+	UnlockAccount(ctx context.Context, filter model.UserFilter) error
+	RequirePasswordChange(ctx context.Context, filter model.UserFilter) error
+	SetRolePasswordHistorySize(ctx context.Context, role model.Role, size int) error
+	SetRolePasswordMaxAge(ctx context.Context, role model.Role, days int) error
 	Me(ctx context.Context) (model.User, error)
 	SendActivationCode(ctx context.Context) error
 	CheckActivationCode(ctx context.Context, code string) error
+	SendPhoneVerificationCode(ctx context.Context) error
+	CheckPhoneVerificationCode(ctx context.Context, code string) error
+	RequestEmailChange(ctx context.Context, email string) error
+	ConfirmEmailChange(ctx context.Context, code string) error
+	CancelEmailChange(ctx context.Context, cancelToken string) error
 }
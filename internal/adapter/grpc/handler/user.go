@@ -103,6 +103,66 @@ func (h *UserHandler) Delete(ctx context.Context, req *usersvc.DeleteRequest) (*
 	return &usersvc.DeleteResponse{}, nil
 }
 
+func (h *UserHandler) UnlockAccount(ctx context.Context, req *usersvc.UnlockAccountRequest) (*usersvc.UnlockAccountResponse, error) {
+	filter := model.UserFilter{
+		ID:    req.ID,
+		Email: req.Email,
+	}
+
+	err := h.userService.UnlockAccount(ctx, filter)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.UnlockAccountResponse{}, nil
+}
+
+func (h *UserHandler) RequirePasswordChange(ctx context.Context, req *usersvc.RequirePasswordChangeRequest) (*usersvc.RequirePasswordChangeResponse, error) {
+	filter := model.UserFilter{
+		ID:    req.ID,
+		Email: req.Email,
+	}
+
+	err := h.userService.RequirePasswordChange(ctx, filter)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.RequirePasswordChangeResponse{}, nil
+}
+
+func (h *UserHandler) SetRolePasswordHistory(ctx context.Context, req *usersvc.SetRolePasswordHistoryRequest) (*usersvc.SetRolePasswordHistoryResponse, error) {
+	role, err := model.FromStringToRole(req.Role)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(model.ValidationErrors{
+			"role": err,
+		})
+	}
+
+	err = h.userService.SetRolePasswordHistorySize(ctx, role, int(req.Size))
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.SetRolePasswordHistoryResponse{}, nil
+}
+
+func (h *UserHandler) SetRolePasswordMaxAge(ctx context.Context, req *usersvc.SetRolePasswordMaxAgeRequest) (*usersvc.SetRolePasswordMaxAgeResponse, error) {
+	role, err := model.FromStringToRole(req.Role)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(model.ValidationErrors{
+			"role": err,
+		})
+	}
+
+	err = h.userService.SetRolePasswordMaxAge(ctx, role, int(req.Days))
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.SetRolePasswordMaxAgeResponse{}, nil
+}
+
 func (h *UserHandler) Me(ctx context.Context, _ *usersvc.MeRequest) (*usersvc.MeResponse, error) {
 	user, err := h.userService.Me(ctx)
 	if err != nil {
@@ -131,3 +191,48 @@ func (h *UserHandler) CheckActivationCode(ctx context.Context, req *usersvc.Chec
 
 	return &usersvc.CheckActivationCodeResponse{}, nil
 }
+
+func (h *UserHandler) SendPhoneVerificationCode(ctx context.Context, _ *usersvc.SendPhoneVerificationCodeRequest) (*usersvc.SendPhoneVerificationCodeResponse, error) {
+	err := h.userService.SendPhoneVerificationCode(ctx)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.SendPhoneVerificationCodeResponse{}, nil
+}
+
+func (h *UserHandler) CheckPhoneVerificationCode(ctx context.Context, req *usersvc.CheckPhoneVerificationCodeRequest) (*usersvc.CheckPhoneVerificationCodeResponse, error) {
+	err := h.userService.CheckPhoneVerificationCode(ctx, req.Code)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.CheckPhoneVerificationCodeResponse{}, nil
+}
+
+func (h *UserHandler) RequestEmailChange(ctx context.Context, req *usersvc.RequestEmailChangeRequest) (*usersvc.RequestEmailChangeResponse, error) {
+	err := h.userService.RequestEmailChange(ctx, req.Email)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.RequestEmailChangeResponse{}, nil
+}
+
+func (h *UserHandler) ConfirmEmailChange(ctx context.Context, req *usersvc.ConfirmEmailChangeRequest) (*usersvc.ConfirmEmailChangeResponse, error) {
+	err := h.userService.ConfirmEmailChange(ctx, req.Code)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.ConfirmEmailChangeResponse{}, nil
+}
+
+func (h *UserHandler) CancelEmailChange(ctx context.Context, req *usersvc.CancelEmailChangeRequest) (*usersvc.CancelEmailChangeResponse, error) {
+	err := h.userService.CancelEmailChange(ctx, req.CancelToken)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	return &usersvc.CancelEmailChangeResponse{}, nil
+}
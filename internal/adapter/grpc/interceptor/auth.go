@@ -2,29 +2,58 @@ package interceptor
 
 import (
 	"context"
+	authsvc "github.com/sorawaslocked/car-rental-protos/gen/service/auth"
 	usersvc "github.com/sorawaslocked/car-rental-protos/gen/service/user"
 	"github.com/sorawaslocked/car-rental-user-service/internal/adapter/grpc/dto"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"log/slog"
 	"strings"
+	"time"
 )
 
 type _claims struct {
-	id    uint64
-	roles []model.Role
+	subjectType  model.SubjectType
+	id           uint64
+	clientID     string
+	organization string
+	roles        []model.Role
+	permissions  map[model.Permission]bool // permissions are granted to the roles, loaded only for users
+	scopes       []string
+	scoped       bool   // scoped restricts users to their scopes on top of their roles, which API keys do
+	actorID      uint64 // actorID is the support agent impersonating the user
+	sessionID    string
+	authTime     time.Time // authTime is when the user last proved their identity, zero for API keys
 }
 
 // AuthInterceptor is a middleware struct to handle authorization and authentication
 type AuthInterceptor struct {
-	jwtProvider    JwtProvider
-	permittedRoles map[string]map[model.Role]bool // permittedRoles maps endpoints to the roles which can access it
+	log                    *slog.Logger
+	jwtProvider            JwtProvider
+	tokenRevocationStorage TokenRevocationStorage
+	apiKeyAuthenticator    APIKeyAuthenticator
+	auditRepo              AuditRepository
+	permissions            *permissionCache
 }
 
-func NewAuthInterceptor(jwtProvider JwtProvider) *AuthInterceptor {
+func NewAuthInterceptor(
+	log *slog.Logger,
+	jwtProvider JwtProvider,
+	tokenRevocationStorage TokenRevocationStorage,
+	apiKeyAuthenticator APIKeyAuthenticator,
+	auditRepo AuditRepository,
+	permissionRepo PermissionRepository,
+	permissionVersionStorage PermissionVersionStorage,
+) *AuthInterceptor {
 	return &AuthInterceptor{
-		jwtProvider:    jwtProvider,
-		permittedRoles: createPermittedRoles(),
+		log:                    log,
+		jwtProvider:            jwtProvider,
+		tokenRevocationStorage: tokenRevocationStorage,
+		apiKeyAuthenticator:    apiKeyAuthenticator,
+		auditRepo:              auditRepo,
+		permissions:            newPermissionCache(permissionRepo, permissionVersionStorage),
 	}
 }
 
@@ -34,16 +63,44 @@ func (i *AuthInterceptor) Unary(ctx context.Context, req any, info *grpc.UnarySe
 		return nil, dto.ToStatusCodeError(model.ErrMissingMetadata)
 	}
 
-	claims, err := i.authenticateAndGetClaims(md["authorization"], info.FullMethod)
+	claims, err := i.authenticateAndGetClaims(ctx, md, info.FullMethod)
 	if err != nil {
 		return nil, dto.ToStatusCodeError(err)
 	}
 	// No-auth method
-	if claims.id < 1 {
+	if claims.subjectType == "" {
 		return handler(ctx, req)
 	}
 
-	err = i.authorize(claims, info.FullMethod)
+	switch claims.subjectType {
+	case model.SubjectTypeClient, model.SubjectTypeOrganization:
+		err = i.authorizeClient(claims, info.FullMethod)
+		if err != nil {
+			return nil, dto.ToStatusCodeError(err)
+		}
+
+		if claims.subjectType == model.SubjectTypeClient {
+			ctx = context.WithValue(ctx, "clientID", claims.clientID)
+		} else {
+			ctx = context.WithValue(ctx, "organization", claims.organization)
+		}
+
+		return handler(ctx, req)
+	}
+
+	// Every log line of an impersonated request names the support agent
+	if claims.actorID != 0 {
+		logger.AddRequestAttrs(ctx, slog.Uint64("actorId", claims.actorID))
+	}
+
+	claims.permissions, err = i.permissions.forRoles(ctx, claims.roles)
+	if err != nil {
+		i.log.ErrorContext(ctx, "permission cache: loading role permissions", logger.Err(err))
+
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	err = authorize(claims, info.FullMethod)
 	if err != nil {
 		return nil, dto.ToStatusCodeError(err)
 	}
@@ -53,7 +110,22 @@ func (i *AuthInterceptor) Unary(ctx context.Context, req any, info *grpc.UnarySe
 		return nil, dto.ToStatusCodeError(err)
 	}
 
+	err = checkRecentAuth(req, claims, info.FullMethod)
+	if err != nil {
+		return nil, dto.ToStatusCodeError(err)
+	}
+
+	if claims.actorID != 0 {
+		err = i.auditImpersonation(ctx, claims, info.FullMethod)
+		if err != nil {
+			return nil, dto.ToStatusCodeError(err)
+		}
+
+		ctx = context.WithValue(ctx, "actorID", claims.actorID)
+	}
+
 	ctx = context.WithValue(ctx, "userID", claims.id)
+	ctx = context.WithValue(ctx, "sessionID", claims.sessionID)
 
 	m, err := handler(ctx, req)
 	if err != nil {
@@ -63,24 +135,74 @@ func (i *AuthInterceptor) Unary(ctx context.Context, req any, info *grpc.UnarySe
 	return m, err
 }
 
-func (i *AuthInterceptor) authenticateAndGetClaims(authorization []string, method string) (_claims, error) {
-	if i.permittedRoles[method] == nil {
+// authenticateAndGetClaims authenticates the bearer token of the request,
+// or its API key when there is no token
+func (i *AuthInterceptor) authenticateAndGetClaims(ctx context.Context, md metadata.MD, method string) (_claims, error) {
+	if _, ok := methodPermissions[method]; !ok {
 		return _claims{}, nil
 	}
 
+	authorization := md["authorization"]
 	if len(authorization) < 1 {
+		if apiKeys := md["x-api-key"]; len(apiKeys) > 0 {
+			return i.authenticateAPIKey(ctx, apiKeys[0])
+		}
+
 		return _claims{}, model.ErrInvalidToken
 	}
 
 	token := strings.TrimPrefix(authorization[0], "Bearer ")
 
-	id, roleStrings, err := i.jwtProvider.VerifyAndParseClaims(token)
+	tokenClaims, err := i.jwtProvider.VerifyAndParseClaims(token)
 	if err != nil {
 		return _claims{}, model.ErrInvalidToken
 	}
+	switch {
+	case tokenClaims.Type == model.TokenTypeAccess:
+	case tokenClaims.Type == model.TokenTypeMFA && mfaTokenMethods[method]:
+	case tokenClaims.Type == model.TokenTypePasswordChange:
+		if !passwordChangeTokenMethods[method] {
+			return _claims{}, model.ErrPasswordChangeRequired
+		}
+	default:
+		return _claims{}, model.ErrInvalidToken
+	}
+
+	err = i.checkRevocation(ctx, tokenClaims)
+	if err != nil {
+		return _claims{}, err
+	}
+
+	return toClaims(tokenClaims, false)
+}
+
+func (i *AuthInterceptor) authenticateAPIKey(ctx context.Context, key string) (_claims, error) {
+	tokenClaims, err := i.apiKeyAuthenticator.Authenticate(ctx, key)
+	if err != nil {
+		return _claims{}, err
+	}
+
+	return toClaims(tokenClaims, true)
+}
 
-	roles := make([]model.Role, len(roleStrings))
-	for idx, roleString := range roleStrings {
+func toClaims(tokenClaims model.TokenClaims, scoped bool) (_claims, error) {
+	switch tokenClaims.SubjectType {
+	case model.SubjectTypeClient:
+		return _claims{
+			subjectType: model.SubjectTypeClient,
+			clientID:    tokenClaims.ClientID,
+			scopes:      tokenClaims.Scopes,
+		}, nil
+	case model.SubjectTypeOrganization:
+		return _claims{
+			subjectType:  model.SubjectTypeOrganization,
+			organization: tokenClaims.Organization,
+			scopes:       tokenClaims.Scopes,
+		}, nil
+	}
+
+	roles := make([]model.Role, len(tokenClaims.Roles))
+	for idx, roleString := range tokenClaims.Roles {
 		role, err := model.FromStringToRole(roleString)
 		if err != nil {
 			return _claims{}, model.ErrInvalidToken
@@ -90,21 +212,147 @@ func (i *AuthInterceptor) authenticateAndGetClaims(authorization []string, metho
 	}
 
 	return _claims{
-		id:    id,
-		roles: roles,
+		subjectType: model.SubjectTypeUser,
+		id:          tokenClaims.UserID,
+		roles:       roles,
+		scopes:      tokenClaims.Scopes,
+		scoped:      scoped,
+		actorID:     tokenClaims.ActorID,
+		sessionID:   tokenClaims.SessionID,
+		authTime:    tokenClaims.AuthTime,
 	}, nil
 }
 
-func (i *AuthInterceptor) authorize(claims _claims, method string) error {
-	permittedRolesForMethod := i.permittedRoles[method]
+// checkRevocation rejects tokens which were revoked one by one
+// or issued before all tokens of the user or the service client were revoked
+func (i *AuthInterceptor) checkRevocation(ctx context.Context, tokenClaims model.TokenClaims) error {
+	revoked, err := i.tokenRevocationStorage.IsTokenRevoked(ctx, tokenClaims.TokenID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return model.ErrRevokedToken
+	}
+
+	// Service clients have no sessions, their tokens are revoked when they are deactivated
+	if tokenClaims.SubjectType == model.SubjectTypeClient {
+		validAfter, err := i.tokenRevocationStorage.ClientTokensValidAfter(ctx, tokenClaims.ClientID)
+		if err != nil {
+			return err
+		}
+		if tokenClaims.IssuedAt.Before(validAfter) {
+			return model.ErrRevokedToken
+		}
+
+		return nil
+	}
+
+	if tokenClaims.SessionID != "" {
+		revoked, err = i.tokenRevocationStorage.IsSessionRevoked(ctx, tokenClaims.SessionID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return model.ErrRevokedToken
+		}
+	}
+
+	validAfter, err := i.tokenRevocationStorage.UserTokensValidAfter(ctx, tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+	if tokenClaims.IssuedAt.Before(validAfter) {
+		return model.ErrRevokedToken
+	}
+
+	return nil
+}
+
+func authorize(claims _claims, method string) error {
+	if claims.scoped && !hasScope(claims.scopes, method) {
+		return model.ErrInsufficientPermissions
+	}
+
+	if !claims.permissions[methodPermissions[method]] {
+		return model.ErrInsufficientPermissions
+	}
+
+	return nil
+}
+
+// authorizeClient permits service clients and organization API keys only the methods
+// which accept them and which they were granted. End-user roles do not apply to them
+func (i *AuthInterceptor) authorizeClient(claims _claims, method string) error {
+	if !clientMethods[method] || !hasScope(claims.scopes, method) {
+		return model.ErrInsufficientPermissions
+	}
+	if claims.subjectType == model.SubjectTypeOrganization && serviceClientOnlyMethods[method] {
+		return model.ErrInsufficientPermissions
+	}
+
+	return nil
+}
+
+func hasScope(scopes []string, method string) bool {
+	for _, scope := range scopes {
+		if scope == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// auditImpersonation blocks the methods support agents can not call as a customer
+// and records every other call made with an impersonation token
+func (i *AuthInterceptor) auditImpersonation(ctx context.Context, claims _claims, method string) error {
+	if impersonationBlockedMethods[method] {
+		return model.ErrImpersonating
+	}
+
+	i.log.InfoContext(
+		ctx,
+		"impersonated request",
+		slog.Uint64("userId", claims.id),
+		slog.String("method", method),
+	)
+
+	err := i.auditRepo.Insert(ctx, model.AuditEvent{
+		ActorID:   &claims.actorID,
+		UserID:    &claims.id,
+		Action:    model.AuditActionImpersonatedCall,
+		Method:    method,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		i.log.ErrorContext(ctx, "sql: inserting audit event", logger.Err(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// checkRecentAuth rejects calls of methods requiring a recent authentication
+// made with tokens of a user who authenticated too long ago
+func checkRecentAuth(request any, claims _claims, method string) error {
+	maxAge, ok := recentAuthMethods[method]
+	if !ok {
+		return nil
+	}
 
-	for _, role := range claims.roles {
-		if _, ok := permittedRolesForMethod[role]; ok {
+	if method == UserServiceUpdate {
+		req := request.(*usersvc.UpdateRequest)
+		if len(req.Roles) == 0 {
 			return nil
 		}
 	}
 
-	return model.ErrInsufficientPermissions
+	if claims.authTime.IsZero() || time.Since(claims.authTime) > maxAge {
+		return model.ErrReauthenticationRequired
+	}
+
+	return nil
 }
 
 func (i *AuthInterceptor) matchesID(request any, claims _claims, method string) error {
@@ -112,15 +360,38 @@ func (i *AuthInterceptor) matchesID(request any, claims _claims, method string)
 	case UserServiceGet:
 		req := request.(*usersvc.GetRequest)
 
-		for _, role := range claims.roles {
-			if role == model.RoleAdmin {
-				return nil
-			}
+		if claims.permissions[model.PermissionUsersReadAny] {
+			return nil
 		}
 
 		if req.ID != nil && *req.ID != claims.id {
 			return model.ErrInsufficientPermissions
 		}
+	case UserServiceUpdate:
+		req := request.(*usersvc.UpdateRequest)
+
+		if len(req.Roles) > 0 && !claims.permissions[model.PermissionRolesManage] {
+			return model.ErrInsufficientPermissions
+		}
+
+		if claims.permissions[model.PermissionUsersUpdateAny] {
+			return nil
+		}
+
+		// The user is looked up by every field of the filter, so the ID alone pins it to the caller
+		if req.ID == nil || *req.ID != claims.id {
+			return model.ErrInsufficientPermissions
+		}
+	case AuthServiceGetLoginHistory:
+		req := request.(*authsvc.GetLoginHistoryRequest)
+
+		if claims.permissions[model.PermissionLoginHistoryReadAny] {
+			return nil
+		}
+
+		if req.UserId != nil && *req.UserId != claims.id {
+			return model.ErrInsufficientPermissions
+		}
 	}
 
 	return nil
@@ -0,0 +1,145 @@
+package interceptor
+
+import (
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"time"
+)
+
+const (
+	AuthServiceChangePassword          = "/service.auth.AuthService/ChangePassword"
+	AuthServiceReauthenticate          = "/service.auth.AuthService/Reauthenticate"
+	AuthServiceImpersonate             = "/service.auth.AuthService/Impersonate"
+	AuthServiceListSessions            = "/service.auth.AuthService/ListSessions"
+	AuthServiceRevokeSession           = "/service.auth.AuthService/RevokeSession"
+	AuthServiceRevokeAllSessions       = "/service.auth.AuthService/RevokeAllSessions"
+	AuthServiceListKnownDevices        = "/service.auth.AuthService/ListKnownDevices"
+	AuthServiceRemoveKnownDevice       = "/service.auth.AuthService/RemoveKnownDevice"
+	AuthServiceGetLoginHistory         = "/service.auth.AuthService/GetLoginHistory"
+	AuthServiceIntrospect              = "/service.auth.AuthService/Introspect"
+	AuthServiceEnrollTotp              = "/service.auth.AuthService/EnrollTotp"
+	AuthServiceConfirmTotp             = "/service.auth.AuthService/ConfirmTotp"
+	AuthServiceSetRoleMfaRequirement   = "/service.auth.AuthService/SetRoleMfaRequirement"
+	AuthServiceRegisterServiceClient   = "/service.auth.AuthService/RegisterServiceClient"
+	AuthServiceDeactivateServiceClient = "/service.auth.AuthService/DeactivateServiceClient"
+	AuthServiceCreateApiKey            = "/service.auth.AuthService/CreateApiKey"
+	AuthServiceListApiKeys             = "/service.auth.AuthService/ListApiKeys"
+	AuthServiceUpdateApiKey            = "/service.auth.AuthService/UpdateApiKey"
+	AuthServiceRevokeApiKey            = "/service.auth.AuthService/RevokeApiKey"
+	AuthServiceCreateRole              = "/service.auth.AuthService/CreateRole"
+	AuthServiceGrantRolePermission     = "/service.auth.AuthService/GrantRolePermission"
+	AuthServiceRevokeRolePermission    = "/service.auth.AuthService/RevokeRolePermission"
+
+	UserServiceCreate                     = "/service.user.UserService/Save"
+	UserServiceGet                        = "/service.user.UserService/Get"
+	UserServiceGetAll                     = "/service.user.UserService/GetAll"
+	UserServiceUpdate                     = "/service.user.UserService/Update"
+	UserServiceDelete                     = "/service.user.UserService/Delete"
+	UserServiceUnlockAccount              = "/service.user.UserService/UnlockAccount"
+	UserServiceRequirePasswordChange      = "/service.user.UserService/RequirePasswordChange"
+	UserServiceSetRolePasswordHistory     = "/service.user.UserService/SetRolePasswordHistory"
+	UserServiceSetRolePasswordMaxAge      = "/service.user.UserService/SetRolePasswordMaxAge"
+	UserServiceMe                         = "/service.user.UserService/Me"
+	UserServiceSendActivationCode         = "/service.user.UserService/SendActivationCode"
+	UserServiceCheckActivationCode        = "/service.user.UserService/CheckActivationCode"
+	UserServiceSendPhoneVerificationCode  = "/service.user.UserService/SendPhoneVerificationCode"
+	UserServiceCheckPhoneVerificationCode = "/service.user.UserService/CheckPhoneVerificationCode"
+	UserServiceRequestEmailChange         = "/service.user.UserService/RequestEmailChange"
+	UserServiceConfirmEmailChange         = "/service.user.UserService/ConfirmEmailChange"
+)
+
+// mfaTokenMethods can be called with the MFA token of an unfinished login,
+// so that users whose role requires 2FA can enroll before their first login
+var mfaTokenMethods = map[string]bool{
+	AuthServiceEnrollTotp:  true,
+	AuthServiceConfirmTotp: true,
+}
+
+// passwordChangeTokenMethods can be called with the password change token of a login
+// whose password expired or has to be changed
+var passwordChangeTokenMethods = map[string]bool{
+	AuthServiceChangePassword: true,
+}
+
+// recentAuthMethods can only be called within the given time after the user last proved their identity,
+// by logging in or reauthenticating. Updates of users only require it when they change roles,
+// changes of the permissions of roles always do
+var recentAuthMethods = map[string]time.Duration{
+	UserServiceUpdate:             5 * time.Minute,
+	UserServiceDelete:             5 * time.Minute,
+	UserServiceRequestEmailChange: 5 * time.Minute,
+
+	AuthServiceGrantRolePermission:  5 * time.Minute,
+	AuthServiceRevokeRolePermission: 5 * time.Minute,
+}
+
+// impersonationBlockedMethods can not be called with impersonation tokens,
+// support agents must not take over the credentials or the account of a customer
+var impersonationBlockedMethods = map[string]bool{
+	AuthServiceChangePassword:     true,
+	AuthServiceReauthenticate:     true,
+	AuthServiceEnrollTotp:         true,
+	AuthServiceConfirmTotp:        true,
+	AuthServiceImpersonate:        true,
+	AuthServiceRevokeSession:      true,
+	AuthServiceRevokeAllSessions:  true,
+	AuthServiceRemoveKnownDevice:  true,
+	UserServiceDelete:             true,
+	UserServiceRequestEmailChange: true,
+	UserServiceConfirmEmailChange: true,
+}
+
+// clientMethods can be called with service client tokens and organization API keys,
+// if the method is also one of the allowed methods of the client or scopes of the key
+var clientMethods = map[string]bool{
+	AuthServiceIntrospect: true,
+	UserServiceGet:        true,
+}
+
+// serviceClientOnlyMethods are client methods organization API keys can not call
+var serviceClientOnlyMethods = map[string]bool{
+	AuthServiceIntrospect: true,
+}
+
+// methodPermissions maps the methods which require authentication to the permission needed to call them.
+// Which roles have the permissions is kept in the database
+var methodPermissions = map[string]model.Permission{
+	AuthServiceChangePassword:          model.PermissionAccountManage,
+	AuthServiceReauthenticate:          model.PermissionAccountManage,
+	AuthServiceEnrollTotp:              model.PermissionAccountManage,
+	AuthServiceConfirmTotp:             model.PermissionAccountManage,
+	AuthServiceListSessions:            model.PermissionAccountManage,
+	AuthServiceRevokeSession:           model.PermissionAccountManage,
+	AuthServiceRevokeAllSessions:       model.PermissionAccountManage,
+	AuthServiceListKnownDevices:        model.PermissionAccountManage,
+	AuthServiceRemoveKnownDevice:       model.PermissionAccountManage,
+	AuthServiceGetLoginHistory:         model.PermissionLoginHistoryRead,
+	AuthServiceIntrospect:              model.PermissionTokensIntrospect,
+	AuthServiceImpersonate:             model.PermissionUsersImpersonate,
+	AuthServiceSetRoleMfaRequirement:   model.PermissionRolesManage,
+	AuthServiceCreateRole:              model.PermissionRolesManage,
+	AuthServiceGrantRolePermission:     model.PermissionRolesManage,
+	AuthServiceRevokeRolePermission:    model.PermissionRolesManage,
+	AuthServiceRegisterServiceClient:   model.PermissionServiceClientsManage,
+	AuthServiceDeactivateServiceClient: model.PermissionServiceClientsManage,
+	AuthServiceCreateApiKey:            model.PermissionAPIKeysManage,
+	AuthServiceListApiKeys:             model.PermissionAPIKeysManage,
+	AuthServiceUpdateApiKey:            model.PermissionAPIKeysManage,
+	AuthServiceRevokeApiKey:            model.PermissionAPIKeysManage,
+
+	UserServiceCreate:                     model.PermissionUsersCreate,
+	UserServiceGet:                        model.PermissionUsersRead,
+	UserServiceGetAll:                     model.PermissionUsersList,
+	UserServiceUpdate:                     model.PermissionUsersUpdate,
+	UserServiceDelete:                     model.PermissionUsersDelete,
+	UserServiceUnlockAccount:              model.PermissionUsersUnlock,
+	UserServiceRequirePasswordChange:      model.PermissionUsersRequirePasswordChange,
+	UserServiceSetRolePasswordHistory:     model.PermissionRolesManage,
+	UserServiceSetRolePasswordMaxAge:      model.PermissionRolesManage,
+	UserServiceMe:                         model.PermissionProfileRead,
+	UserServiceSendActivationCode:         model.PermissionAccountActivate,
+	UserServiceCheckActivationCode:        model.PermissionAccountActivate,
+	UserServiceSendPhoneVerificationCode:  model.PermissionAccountManage,
+	UserServiceCheckPhoneVerificationCode: model.PermissionAccountManage,
+	UserServiceRequestEmailChange:         model.PermissionAccountManage,
+	UserServiceConfirmEmailChange:         model.PermissionAccountManage,
+}
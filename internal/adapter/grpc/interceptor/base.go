@@ -9,8 +9,10 @@ import (
 )
 
 const (
-	CtxClientIPKey  = "client-ip"
-	CtxRequestIDKey = "request-id"
+	CtxClientIPKey   = "client-ip"
+	CtxRequestIDKey  = "request-id"
+	CtxUserAgentKey  = "user-agent"
+	CtxDeviceNameKey = "device-name"
 )
 
 type BaseInterceptor struct{}
@@ -27,6 +29,8 @@ func (i *BaseInterceptor) Unary(ctx context.Context, req any, _ *grpc.UnaryServe
 
 	ctx = context.WithValue(ctx, CtxRequestIDKey, requestIDFromMetadata(md))
 	ctx = context.WithValue(ctx, CtxClientIPKey, clientIPFromMetadata(md))
+	ctx = context.WithValue(ctx, CtxUserAgentKey, userAgentFromMetadata(md))
+	ctx = context.WithValue(ctx, CtxDeviceNameKey, deviceNameFromMetadata(md))
 
 	return handler(ctx, req)
 }
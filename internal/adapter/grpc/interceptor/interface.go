@@ -1,9 +1,36 @@
 package interceptor
 
-import "time"
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"time"
+)
 
 type JwtProvider interface {
-	GenerateAccessToken(id uint64, roles []string) (string, time.Time, error)
-	GenerateRefreshToken(id uint64, roles []string) (string, time.Time, error)
-	VerifyAndParseClaims(token string) (uint64, []string, error)
+	GenerateAccessToken(claims model.TokenClaims) (string, time.Time, error)
+	GenerateRefreshToken(claims model.TokenClaims) (string, time.Time, error)
+	VerifyAndParseClaims(token string) (model.TokenClaims, error)
+}
+
+type TokenRevocationStorage interface {
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+	UserTokensValidAfter(ctx context.Context, userID uint64) (time.Time, error)
+	ClientTokensValidAfter(ctx context.Context, clientID string) (time.Time, error)
+}
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (model.TokenClaims, error)
+}
+
+type AuditRepository interface {
+	Insert(ctx context.Context, event model.AuditEvent) error
+}
+
+type PermissionRepository interface {
+	Permissions(ctx context.Context) (model.RolePermissions, error)
+}
+
+type PermissionVersionStorage interface {
+	Version(ctx context.Context) (int64, error)
 }
@@ -2,6 +2,7 @@ package interceptor
 
 import (
 	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -23,12 +24,15 @@ func (i *LoggerInterceptor) Unary(ctx context.Context, req any, info *grpc.Unary
 	requestID := ctx.Value(CtxRequestIDKey).(string)
 	clientIP := ctx.Value(CtxClientIPKey).(string)
 
-	logger := i.log.With(
+	// The interceptors and services further down add the request attributes they learn, like the actor of an impersonation
+	ctx = logger.WithRequestAttrs(ctx)
+
+	log := i.log.With(
 		slog.String("requestId", requestID),
 		slog.String("clientIP", clientIP),
 		slog.String("method", info.FullMethod),
 	)
-	logger.Info("grpc request")
+	log.InfoContext(ctx, "grpc request")
 
 	m, err := handler(ctx, req)
 
@@ -42,7 +46,8 @@ func (i *LoggerInterceptor) Unary(ctx context.Context, req any, info *grpc.Unary
 		statusString = codes.OK.String()
 	}
 
-	logger.Info(
+	log.InfoContext(
+		ctx,
 		"grpc response",
 		slog.String("status", statusString),
 	)
@@ -67,3 +72,21 @@ func requestIDFromMetadata(md metadata.MD) string {
 
 	return ""
 }
+
+func userAgentFromMetadata(md metadata.MD) string {
+	userAgents := md.Get("user-agent")
+	if len(userAgents) > 0 {
+		return userAgents[0]
+	}
+
+	return ""
+}
+
+func deviceNameFromMetadata(md metadata.MD) string {
+	deviceNames := md.Get("x-device-name")
+	if len(deviceNames) > 0 {
+		return deviceNames[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,81 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"sync"
+	"time"
+)
+
+// permissionCacheMaxAge bounds how long permissions changed in the database directly,
+// without bumping the version, take to apply
+const permissionCacheMaxAge = time.Minute
+
+// permissionCache keeps the permissions of the roles in memory. Every change of them bumps a version
+// shared by all instances, a cache whose version is behind reloads the permissions from the database
+type permissionCache struct {
+	permissionRepo           PermissionRepository
+	permissionVersionStorage PermissionVersionStorage
+
+	mu          sync.RWMutex
+	loadedAt    time.Time
+	version     int64
+	permissions model.RolePermissions
+}
+
+func newPermissionCache(
+	permissionRepo PermissionRepository,
+	permissionVersionStorage PermissionVersionStorage,
+) *permissionCache {
+	return &permissionCache{
+		permissionRepo:           permissionRepo,
+		permissionVersionStorage: permissionVersionStorage,
+	}
+}
+
+// forRoles returns the permissions granted to any of the roles
+func (c *permissionCache) forRoles(ctx context.Context, roles []model.Role) (map[model.Permission]bool, error) {
+	rolePermissions, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[model.Permission]bool)
+	for _, role := range roles {
+		for permission := range rolePermissions[role] {
+			permissions[permission] = true
+		}
+	}
+
+	return permissions, nil
+}
+
+func (c *permissionCache) get(ctx context.Context) (model.RolePermissions, error) {
+	version, err := c.permissionVersionStorage.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	if c.version == version && time.Since(c.loadedAt) < permissionCacheMaxAge {
+		permissions := c.permissions
+		c.mu.RUnlock()
+
+		return permissions, nil
+	}
+	c.mu.RUnlock()
+
+	// The version is read before loading, a change made meanwhile is loaded by the next request
+	permissions, err := c.permissionRepo.Permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.loadedAt = time.Now()
+	c.version = version
+	c.permissions = permissions
+	c.mu.Unlock()
+
+	return permissions, nil
+}
@@ -13,6 +13,12 @@ import (
 	"net"
 )
 
+// APIKeyService both manages API keys and authenticates the requests made with them
+type APIKeyService interface {
+	handler.APIKeyService
+	interceptor.APIKeyAuthenticator
+}
+
 type Server struct {
 	s   *grpc.Server
 	cfg grpccfg.Config
@@ -23,15 +29,38 @@ func NewServer(
 	cfg grpccfg.Config,
 	log *slog.Logger,
 	authService handler.AuthService,
+	mfaService handler.MFAService,
+	loginHistoryService handler.LoginHistoryService,
+	serviceClientService handler.ServiceClientService,
+	apiKeyService APIKeyService,
+	roleService handler.RoleService,
 	userService handler.UserService,
 	jwtProvider interceptor.JwtProvider,
+	tokenRevocationStorage interceptor.TokenRevocationStorage,
+	auditRepo interceptor.AuditRepository,
+	permissionRepo interceptor.PermissionRepository,
+	permissionVersionStorage interceptor.PermissionVersionStorage,
 ) *Server {
 	server := &Server{
 		cfg: cfg,
 		log: log,
 	}
 
-	server.register(authService, userService, jwtProvider, log)
+	server.register(
+		authService,
+		mfaService,
+		loginHistoryService,
+		serviceClientService,
+		apiKeyService,
+		roleService,
+		userService,
+		jwtProvider,
+		tokenRevocationStorage,
+		auditRepo,
+		permissionRepo,
+		permissionVersionStorage,
+		log,
+	)
 
 	return server
 }
@@ -55,13 +84,30 @@ func (s *Server) Stop() {
 
 func (s *Server) register(
 	authService handler.AuthService,
+	mfaService handler.MFAService,
+	loginHistoryService handler.LoginHistoryService,
+	serviceClientService handler.ServiceClientService,
+	apiKeyService APIKeyService,
+	roleService handler.RoleService,
 	userService handler.UserService,
 	jwtProvider interceptor.JwtProvider,
+	tokenRevocationStorage interceptor.TokenRevocationStorage,
+	auditRepo interceptor.AuditRepository,
+	permissionRepo interceptor.PermissionRepository,
+	permissionVersionStorage interceptor.PermissionVersionStorage,
 	log *slog.Logger,
 ) {
 	baseInterceptor := interceptor.NewBaseInterceptor()
 	loggerInterceptor := interceptor.NewLoggerInterceptor(log)
-	authInterceptor := interceptor.NewAuthInterceptor(jwtProvider)
+	authInterceptor := interceptor.NewAuthInterceptor(
+		log,
+		jwtProvider,
+		tokenRevocationStorage,
+		apiKeyService,
+		auditRepo,
+		permissionRepo,
+		permissionVersionStorage,
+	)
 
 	s.s = grpc.NewServer(grpc.ChainUnaryInterceptor(
 		baseInterceptor.Unary,
@@ -69,7 +115,15 @@ func (s *Server) register(
 		authInterceptor.Unary,
 	))
 
-	authsvc.RegisterAuthServiceServer(s.s, handler.NewAuthHandler(s.log, authService))
+	authsvc.RegisterAuthServiceServer(s.s, handler.NewAuthHandler(
+		s.log,
+		authService,
+		mfaService,
+		loginHistoryService,
+		serviceClientService,
+		apiKeyService,
+		roleService,
+	))
 	usersvc.RegisterUserServiceServer(s.s, handler.NewUserHandler(s.log, userService))
 
 	reflection.Register(s.s)
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"log/slog"
+	"net/http"
+)
+
+type AuthService interface {
+	JWKS(ctx context.Context) []model.JSONWebKey
+}
+
+type JWKSHandler struct {
+	log         *slog.Logger
+	authService AuthService
+}
+
+func NewJWKSHandler(log *slog.Logger, authService AuthService) *JWKSHandler {
+	return &JWKSHandler{
+		log:         log,
+		authService: authService,
+	}
+}
+
+func (h *JWKSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	keySet := struct {
+		Keys []model.JSONWebKey `json:"keys"`
+	}{
+		Keys: h.authService.JWKS(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	err := json.NewEncoder(w).Encode(keySet)
+	if err != nil {
+		h.log.Error("http: encoding jwks", logger.Err(err))
+	}
+}
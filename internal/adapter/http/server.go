@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	httpcfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/http"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type Server struct {
+	s   *http.Server
+	cfg httpcfg.Config
+	log *slog.Logger
+}
+
+func NewServer(
+	cfg httpcfg.Config,
+	log *slog.Logger,
+	authService AuthService,
+) *Server {
+	server := &Server{
+		cfg: cfg,
+		log: log,
+	}
+
+	server.register(authService)
+
+	return server
+}
+
+func (s *Server) MustRun() {
+	go func() {
+		if err := s.run(); err != nil {
+			panic(err)
+		}
+	}()
+}
+
+func (s *Server) Stop() {
+	s.log.Info("stopping http server", slog.String("addr", s.s.Addr))
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.s.Shutdown(ctx); err != nil {
+		s.log.Error("stopping http server", logger.Err(err))
+	}
+}
+
+func (s *Server) register(authService AuthService) {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /.well-known/jwks.json", NewJWKSHandler(s.log, authService))
+
+	s.s = &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port),
+		Handler:      mux,
+		ReadTimeout:  s.cfg.ReadTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
+	}
+}
+
+func (s *Server) run() error {
+	s.log.Info("starting http server", slog.String("addr", s.s.Addr))
+
+	if err := s.s.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"github.com/mailersend/mailersend-go"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/mailer"
+	"html"
 	"time"
 )
 
@@ -27,13 +29,75 @@ func New(cfg mailer.Config) *Mailer {
 }
 
 func (m *Mailer) SendActivationCode(ctx context.Context, receiver, code string) error {
-	c, cancel := context.WithTimeout(ctx, time.Second*5)
-	defer cancel()
-
 	subject := "Activation Code"
 	text := fmt.Sprintf("Your activation code: %s", code)
 	html := fmt.Sprintf("Your activation code: %s", code)
 
+	return m.send(ctx, receiver, subject, text, html)
+}
+
+func (m *Mailer) SendPasswordResetToken(ctx context.Context, receiver, token string) error {
+	subject := "Password Reset"
+	text := fmt.Sprintf("Your password reset token: %s\nIf you did not request a password reset, ignore this email.", token)
+	html := fmt.Sprintf("Your password reset token: %s<br>If you did not request a password reset, ignore this email.", token)
+
+	return m.send(ctx, receiver, subject, text, html)
+}
+
+func (m *Mailer) SendLoginCode(ctx context.Context, receiver, code, link string) error {
+	subject := "Your login code"
+	text := fmt.Sprintf("Your login code: %s\nOr sign in with this link: %s\nIf you did not try to sign in, ignore this email.", code, link)
+	html := fmt.Sprintf("Your login code: %s<br>Or sign in with <a href=\"%s\">this link</a>.<br>If you did not try to sign in, ignore this email.", code, link)
+
+	return m.send(ctx, receiver, subject, text, html)
+}
+
+func (m *Mailer) SendPasswordChangedNotification(ctx context.Context, receiver string) error {
+	subject := "Your password was changed"
+	text := "The password of your account was changed and you were signed out on your other devices.\nIf you did not change it, reset your password right away and contact support."
+	html := "The password of your account was changed and you were signed out on your other devices.<br>If you did not change it, reset your password right away and contact support."
+
+	return m.send(ctx, receiver, subject, text, html)
+}
+
+func (m *Mailer) SendNewDeviceAlert(ctx context.Context, receiver string, device model.KnownDevice, reportLink string) error {
+	// The user agent and device name come from the client and are escaped in the html body
+	deviceName := html.EscapeString(device.DeviceName)
+	userAgent := html.EscapeString(device.UserAgent)
+
+	subject := "New sign-in to your account"
+	text := fmt.Sprintf(
+		"Your account was signed in to from a new device or location.\nDevice: %s\nBrowser: %s\nNetwork: %s\nIf this wasn't you, sign the device out and reset your password: %s",
+		device.DeviceName, device.UserAgent, device.IPRange, reportLink,
+	)
+	html := fmt.Sprintf(
+		"Your account was signed in to from a new device or location.<br>Device: %s<br>Browser: %s<br>Network: %s<br>If this wasn't you, <a href=\"%s\">sign the device out and reset your password</a>.",
+		deviceName, userAgent, device.IPRange, reportLink,
+	)
+
+	return m.send(ctx, receiver, subject, text, html)
+}
+
+func (m *Mailer) SendEmailChangeCode(ctx context.Context, receiver, code string) error {
+	subject := "Confirm your new email"
+	text := fmt.Sprintf("Your email confirmation code: %s", code)
+	html := fmt.Sprintf("Your email confirmation code: %s", code)
+
+	return m.send(ctx, receiver, subject, text, html)
+}
+
+func (m *Mailer) SendEmailChangeNotice(ctx context.Context, receiver, newEmail, cancelToken string) error {
+	subject := "Your email is being changed"
+	text := fmt.Sprintf("A change of your account email to %s was requested.\nIf it was not you, cancel it with this token: %s", newEmail, cancelToken)
+	html := fmt.Sprintf("A change of your account email to %s was requested.<br>If it was not you, cancel it with this token: %s", newEmail, cancelToken)
+
+	return m.send(ctx, receiver, subject, text, html)
+}
+
+func (m *Mailer) send(ctx context.Context, receiver, subject, text, html string) error {
+	c, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
 	recipients := []mailersend.Recipient{
 		{
 			Email: receiver,
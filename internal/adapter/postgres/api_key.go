@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+	"strings"
+)
+
+const apiKeyColumns = `id, name, prefix, key_hash, user_id, organization, scopes, expires_at, revoked_at, created_at`
+
+type APIKeyRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewAPIKeyRepository(log *slog.Logger, db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+func (r *APIKeyRepository) Insert(ctx context.Context, key model.APIKey) (uint64, error) {
+	var id uint64
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO api_keys
+		(name, prefix, key_hash, user_id, organization, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.UserID,
+		key.Organization,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		key.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "api_keys_user_id_fkey" {
+			return 0, model.ErrNotFound
+		}
+
+		return 0, model.ErrSql
+	}
+
+	return id, nil
+}
+
+func (r *APIKeyRepository) FindOne(ctx context.Context, id uint64) (model.APIKey, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id)
+
+	return scanAPIKey(row)
+}
+
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash []byte) (model.APIKey, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash)
+
+	return scanAPIKey(row)
+}
+
+func (r *APIKeyRepository) Find(ctx context.Context, filter model.APIKeyFilter) ([]model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys`
+
+	var whereClauses []string
+	var args []any
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		whereClauses = append(whereClauses, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.Organization != nil {
+		args = append(args, *filter.Organization)
+		whereClauses = append(whereClauses, fmt.Sprintf("organization = $%d", len(args)))
+	}
+	if len(whereClauses) > 0 {
+		query += " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+	query += " ORDER BY id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, model.ErrSql
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if rows.Err() != nil {
+		return nil, model.ErrSql
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepository) Update(ctx context.Context, id uint64, update model.APIKeyUpdate) error {
+	var setClauses []string
+	var args []any
+	if update.Scopes != nil {
+		args = append(args, pq.Array(*update.Scopes))
+		setClauses = append(setClauses, fmt.Sprintf("scopes = $%d", len(args)))
+	}
+	if update.ExpiresAt != nil {
+		args = append(args, *update.ExpiresAt)
+		setClauses = append(setClauses, fmt.Sprintf("expires_at = $%d", len(args)))
+	}
+	if update.RevokedAt != nil {
+		args = append(args, *update.RevokedAt)
+		setClauses = append(setClauses, fmt.Sprintf("revoked_at = $%d", len(args)))
+	}
+	if len(setClauses) == 0 {
+		return model.ErrNoUpdateFields
+	}
+
+	args = append(args, id)
+	query := fmt.Sprintf("UPDATE api_keys SET %s WHERE id = $%d", strings.Join(setClauses, ", "), len(args))
+
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (model.APIKey, error) {
+	var k model.APIKey
+	var userID sql.NullInt64
+	var organization sql.NullString
+	var expiresAt, revokedAt sql.NullTime
+
+	err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		&userID,
+		&organization,
+		pq.Array(&k.Scopes),
+		&expiresAt,
+		&revokedAt,
+		&k.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.APIKey{}, model.ErrNotFound
+		}
+
+		return model.APIKey{}, model.ErrSql
+	}
+
+	if userID.Valid {
+		id := uint64(userID.Int64)
+		k.UserID = &id
+	}
+	if organization.Valid {
+		k.Organization = &organization.String
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+
+	return k, nil
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+)
+
+// AuditRepository keeps audit events. Users are not referenced by a foreign key,
+// so that the events of deleted users are kept
+type AuditRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewAuditRepository(log *slog.Logger, db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+func (r *AuditRepository) Insert(ctx context.Context, event model.AuditEvent) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO audit_events
+		(actor_id, user_id, action, method, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		event.ActorID,
+		event.UserID,
+		event.Action,
+		event.Method,
+		event.Reason,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
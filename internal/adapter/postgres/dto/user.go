@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
 )
@@ -46,6 +47,11 @@ func WhereClausesFromFilter(filter model.UserFilter, args []any, argNumber int)
 		args = append(args, *filter.IsConfirmed)
 		argNumber++
 	}
+	if filter.PasswordHash != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("password_hash = $%d", argNumber))
+		args = append(args, *filter.PasswordHash)
+		argNumber++
+	}
 
 	return whereClauses, args
 }
@@ -62,7 +68,7 @@ func SetClausesFromUpdateData(update model.UserUpdate) ([]string, []any, int) {
 	}
 	if update.PhoneNumber != nil {
 		setClauses = append(setClauses, fmt.Sprintf("phone_number = $%d", argNumber))
-		args = append(args, *update.PhoneNumber)
+		args = append(args, NullString(*update.PhoneNumber))
 		argNumber++
 	}
 	if update.FirstName != nil {
@@ -85,6 +91,16 @@ func SetClausesFromUpdateData(update model.UserUpdate) ([]string, []any, int) {
 		args = append(args, *update.PasswordHash)
 		argNumber++
 	}
+	if update.PasswordChangedAt != nil {
+		setClauses = append(setClauses, fmt.Sprintf("password_changed_at = $%d", argNumber))
+		args = append(args, *update.PasswordChangedAt)
+		argNumber++
+	}
+	if update.MustChangePassword != nil {
+		setClauses = append(setClauses, fmt.Sprintf("must_change_password = $%d", argNumber))
+		args = append(args, *update.MustChangePassword)
+		argNumber++
+	}
 	if update.IsActive != nil {
 		setClauses = append(setClauses, fmt.Sprintf("is_active = $%d", argNumber))
 		args = append(args, *update.IsActive)
@@ -95,9 +111,23 @@ func SetClausesFromUpdateData(update model.UserUpdate) ([]string, []any, int) {
 		args = append(args, *update.IsConfirmed)
 		argNumber++
 	}
+	if update.IsPhoneVerified != nil {
+		setClauses = append(setClauses, fmt.Sprintf("is_phone_verified = $%d", argNumber))
+		args = append(args, *update.IsPhoneVerified)
+		argNumber++
+	}
 	setClauses = append(setClauses, fmt.Sprintf("updated_at = $%d", argNumber))
 	args = append(args, update.UpdatedAt)
 	argNumber++
 
 	return setClauses, args, argNumber
 }
+
+// NullString maps an empty string to NULL, so that optional unique columns
+// like phone_number do not collide on empty values
+func NullString(s string) sql.NullString {
+	return sql.NullString{
+		String: s,
+		Valid:  s != "",
+	}
+}
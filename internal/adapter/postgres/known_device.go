@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+	"time"
+)
+
+// KnownDeviceRepository keeps the devices users have logged in on
+type KnownDeviceRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewKnownDeviceRepository(log *slog.Logger, db *sql.DB) *KnownDeviceRepository {
+	return &KnownDeviceRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Insert saves a new device of the user. A device with the same fingerprint saved
+// by a concurrent login is updated instead
+func (r *KnownDeviceRepository) Insert(ctx context.Context, device model.KnownDevice) (uint64, error) {
+	var id uint64
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO known_devices
+		(user_id, fingerprint, device_name, user_agent, ip_range, created_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, fingerprint)
+		DO UPDATE SET ip_range = EXCLUDED.ip_range, last_seen_at = EXCLUDED.last_seen_at
+		RETURNING id`,
+		device.UserID,
+		device.Fingerprint,
+		device.DeviceName,
+		device.UserAgent,
+		device.IPRange,
+		device.CreatedAt,
+		device.LastSeenAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, model.ErrSql
+	}
+
+	return id, nil
+}
+
+// Find returns the devices of the user, most recently seen first
+func (r *KnownDeviceRepository) Find(ctx context.Context, userID uint64) ([]model.KnownDevice, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT id, user_id, fingerprint, device_name, user_agent, ip_range, created_at, last_seen_at
+		FROM known_devices
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, model.ErrSql
+	}
+	defer rows.Close()
+
+	var devices []model.KnownDevice
+	for rows.Next() {
+		var d model.KnownDevice
+
+		err = rows.Scan(
+			&d.ID,
+			&d.UserID,
+			&d.Fingerprint,
+			&d.DeviceName,
+			&d.UserAgent,
+			&d.IPRange,
+			&d.CreatedAt,
+			&d.LastSeenAt,
+		)
+		if err != nil {
+			return nil, model.ErrSql
+		}
+
+		devices = append(devices, d)
+	}
+	if rows.Err() != nil {
+		return nil, model.ErrSql
+	}
+
+	return devices, nil
+}
+
+// UpdateLastSeen marks the device as seen, an empty ipRange keeps the stored one
+func (r *KnownDeviceRepository) UpdateLastSeen(ctx context.Context, id uint64, ipRange string, lastSeenAt time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE known_devices SET ip_range = COALESCE(NULLIF($1, ''), ip_range), last_seen_at = $2 WHERE id = $3`,
+		ipRange,
+		lastSeenAt,
+		id,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+// Delete removes a device of the user, model.ErrNotFound is returned if the user has no such device
+func (r *KnownDeviceRepository) Delete(ctx context.Context, userID, id uint64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM known_devices WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+	"time"
+)
+
+// LoginEventRepository keeps the login attempts of users
+type LoginEventRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewLoginEventRepository(log *slog.Logger, db *sql.DB) *LoginEventRepository {
+	return &LoginEventRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+func (r *LoginEventRepository) Insert(ctx context.Context, event model.LoginEvent) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO login_events
+		(user_id, method, outcome, client_ip, user_agent, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		event.UserID,
+		event.Method,
+		event.Outcome,
+		event.ClientIP,
+		event.UserAgent,
+		event.RequestID,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+// Find returns up to limit events of the user older than the event beforeID, newest first.
+// A beforeID of 0 starts from the newest event
+func (r *LoginEventRepository) Find(ctx context.Context, userID, beforeID uint64, limit int) ([]model.LoginEvent, error) {
+	query := `
+		SELECT id, user_id, method, outcome, client_ip, user_agent, request_id, created_at
+		FROM login_events
+		WHERE user_id = $1`
+	args := []any{userID}
+	if beforeID != 0 {
+		args = append(args, beforeID)
+		query += fmt.Sprintf(" AND id < $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, model.ErrSql
+	}
+	defer rows.Close()
+
+	var events []model.LoginEvent
+	for rows.Next() {
+		var e model.LoginEvent
+		var eventUserID sql.NullInt64
+
+		err = rows.Scan(
+			&e.ID,
+			&eventUserID,
+			&e.Method,
+			&e.Outcome,
+			&e.ClientIP,
+			&e.UserAgent,
+			&e.RequestID,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, model.ErrSql
+		}
+
+		if eventUserID.Valid {
+			id := uint64(eventUserID.Int64)
+			e.UserID = &id
+		}
+
+		events = append(events, e)
+	}
+	if rows.Err() != nil {
+		return nil, model.ErrSql
+	}
+
+	return events, nil
+}
+
+// DeleteBefore removes the events created before the time and returns how many were removed
+func (r *LoginEventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM login_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, model.ErrSql
+	}
+
+	return rowsAffected, nil
+}
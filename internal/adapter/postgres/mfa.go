@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+	"time"
+)
+
+type MFARepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewMFARepository(log *slog.Logger, db *sql.DB) *MFARepository {
+	return &MFARepository{
+		log: log,
+		db:  db,
+	}
+}
+
+func (r *MFARepository) FindTOTP(ctx context.Context, userID uint64) (model.TOTP, error) {
+	query := `
+		SELECT user_id, secret, is_confirmed, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id = $1`
+
+	var t model.TOTP
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.IsConfirmed,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TOTP{}, model.ErrNotFound
+		}
+
+		return model.TOTP{}, model.ErrSql
+	}
+
+	return t, nil
+}
+
+// SaveTOTP starts a new enrollment of the user, replacing an unconfirmed one
+func (r *MFARepository) SaveTOTP(ctx context.Context, totp model.TOTP) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO user_totp
+		(user_id, secret, is_confirmed, last_used_step, created_at)
+		VALUES ($1, $2, FALSE, 0, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+		WHERE user_totp.is_confirmed = FALSE`,
+		totp.UserID,
+		totp.Secret,
+		totp.CreatedAt,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+// ConfirmTOTP enables the enrollment of the user and replaces the recovery codes
+func (r *MFARepository) ConfirmTOTP(ctx context.Context, userID uint64, recoveryCodeHashes [][]byte, confirmedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.ErrSqlTransaction
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`
+		UPDATE user_totp
+		SET is_confirmed = TRUE, confirmed_at = $2
+		WHERE user_id = $1 AND is_confirmed = FALSE`,
+		userID,
+		confirmedAt,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO user_recovery_codes
+			(user_id, code_hash)
+			VALUES ($1, $2)`,
+			userID,
+			codeHash,
+		)
+		if err != nil {
+			return model.ErrSql
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return model.ErrSqlTransaction
+	}
+
+	return nil
+}
+
+// UseTOTPStep marks the time step as used. It returns model.ErrNotFound
+// if a code of the same or a later step was already accepted
+func (r *MFARepository) UseTOTPStep(ctx context.Context, userID uint64, step int64) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		`
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND is_confirmed = TRUE AND last_used_step < $2`,
+		userID,
+		step,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+// UseRecoveryCode deletes the recovery code so it can not be used again.
+// It returns model.ErrNotFound if the user has no such code
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID uint64, codeHash []byte) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM user_recovery_codes WHERE user_id = $1 AND code_hash = $2`,
+		userID,
+		codeHash,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
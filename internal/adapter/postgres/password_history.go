@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+	"time"
+)
+
+// PasswordHistoryRepository keeps the hash of every password a user has set
+type PasswordHistoryRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewPasswordHistoryRepository(log *slog.Logger, db *sql.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+func (r *PasswordHistoryRepository) Insert(ctx context.Context, userID uint64, passwordHash []byte, createdAt time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, $3)`,
+		userID,
+		passwordHash,
+		createdAt,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+// FindRecent returns the hashes of the last limit passwords of the user, newest first
+func (r *PasswordHistoryRepository) FindRecent(ctx context.Context, userID uint64, limit int) ([][]byte, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT password_hash FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		userID,
+		limit,
+	)
+	if err != nil {
+		return nil, model.ErrSql
+	}
+	defer rows.Close()
+
+	var hashes [][]byte
+	for rows.Next() {
+		var hash []byte
+
+		err = rows.Scan(&hash)
+		if err != nil {
+			return nil, model.ErrSql
+		}
+
+		hashes = append(hashes, hash)
+	}
+	if rows.Err() != nil {
+		return nil, model.ErrSql
+	}
+
+	return hashes, nil
+}
@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+)
+
+// RoleRepository keeps the roles, their settings and the permissions granted to them
+type RoleRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewRoleRepository(log *slog.Logger, db *sql.DB) *RoleRepository {
+	return &RoleRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// IsMFARequired reports whether any of the roles requires two-factor authentication
+func (r *RoleRepository) IsMFARequired(ctx context.Context, roles []model.Role) (bool, error) {
+	roleNames := make([]string, len(roles))
+	for i, role := range roles {
+		roleNames[i] = string(role)
+	}
+
+	var required bool
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM roles WHERE name = ANY($1) AND mfa_required)`,
+		pq.Array(roleNames),
+	).Scan(&required)
+	if err != nil {
+		return false, model.ErrSql
+	}
+
+	return required, nil
+}
+
+// PasswordHistorySize returns the largest number of recent passwords any of the roles forbids reusing
+func (r *RoleRepository) PasswordHistorySize(ctx context.Context, roles []model.Role) (int, error) {
+	roleNames := make([]string, len(roles))
+	for i, role := range roles {
+		roleNames[i] = string(role)
+	}
+
+	var size int
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(MAX(password_history_size), 0) FROM roles WHERE name = ANY($1)`,
+		pq.Array(roleNames),
+	).Scan(&size)
+	if err != nil {
+		return 0, model.ErrSql
+	}
+
+	return size, nil
+}
+
+// PasswordMaxAge returns the shortest number of days any of the roles lets a password be used for.
+// Zero means the passwords of the roles do not expire
+func (r *RoleRepository) PasswordMaxAge(ctx context.Context, roles []model.Role) (int, error) {
+	roleNames := make([]string, len(roles))
+	for i, role := range roles {
+		roleNames[i] = string(role)
+	}
+
+	var days int
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(MIN(password_max_age_days), 0) FROM roles WHERE name = ANY($1) AND password_max_age_days > 0`,
+		pq.Array(roleNames),
+	).Scan(&days)
+	if err != nil {
+		return 0, model.ErrSql
+	}
+
+	return days, nil
+}
+
+func (r *RoleRepository) SetPasswordMaxAge(ctx context.Context, role model.Role, days int) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE roles SET password_max_age_days = $2 WHERE name = $1`,
+		string(role),
+		days,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) SetPasswordHistorySize(ctx context.Context, role model.Role, size int) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE roles SET password_history_size = $2 WHERE name = $1`,
+		string(role),
+		size,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) SetMFARequired(ctx context.Context, role model.Role, required bool) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE roles SET mfa_required = $2 WHERE name = $1`,
+		string(role),
+		required,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+// Create adds a role without permissions
+func (r *RoleRepository) Create(ctx context.Context, role model.Role) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO roles (name) VALUES ($1)`, string(role))
+	if err != nil {
+		var pqErr *pq.Error
+
+		if errors.As(err, &pqErr) && pqErr.Constraint == "roles_name_key" {
+			return model.ErrDuplicateRole
+		}
+
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+// Permissions returns the permissions granted to every role
+func (r *RoleRepository) Permissions(ctx context.Context) (model.RolePermissions, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT r.name, p.name
+		FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		JOIN permissions p ON p.id = rp.permission_id`,
+	)
+	if err != nil {
+		return nil, model.ErrSql
+	}
+	defer rows.Close()
+
+	permissions := make(model.RolePermissions)
+	for rows.Next() {
+		var role model.Role
+		var permission model.Permission
+
+		err = rows.Scan(&role, &permission)
+		if err != nil {
+			return nil, model.ErrSql
+		}
+
+		if permissions[role] == nil {
+			permissions[role] = make(map[model.Permission]bool)
+		}
+		permissions[role][permission] = true
+	}
+	if rows.Err() != nil {
+		return nil, model.ErrSql
+	}
+
+	return permissions, nil
+}
+
+// GrantPermission grants the permission to the role, granting it again changes nothing
+func (r *RoleRepository) GrantPermission(ctx context.Context, role model.Role, permission model.Permission) error {
+	roleID, permissionID, err := r.findRoleAndPermission(ctx, role, permission)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO role_permissions
+		(role_id, permission_id)
+		VALUES ($1, $2)
+		ON CONFLICT (role_id, permission_id) DO NOTHING`,
+		roleID,
+		permissionID,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+// RevokePermission takes the permission away from the role, revoking a permission the role lacks changes nothing
+func (r *RoleRepository) RevokePermission(ctx context.Context, role model.Role, permission model.Permission) error {
+	roleID, permissionID, err := r.findRoleAndPermission(ctx, role, permission)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		`DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`,
+		roleID,
+		permissionID,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+// findRoleAndPermission returns the IDs of the role and the permission,
+// ErrNotFound when there is no such role and ErrInvalidPermission when there is no such permission
+func (r *RoleRepository) findRoleAndPermission(
+	ctx context.Context,
+	role model.Role,
+	permission model.Permission,
+) (int64, int64, error) {
+	var roleID, permissionID sql.NullInt64
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT (SELECT id FROM roles WHERE name = $1), (SELECT id FROM permissions WHERE name = $2)`,
+		string(role),
+		string(permission),
+	).Scan(&roleID, &permissionID)
+	if err != nil {
+		return 0, 0, model.ErrSql
+	}
+
+	if !roleID.Valid {
+		return 0, 0, model.ErrNotFound
+	}
+	if !permissionID.Valid {
+		return 0, 0, model.ErrInvalidPermission
+	}
+
+	return roleID.Int64, permissionID.Int64, nil
+}
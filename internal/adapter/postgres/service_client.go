@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+)
+
+type ServiceClientRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewServiceClientRepository(log *slog.Logger, db *sql.DB) *ServiceClientRepository {
+	return &ServiceClientRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+func (r *ServiceClientRepository) Insert(ctx context.Context, client model.ServiceClient) (uint64, error) {
+	var id uint64
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO service_clients
+		(client_id, name, secret_hash, allowed_methods, is_active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		client.ClientID,
+		client.Name,
+		client.SecretHash,
+		pq.Array(client.AllowedMethods),
+		client.IsActive,
+		client.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, model.ErrSql
+	}
+
+	return id, nil
+}
+
+func (r *ServiceClientRepository) FindByClientID(ctx context.Context, clientID string) (model.ServiceClient, error) {
+	query := `
+		SELECT id, client_id, name, secret_hash, allowed_methods, is_active, created_at
+		FROM service_clients
+		WHERE client_id = $1`
+
+	var c model.ServiceClient
+
+	err := r.db.QueryRowContext(ctx, query, clientID).Scan(
+		&c.ID,
+		&c.ClientID,
+		&c.Name,
+		&c.SecretHash,
+		pq.Array(&c.AllowedMethods),
+		&c.IsActive,
+		&c.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ServiceClient{}, model.ErrNotFound
+		}
+
+		return model.ServiceClient{}, model.ErrSql
+	}
+
+	return c, nil
+}
+
+func (r *ServiceClientRepository) Deactivate(ctx context.Context, clientID string) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE service_clients SET is_active = FALSE WHERE client_id = $1`,
+		clientID,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.ErrSql
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
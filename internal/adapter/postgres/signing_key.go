@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"log/slog"
+)
+
+type SigningKeyRepository struct {
+	log *slog.Logger
+	db  *sql.DB
+}
+
+func NewSigningKeyRepository(log *slog.Logger, db *sql.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+func (r *SigningKeyRepository) FindAll(ctx context.Context) ([]model.SigningKey, error) {
+	query := `
+		SELECT id, algorithm, private_key, created_at
+		FROM jwt_signing_keys`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, model.ErrSql
+	}
+	defer rows.Close()
+
+	var keys []model.SigningKey
+	for rows.Next() {
+		var k model.SigningKey
+
+		err = rows.Scan(&k.ID, &k.Algorithm, &k.PrivateKey, &k.CreatedAt)
+		if err != nil {
+			return nil, model.ErrSql
+		}
+
+		keys = append(keys, k)
+	}
+
+	if rows.Err() != nil {
+		return nil, model.ErrSql
+	}
+
+	return keys, nil
+}
+
+func (r *SigningKeyRepository) Insert(ctx context.Context, key model.SigningKey) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO jwt_signing_keys
+		(id, algorithm, private_key, created_at)
+		VALUES ($1, $2, $3, $4)`,
+		key.ID,
+		key.Algorithm,
+		key.PrivateKey,
+		key.CreatedAt,
+	)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
+
+func (r *SigningKeyRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM jwt_signing_keys WHERE id = $1`, id)
+	if err != nil {
+		return model.ErrSql
+	}
+
+	return nil
+}
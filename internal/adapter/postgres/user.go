@@ -36,17 +36,20 @@ func (r *UserRepository) Insert(ctx context.Context, user model.User) (uint64, e
 		`
 		INSERT INTO users
 		(email, phone_number, first_name, last_name, birth_date, 
-		 password_hash, is_active, is_confirmed, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 password_hash, is_active, is_confirmed, password_changed_at,
+		 must_change_password, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id`,
 		user.Email,
-		user.PhoneNumber,
+		dto.NullString(user.PhoneNumber),
 		user.FirstName,
 		user.LastName,
 		user.BirthDate,
 		user.PasswordHash,
 		user.IsActive,
 		user.IsConfirmed,
+		user.PasswordChangedAt,
+		user.MustChangePassword,
 		user.CreatedAt,
 		user.UpdatedAt,
 	).Scan(&userID)
@@ -57,26 +60,15 @@ func (r *UserRepository) Insert(ctx context.Context, user model.User) (uint64, e
 			return 0, model.ErrDuplicateEmail
 		}
 		if errors.As(err, &pqErr) && pqErr.Constraint == "users_phone_number_key" {
-			return 0, model.ErrDuplicateEmail
+			return 0, model.ErrDuplicatePhoneNumber
 		}
 
 		return 0, model.ErrSql
 	}
 
-	roles := user.Roles
-	for _, role := range roles {
-		_, err = tx.ExecContext(
-			ctx,
-			`
-			INSERT INTO user_roles
-			(user_id, role_id)
-			VALUES ($1, $2)`,
-			userID,
-			uint32(role),
-		)
-		if err != nil {
-			return 0, model.ErrSql
-		}
+	err = insertUserRoles(ctx, tx, uint64(userID), user.Roles)
+	if err != nil {
+		return 0, err
 	}
 
 	if tx.Commit() != nil {
@@ -88,8 +80,9 @@ func (r *UserRepository) Insert(ctx context.Context, user model.User) (uint64, e
 
 func (r *UserRepository) FindOne(ctx context.Context, filter model.UserFilter) (model.User, error) {
 	query := `
-        SELECT id, email, phone_number, first_name, last_name, 
+        SELECT id, email, COALESCE(phone_number, ''), first_name, last_name, 
                birth_date, password_hash, is_active, is_confirmed,
+               is_phone_verified, password_changed_at, must_change_password,
                created_at, updated_at
         FROM users`
 
@@ -103,6 +96,7 @@ func (r *UserRepository) FindOne(ctx context.Context, filter model.UserFilter) (
 	err := r.db.QueryRowContext(ctx, query, args...).Scan(
 		&u.ID, &u.Email, &u.PhoneNumber, &u.FirstName, &u.LastName,
 		&u.BirthDate, &u.PasswordHash, &u.IsActive, &u.IsConfirmed,
+		&u.IsPhoneVerified, &u.PasswordChangedAt, &u.MustChangePassword,
 		&u.CreatedAt, &u.UpdatedAt,
 	)
 	if err != nil {
@@ -123,8 +117,9 @@ func (r *UserRepository) FindOne(ctx context.Context, filter model.UserFilter) (
 
 func (r *UserRepository) Find(ctx context.Context, filter model.UserFilter) ([]model.User, error) {
 	query := `
-        SELECT id, email, phone_number, first_name, last_name, 
+        SELECT id, email, COALESCE(phone_number, ''), first_name, last_name, 
                birth_date, password_hash, is_active, is_confirmed,
+               is_phone_verified, password_changed_at, must_change_password,
                created_at, updated_at
         FROM users
     `
@@ -147,6 +142,7 @@ func (r *UserRepository) Find(ctx context.Context, filter model.UserFilter) ([]m
 		err := rows.Scan(
 			&u.ID, &u.Email, &u.PhoneNumber, &u.FirstName, &u.LastName,
 			&u.BirthDate, &u.PasswordHash, &u.IsActive, &u.IsConfirmed,
+			&u.IsPhoneVerified, &u.PasswordChangedAt, &u.MustChangePassword,
 			&u.CreatedAt, &u.UpdatedAt,
 		)
 		if err != nil {
@@ -171,9 +167,10 @@ func (r *UserRepository) Find(ctx context.Context, filter model.UserFilter) ([]m
 
 func (r *UserRepository) findRolesForUser(ctx context.Context, userId uint64) ([]model.Role, error) {
 	query := `
-		SELECT role_id
-		FROM user_roles
-		WHERE user_id = $1`
+		SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1`
 
 	rows, err := r.db.QueryContext(ctx, query, userId)
 	if err != nil {
@@ -196,6 +193,34 @@ func (r *UserRepository) findRolesForUser(ctx context.Context, userId uint64) ([
 	return roles, nil
 }
 
+// insertUserRoles gives the user the roles by their names, ErrInvalidRole when one of them does not exist
+func insertUserRoles(ctx context.Context, tx *sql.Tx, userID uint64, roles []model.Role) error {
+	for _, role := range roles {
+		res, err := tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO user_roles
+			(user_id, role_id)
+			SELECT $1, id FROM roles WHERE name = $2`,
+			userID,
+			string(role),
+		)
+		if err != nil {
+			return model.ErrSql
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return model.ErrSql
+		}
+		if rowsAffected == 0 {
+			return model.ErrInvalidRole
+		}
+	}
+
+	return nil
+}
+
 func (r *UserRepository) Update(ctx context.Context, filter model.UserFilter, update model.UserUpdate) error {
 	query := "UPDATE users SET "
 
@@ -227,7 +252,9 @@ func (r *UserRepository) Update(ctx context.Context, filter model.UserFilter, up
 		case errors.Is(err, sql.ErrNoRows):
 			return model.ErrNotFound
 		case errors.As(err, &pqErr) && pqErr.Constraint == "users_email_key":
-			return model.ErrSqlTransaction
+			return model.ErrDuplicateEmail
+		case errors.As(err, &pqErr) && pqErr.Constraint == "users_phone_number_key":
+			return model.ErrDuplicatePhoneNumber
 		default:
 			return model.ErrSql
 		}
@@ -241,13 +268,9 @@ func (r *UserRepository) Update(ctx context.Context, filter model.UserFilter, up
 			return model.ErrSql
 		}
 
-		for _, role := range *update.Roles {
-			query = "INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2)"
-
-			_, err = tx.ExecContext(ctx, query, userID, uint32(role))
-			if err != nil {
-				return model.ErrSql
-			}
+		err = insertUserRoles(ctx, tx, userID, *update.Roles)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -42,8 +42,11 @@ func (rc *ActivationCodeRedisCache) Save(ctx context.Context, userID uint64) (st
 	}
 
 	err = rc.rdb.Set(ctx, rc.key(userID), codeHash, codeExpiration).Err()
+	if err != nil {
+		return "", model.ErrRedis
+	}
 
-	return code, model.ErrRedis
+	return code, nil
 }
 
 func (rc *ActivationCodeRedisCache) Get(ctx context.Context, userID uint64) ([]byte, error) {
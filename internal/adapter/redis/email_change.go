@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"time"
+)
+
+const (
+	emailChangeKeyPrefix       = "user:email_change"
+	emailChangeCancelKeyPrefix = "user:email_change:cancel"
+	emailChangeExpiration      = 30 * time.Minute
+	emailChangeCancelTokenLen  = 32
+)
+
+// EmailChangeRedisCache stores the pending email change of a user until it is
+// confirmed with the code sent to the new address or canceled from the old one
+type EmailChangeRedisCache struct {
+	rdb *redis.Client
+}
+
+func NewEmailChangeRedisCache(client *redis.Client) *EmailChangeRedisCache {
+	return &EmailChangeRedisCache{
+		rdb: client,
+	}
+}
+
+func (rc *EmailChangeRedisCache) key(userID uint64) string {
+	return fmt.Sprintf("%s:%d", emailChangeKeyPrefix, userID)
+}
+
+func (rc *EmailChangeRedisCache) cancelKey(cancelToken string) string {
+	return fmt.Sprintf("%s:%s", emailChangeCancelKeyPrefix, hex.EncodeToString(security.SHA256(cancelToken)))
+}
+
+// Save stages the new email of the user, replacing a pending change,
+// and returns the confirmation code and the cancel token
+func (rc *EmailChangeRedisCache) Save(ctx context.Context, userID uint64, email string) (string, string, error) {
+	code := createCode()
+
+	codeHash, err := security.HashString(code)
+	if err != nil {
+		return "", "", err
+	}
+
+	cancelToken := security.RandomString(emailChangeCancelTokenLen)
+
+	_, err = rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, rc.key(userID))
+		pipe.HSet(ctx, rc.key(userID), "email", email, "code_hash", codeHash)
+		pipe.Expire(ctx, rc.key(userID), emailChangeExpiration)
+		pipe.Set(ctx, rc.cancelKey(cancelToken), userID, emailChangeExpiration)
+
+		return nil
+	})
+	if err != nil {
+		return "", "", model.ErrRedis
+	}
+
+	return code, cancelToken, nil
+}
+
+func (rc *EmailChangeRedisCache) Get(ctx context.Context, userID uint64) (model.PendingEmailChange, error) {
+	fields, err := rc.rdb.HGetAll(ctx, rc.key(userID)).Result()
+	if err != nil {
+		return model.PendingEmailChange{}, model.ErrRedis
+	}
+
+	if len(fields) == 0 {
+		return model.PendingEmailChange{}, model.ErrNotFound
+	}
+
+	return model.PendingEmailChange{
+		UserID:   userID,
+		Email:    fields["email"],
+		CodeHash: []byte(fields["code_hash"]),
+	}, nil
+}
+
+func (rc *EmailChangeRedisCache) Delete(ctx context.Context, userID uint64) error {
+	err := rc.rdb.Del(ctx, rc.key(userID)).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+// AddFailure counts a wrong confirmation code of the pending change and returns the number of failures
+func (rc *EmailChangeRedisCache) AddFailure(ctx context.Context, userID uint64) (int64, error) {
+	failures, err := addCodeFailureScript.Run(ctx, rc.rdb, []string{rc.key(userID)}).Int64()
+	if err != nil {
+		return 0, model.ErrRedis
+	}
+
+	if failures == 0 {
+		return 0, model.ErrNotFound
+	}
+
+	return failures, nil
+}
+
+// Cancel deletes the pending email change the cancel token was issued for
+// and returns its user
+func (rc *EmailChangeRedisCache) Cancel(ctx context.Context, cancelToken string) (uint64, error) {
+	userID, err := rc.rdb.GetDel(ctx, rc.cancelKey(cancelToken)).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, model.ErrNotFound
+		}
+
+		return 0, model.ErrRedis
+	}
+
+	err = rc.Delete(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"time"
+)
+
+const (
+	loginFailuresKeyPrefix = "user:login:failures"
+	loginLockKeyPrefix     = "user:login:lock"
+	loginFailuresWindow    = time.Hour
+)
+
+// LoginAttemptRedisCache counts failed logins and keeps temporary lockouts
+// of a subject, which is an account or a client address
+type LoginAttemptRedisCache struct {
+	rdb *redis.Client
+}
+
+func NewLoginAttemptRedisCache(client *redis.Client) *LoginAttemptRedisCache {
+	return &LoginAttemptRedisCache{
+		rdb: client,
+	}
+}
+
+func (rc *LoginAttemptRedisCache) failuresKey(subject string) string {
+	return fmt.Sprintf("%s:%s", loginFailuresKeyPrefix, subject)
+}
+
+func (rc *LoginAttemptRedisCache) lockKey(subject string) string {
+	return fmt.Sprintf("%s:%s", loginLockKeyPrefix, subject)
+}
+
+// AddFailure increments the failed attempts of the subject and returns the new count.
+// The counter is forgotten after a window without failures
+func (rc *LoginAttemptRedisCache) AddFailure(ctx context.Context, subject string) (int64, error) {
+	var incr *redis.IntCmd
+
+	_, err := rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, rc.failuresKey(subject))
+		pipe.Expire(ctx, rc.failuresKey(subject), loginFailuresWindow)
+
+		return nil
+	})
+	if err != nil {
+		return 0, model.ErrRedis
+	}
+
+	return incr.Val(), nil
+}
+
+func (rc *LoginAttemptRedisCache) Lock(ctx context.Context, subject string, duration time.Duration) error {
+	err := rc.rdb.Set(ctx, rc.lockKey(subject), true, duration).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+// LockedFor returns how long the subject stays locked, zero if it is not locked
+func (rc *LoginAttemptRedisCache) LockedFor(ctx context.Context, subject string) (time.Duration, error) {
+	ttl, err := rc.rdb.PTTL(ctx, rc.lockKey(subject)).Result()
+	if err != nil {
+		return 0, model.ErrRedis
+	}
+
+	// PTTL returns negative values for missing keys
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Reset removes the failed attempts and the lockout of the subject
+func (rc *LoginAttemptRedisCache) Reset(ctx context.Context, subject string) error {
+	err := rc.rdb.Del(ctx, rc.failuresKey(subject), rc.lockKey(subject)).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
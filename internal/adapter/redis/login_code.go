@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"time"
+)
+
+const loginCodeKeyPrefix = "user:code:login"
+
+const (
+	loginCodeHashField        = "code_hash"
+	loginCodeLinkTokenIDField = "link_token_id"
+	loginCodeAttemptsField    = "attempts"
+)
+
+// addCodeFailureScript counts a failed attempt of a pending code stored in a hash,
+// like login, email change and phone verification codes.
+// Returns the number of failed attempts, or 0 if there is no pending code
+var addCodeFailureScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+return redis.call("HINCRBY", KEYS[1], "attempts", 1)
+`)
+
+// LoginCodeRedisCache stores the hashed one-time code of a passwordless login
+// together with the ID of the magic link token sent with it. Every user
+// has at most one pending code, requesting a new one invalidates the previous
+type LoginCodeRedisCache struct {
+	rdb     *redis.Client
+	codeTTL time.Duration
+}
+
+func NewLoginCodeRedisCache(client *redis.Client, codeTTL time.Duration) *LoginCodeRedisCache {
+	return &LoginCodeRedisCache{
+		rdb:     client,
+		codeTTL: codeTTL,
+	}
+}
+
+func (rc *LoginCodeRedisCache) key(userID uint64) string {
+	return fmt.Sprintf("%s:%d", loginCodeKeyPrefix, userID)
+}
+
+// Save replaces the pending code of the user and returns the new code
+func (rc *LoginCodeRedisCache) Save(ctx context.Context, userID uint64, linkTokenID string) (string, error) {
+	code := createCode()
+
+	codeHash, err := security.HashString(code)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, rc.key(userID))
+		pipe.HSet(
+			ctx,
+			rc.key(userID),
+			loginCodeHashField, codeHash,
+			loginCodeLinkTokenIDField, linkTokenID,
+			loginCodeAttemptsField, 0,
+		)
+		pipe.Expire(ctx, rc.key(userID), rc.codeTTL)
+
+		return nil
+	})
+	if err != nil {
+		return "", model.ErrRedis
+	}
+
+	return code, nil
+}
+
+func (rc *LoginCodeRedisCache) Get(ctx context.Context, userID uint64) (model.PendingLoginCode, error) {
+	fields, err := rc.rdb.HGetAll(ctx, rc.key(userID)).Result()
+	if err != nil {
+		return model.PendingLoginCode{}, model.ErrRedis
+	}
+
+	if len(fields) == 0 {
+		return model.PendingLoginCode{}, model.ErrNotFound
+	}
+
+	return model.PendingLoginCode{
+		UserID:      userID,
+		CodeHash:    []byte(fields[loginCodeHashField]),
+		LinkTokenID: fields[loginCodeLinkTokenIDField],
+	}, nil
+}
+
+// AddFailure counts a failed attempt of the pending code and returns the number of failures
+func (rc *LoginCodeRedisCache) AddFailure(ctx context.Context, userID uint64) (int64, error) {
+	failures, err := addCodeFailureScript.Run(ctx, rc.rdb, []string{rc.key(userID)}).Int64()
+	if err != nil {
+		return 0, model.ErrRedis
+	}
+
+	if failures == 0 {
+		return 0, model.ErrNotFound
+	}
+
+	return failures, nil
+}
+
+// Consume deletes the pending code of the user, so that every code is used once.
+// Only the caller which deleted it gets no error
+func (rc *LoginCodeRedisCache) Consume(ctx context.Context, userID uint64) error {
+	n, err := rc.rdb.Del(ctx, rc.key(userID)).Result()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	if n == 0 {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"time"
+)
+
+const (
+	passwordResetKeyPrefix     = "user:password_reset"
+	passwordResetUserKeyPrefix = "user:password_reset:user"
+	passwordResetExpiration    = 30 * time.Minute
+	passwordResetTokenLength   = 32
+)
+
+// PasswordResetRedisCache stores the hashes of password reset tokens. Every user
+// has at most one valid token, requesting a new one invalidates the previous
+type PasswordResetRedisCache struct {
+	rdb *redis.Client
+}
+
+func NewPasswordResetRedisCache(client *redis.Client) *PasswordResetRedisCache {
+	return &PasswordResetRedisCache{
+		rdb: client,
+	}
+}
+
+func (rc *PasswordResetRedisCache) tokenKey(tokenHash string) string {
+	return fmt.Sprintf("%s:%s", passwordResetKeyPrefix, tokenHash)
+}
+
+func (rc *PasswordResetRedisCache) userKey(userID uint64) string {
+	return fmt.Sprintf("%s:%d", passwordResetUserKeyPrefix, userID)
+}
+
+func hashResetToken(token string) string {
+	return hex.EncodeToString(security.SHA256(token))
+}
+
+func (rc *PasswordResetRedisCache) Save(ctx context.Context, userID uint64) (string, error) {
+	token := security.RandomString(passwordResetTokenLength)
+	tokenHash := hashResetToken(token)
+
+	previousHash, err := rc.rdb.SetArgs(ctx, rc.userKey(userID), tokenHash, redis.SetArgs{
+		TTL: passwordResetExpiration,
+		Get: true,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", model.ErrRedis
+	}
+
+	_, err = rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previousHash != "" {
+			pipe.Del(ctx, rc.tokenKey(previousHash))
+		}
+		pipe.Set(ctx, rc.tokenKey(tokenHash), userID, passwordResetExpiration)
+
+		return nil
+	})
+	if err != nil {
+		return "", model.ErrRedis
+	}
+
+	return token, nil
+}
+
+// Find returns the user the token was issued for without using the token up
+func (rc *PasswordResetRedisCache) Find(ctx context.Context, token string) (uint64, error) {
+	userID, err := rc.rdb.Get(ctx, rc.tokenKey(hashResetToken(token))).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, model.ErrNotFound
+		}
+
+		return 0, model.ErrRedis
+	}
+
+	return userID, nil
+}
+
+// Consume returns the user the token was issued for and deletes it,
+// so every token can be used once
+func (rc *PasswordResetRedisCache) Consume(ctx context.Context, token string) (uint64, error) {
+	userID, err := rc.rdb.GetDel(ctx, rc.tokenKey(hashResetToken(token))).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, model.ErrNotFound
+		}
+
+		return 0, model.ErrRedis
+	}
+
+	err = rc.rdb.Del(ctx, rc.userKey(userID)).Err()
+	if err != nil {
+		return 0, model.ErrRedis
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+)
+
+const permissionVersionKey = "user:rbac:version"
+
+// PermissionVersionRedisCache keeps a counter which is bumped whenever roles or their permissions change,
+// so that every instance knows when to reload its cached permissions
+type PermissionVersionRedisCache struct {
+	rdb *redis.Client
+}
+
+func NewPermissionVersionRedisCache(client *redis.Client) *PermissionVersionRedisCache {
+	return &PermissionVersionRedisCache{
+		rdb: client,
+	}
+}
+
+// Version returns the current version, zero when the permissions never changed
+func (rc *PermissionVersionRedisCache) Version(ctx context.Context) (int64, error) {
+	version, err := rc.rdb.Get(ctx, permissionVersionKey).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+
+		return 0, model.ErrRedis
+	}
+
+	return version, nil
+}
+
+func (rc *PermissionVersionRedisCache) Bump(ctx context.Context) error {
+	err := rc.rdb.Incr(ctx, permissionVersionKey).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+)
+
+const phoneVerificationKeyPrefix = "user:code:phone_verification"
+
+// PhoneVerificationRedisCache stores the hashed verification code sent to a user
+// together with the phone number it was sent to
+type PhoneVerificationRedisCache struct {
+	rdb *redis.Client
+}
+
+func NewPhoneVerificationRedisCache(client *redis.Client) *PhoneVerificationRedisCache {
+	return &PhoneVerificationRedisCache{
+		rdb: client,
+	}
+}
+
+func (rc *PhoneVerificationRedisCache) key(userID uint64) string {
+	return fmt.Sprintf("%s:%d", phoneVerificationKeyPrefix, userID)
+}
+
+// Save replaces the pending verification of the user and returns the new code
+func (rc *PhoneVerificationRedisCache) Save(ctx context.Context, userID uint64, phoneNumber string) (string, error) {
+	code := createCode()
+
+	codeHash, err := security.HashString(code)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, rc.key(userID))
+		pipe.HSet(ctx, rc.key(userID), "phone_number", phoneNumber, "code_hash", codeHash)
+		pipe.Expire(ctx, rc.key(userID), codeExpiration)
+
+		return nil
+	})
+	if err != nil {
+		return "", model.ErrRedis
+	}
+
+	return code, nil
+}
+
+func (rc *PhoneVerificationRedisCache) Get(ctx context.Context, userID uint64) (model.PendingPhoneVerification, error) {
+	fields, err := rc.rdb.HGetAll(ctx, rc.key(userID)).Result()
+	if err != nil {
+		return model.PendingPhoneVerification{}, model.ErrRedis
+	}
+
+	if len(fields) == 0 {
+		return model.PendingPhoneVerification{}, model.ErrNotFound
+	}
+
+	return model.PendingPhoneVerification{
+		UserID:      userID,
+		PhoneNumber: fields["phone_number"],
+		CodeHash:    []byte(fields["code_hash"]),
+	}, nil
+}
+
+func (rc *PhoneVerificationRedisCache) Delete(ctx context.Context, userID uint64) error {
+	err := rc.rdb.Del(ctx, rc.key(userID)).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+// AddFailure counts a wrong code of the pending verification and returns the number of failures
+func (rc *PhoneVerificationRedisCache) AddFailure(ctx context.Context, userID uint64) (int64, error) {
+	failures, err := addCodeFailureScript.Run(ctx, rc.rdb, []string{rc.key(userID)}).Int64()
+	if err != nil {
+		return 0, model.ErrRedis
+	}
+
+	if failures == 0 {
+		return 0, model.ErrNotFound
+	}
+
+	return failures, nil
+}
@@ -6,13 +6,53 @@ import (
 	"fmt"
 	"github.com/redis/go-redis/v9"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	refreshTokenKeyPrefix = "user:token:refresh"
+	sessionKeyPrefix      = "user:session"
+	userSessionsKeyPrefix = "user:sessions"
 )
 
+const (
+	sessionRefreshTokenIDField = "refresh_token_id"
+	sessionDeviceNameField     = "device_name"
+	sessionUserAgentField      = "user_agent"
+	sessionClientIPField       = "client_ip"
+	sessionCreatedAtField      = "created_at"
+	sessionLastUsedAtField     = "last_used_at"
+)
+
+// rotateRefreshTokenScript swaps the current refresh token ID of a session
+// only if the presented one is still current.
+// The session stays in the index of the user for as long as it lives.
+// Returns 1 on success, 0 if the session does not exist, -1 on reuse.
+// Sessions saved as plain strings before they were hashes count as missing
+var rotateRefreshTokenScript = redis.NewScript(`
+if redis.call("TYPE", KEYS[1]).ok ~= "hash" then
+	return 0
+end
+local current = redis.call("HGET", KEYS[1], "refresh_token_id")
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	return -1
+end
+redis.call("HSET", KEYS[1], "refresh_token_id", ARGV[2], "last_used_at", ARGV[4])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+redis.call("SADD", KEYS[2], ARGV[5])
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+return 1
+`)
+
+// SessionRedisCache stores one record per login session, so that
+// every device of a user keeps its own refresh token lifecycle.
+// The record holds the ID of the only refresh token currently valid for the session
+// and the device the session was started on.
+// The IDs of the sessions of a user are indexed in a set, so they are listed without scanning the keyspace
 type SessionRedisCache struct {
 	rdb             *redis.Client
 	refreshTokenTTL time.Duration
@@ -25,12 +65,37 @@ func NewSessionRedisCache(client *redis.Client, refreshTokenTTL time.Duration) *
 	}
 }
 
-func (rc *SessionRedisCache) key(userID uint64) string {
-	return fmt.Sprintf("%s:%d", refreshTokenKeyPrefix, userID)
+func (rc *SessionRedisCache) key(userID uint64, sessionID string) string {
+	return fmt.Sprintf("%s:%d:%s", sessionKeyPrefix, userID, sessionID)
 }
 
-func (rc *SessionRedisCache) Save(ctx context.Context, userID uint64) error {
-	err := rc.rdb.Set(ctx, rc.key(userID), true, rc.refreshTokenTTL).Err()
+func (rc *SessionRedisCache) indexKey(userID uint64) string {
+	return fmt.Sprintf("%s:%d", userSessionsKeyPrefix, userID)
+}
+
+func (rc *SessionRedisCache) Save(ctx context.Context, session model.Session, refreshTokenID string) error {
+	key := rc.key(session.UserID, session.ID)
+	indexKey := rc.indexKey(session.UserID)
+
+	_, err := rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(
+			ctx,
+			key,
+			sessionRefreshTokenIDField, refreshTokenID,
+			sessionDeviceNameField, session.DeviceName,
+			sessionUserAgentField, session.UserAgent,
+			sessionClientIPField, session.ClientIP,
+			sessionCreatedAtField, session.CreatedAt.Unix(),
+			sessionLastUsedAtField, session.LastUsedAt.Unix(),
+		)
+		pipe.Expire(ctx, key, rc.refreshTokenTTL)
+		// The index lives as long as the newest session of the user
+		pipe.SAdd(ctx, indexKey, session.ID)
+		pipe.Expire(ctx, indexKey, rc.refreshTokenTTL)
+
+		return nil
+	})
 	if err != nil {
 		return model.ErrRedis
 	}
@@ -38,21 +103,135 @@ func (rc *SessionRedisCache) Save(ctx context.Context, userID uint64) error {
 	return nil
 }
 
-func (rc *SessionRedisCache) Exists(ctx context.Context, userID uint64) (bool, error) {
-	_, err := rc.rdb.Get(ctx, rc.key(userID)).Bool()
+func (rc *SessionRedisCache) Rotate(
+	ctx context.Context,
+	userID uint64,
+	sessionID, refreshTokenID, newRefreshTokenID string,
+) error {
+	res, err := rotateRefreshTokenScript.Run(
+		ctx,
+		rc.rdb,
+		[]string{rc.key(userID, sessionID), rc.indexKey(userID)},
+		refreshTokenID,
+		newRefreshTokenID,
+		rc.refreshTokenTTL.Milliseconds(),
+		time.Now().Unix(),
+		sessionID,
+	).Int()
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return false, model.ErrNotFound
-		}
+		return model.ErrRedis
+	}
 
+	switch res {
+	case 0:
+		return model.ErrNotFound
+	case -1:
+		return model.ErrRefreshTokenReused
+	default:
+		return nil
+	}
+}
+
+func (rc *SessionRedisCache) Exists(ctx context.Context, userID uint64, sessionID string) (bool, error) {
+	n, err := rc.rdb.Exists(ctx, rc.key(userID, sessionID)).Result()
+	if err != nil {
 		return false, model.ErrRedis
 	}
 
+	if n == 0 {
+		return false, model.ErrNotFound
+	}
+
 	return true, nil
 }
 
-func (rc *SessionRedisCache) Delete(ctx context.Context, userID uint64) error {
-	err := rc.rdb.Del(ctx, rc.key(userID)).Err()
+// Find returns every session of the user
+func (rc *SessionRedisCache) Find(ctx context.Context, userID uint64) ([]model.Session, error) {
+	sessionIDs, err := rc.sessionIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(sessionIDs))
+	_, err = rc.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, sessionID := range sessionIDs {
+			cmds[i] = pipe.HGetAll(ctx, rc.key(userID, sessionID))
+		}
+
+		return nil
+	})
+	if err != nil && !isWrongTypeErr(err) {
+		return nil, model.ErrRedis
+	}
+
+	sessions := make([]model.Session, 0, len(sessionIDs))
+	var expired []any
+	for i, cmd := range cmds {
+		fields, err := cmd.Result()
+		if err != nil {
+			// Sessions saved as plain strings carry no details to list
+			if isWrongTypeErr(err) {
+				continue
+			}
+
+			return nil, model.ErrRedis
+		}
+		// The session expired while its ID was still indexed
+		if len(fields) == 0 {
+			expired = append(expired, sessionIDs[i])
+
+			continue
+		}
+
+		sessions = append(sessions, model.Session{
+			ID:         sessionIDs[i],
+			UserID:     userID,
+			DeviceName: fields[sessionDeviceNameField],
+			UserAgent:  fields[sessionUserAgentField],
+			ClientIP:   fields[sessionClientIPField],
+			CreatedAt:  unixField(fields[sessionCreatedAtField]),
+			LastUsedAt: unixField(fields[sessionLastUsedAtField]),
+		})
+	}
+
+	if len(expired) > 0 {
+		err = rc.rdb.SRem(ctx, rc.indexKey(userID), expired...).Err()
+		if err != nil {
+			return nil, model.ErrRedis
+		}
+	}
+
+	return sessions, nil
+}
+
+// DeleteAll ends every session of the user
+func (rc *SessionRedisCache) DeleteAll(ctx context.Context, userID uint64) error {
+	sessionIDs, err := rc.sessionIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, rc.key(userID, sessionID))
+	}
+	keys = append(keys, rc.indexKey(userID))
+
+	err = rc.rdb.Del(ctx, keys...).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+func (rc *SessionRedisCache) Delete(ctx context.Context, userID uint64, sessionID string) error {
+	_, err := rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, rc.key(userID, sessionID))
+		pipe.SRem(ctx, rc.indexKey(userID), sessionID)
+
+		return nil
+	})
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return model.ErrNotFound
@@ -63,3 +242,25 @@ func (rc *SessionRedisCache) Delete(ctx context.Context, userID uint64) error {
 
 	return nil
 }
+
+func (rc *SessionRedisCache) sessionIDs(ctx context.Context, userID uint64) ([]string, error) {
+	sessionIDs, err := rc.rdb.SMembers(ctx, rc.indexKey(userID)).Result()
+	if err != nil {
+		return nil, model.ErrRedis
+	}
+
+	return sessionIDs, nil
+}
+
+func isWrongTypeErr(err error) bool {
+	return strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+func unixField(value string) time.Time {
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(unix, 0)
+}
@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"time"
+)
+
+const (
+	revokedTokenKeyPrefix           = "user:token:revoked"
+	revokedSessionKeyPrefix         = "user:token:revoked_session"
+	tokensValidAfterKeyPrefix       = "user:token:valid_after"
+	clientTokensValidAfterKeyPrefix = "user:token:client_valid_after"
+)
+
+// TokenRevocationRedisCache keeps a denylist of single tokens and sessions
+// and a per-user and per-client timestamp before which all issued tokens are rejected
+type TokenRevocationRedisCache struct {
+	rdb      *redis.Client
+	tokenTTL time.Duration // tokenTTL is the lifetime of the longest living token
+}
+
+func NewTokenRevocationRedisCache(client *redis.Client, tokenTTL time.Duration) *TokenRevocationRedisCache {
+	return &TokenRevocationRedisCache{
+		rdb:      client,
+		tokenTTL: tokenTTL,
+	}
+}
+
+func (rc *TokenRevocationRedisCache) tokenKey(tokenID string) string {
+	return fmt.Sprintf("%s:%s", revokedTokenKeyPrefix, tokenID)
+}
+
+func (rc *TokenRevocationRedisCache) sessionKey(sessionID string) string {
+	return fmt.Sprintf("%s:%s", revokedSessionKeyPrefix, sessionID)
+}
+
+func (rc *TokenRevocationRedisCache) userKey(userID uint64) string {
+	return fmt.Sprintf("%s:%d", tokensValidAfterKeyPrefix, userID)
+}
+
+func (rc *TokenRevocationRedisCache) clientKey(clientID string) string {
+	return fmt.Sprintf("%s:%s", clientTokensValidAfterKeyPrefix, clientID)
+}
+
+func (rc *TokenRevocationRedisCache) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	err := rc.rdb.Set(ctx, rc.tokenKey(tokenID), true, ttl).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+func (rc *TokenRevocationRedisCache) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	n, err := rc.rdb.Exists(ctx, rc.tokenKey(tokenID)).Result()
+	if err != nil {
+		return false, model.ErrRedis
+	}
+
+	return n > 0, nil
+}
+
+// RevokeSession rejects every token issued for the session until the longest living one expires
+func (rc *TokenRevocationRedisCache) RevokeSession(ctx context.Context, sessionID string) error {
+	err := rc.rdb.Set(ctx, rc.sessionKey(sessionID), true, rc.tokenTTL).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+func (rc *TokenRevocationRedisCache) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := rc.rdb.Exists(ctx, rc.sessionKey(sessionID)).Result()
+	if err != nil {
+		return false, model.ErrRedis
+	}
+
+	return n > 0, nil
+}
+
+// RevokeUserTokens rejects every token of the user issued before revokedAt, to the millisecond
+func (rc *TokenRevocationRedisCache) RevokeUserTokens(ctx context.Context, userID uint64, revokedAt time.Time) error {
+	err := rc.rdb.Set(ctx, rc.userKey(userID), revokedAt.UnixMilli(), rc.tokenTTL).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+func (rc *TokenRevocationRedisCache) UserTokensValidAfter(ctx context.Context, userID uint64) (time.Time, error) {
+	unixMilli, err := rc.rdb.Get(ctx, rc.userKey(userID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, model.ErrRedis
+	}
+
+	return time.UnixMilli(unixMilli), nil
+}
+
+// RevokeClientTokens rejects every token of the service client issued before revokedAt, to the millisecond
+func (rc *TokenRevocationRedisCache) RevokeClientTokens(ctx context.Context, clientID string, revokedAt time.Time) error {
+	err := rc.rdb.Set(ctx, rc.clientKey(clientID), revokedAt.UnixMilli(), rc.tokenTTL).Err()
+	if err != nil {
+		return model.ErrRedis
+	}
+
+	return nil
+}
+
+func (rc *TokenRevocationRedisCache) ClientTokensValidAfter(ctx context.Context, clientID string) (time.Time, error) {
+	unixMilli, err := rc.rdb.Get(ctx, rc.clientKey(clientID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, model.ErrRedis
+	}
+
+	return time.UnixMilli(unixMilli), nil
+}
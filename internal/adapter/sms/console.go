@@ -0,0 +1,50 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/sms"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ConsoleSender writes text messages to stdout or a file instead of delivering them,
+// for local development and testing
+type ConsoleSender struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewConsoleSender(cfg sms.Config) (*ConsoleSender, error) {
+	if cfg.FilePath == "" {
+		return &ConsoleSender{out: os.Stdout}, nil
+	}
+
+	f, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConsoleSender{out: f}, nil
+}
+
+func (s *ConsoleSender) SendPhoneVerificationCode(ctx context.Context, receiver, code string) error {
+	text := fmt.Sprintf("Your phone verification code: %s", code)
+
+	return s.send(ctx, receiver, text)
+}
+
+func (s *ConsoleSender) send(ctx context.Context, receiver, text string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(s.out, "%s sms to %s: %s\n", time.Now().Format(time.RFC3339), receiver, text)
+
+	return err
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"github.com/go-playground/validator/v10"
 	grpcserver "github.com/sorawaslocked/car-rental-user-service/internal/adapter/grpc"
+	httpserver "github.com/sorawaslocked/car-rental-user-service/internal/adapter/http"
 	"github.com/sorawaslocked/car-rental-user-service/internal/adapter/mailer"
 	"github.com/sorawaslocked/car-rental-user-service/internal/adapter/postgres"
 	"github.com/sorawaslocked/car-rental-user-service/internal/adapter/redis"
+	"github.com/sorawaslocked/car-rental-user-service/internal/adapter/sms"
 	"github.com/sorawaslocked/car-rental-user-service/internal/config"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/breached"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/jwt"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/password"
 	postgrescfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/postgres"
 	rediscfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/redis"
 	validatecfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/validate"
@@ -21,12 +25,16 @@ import (
 )
 
 type App struct {
-	log        *slog.Logger
-	grpcServer *grpcserver.Server
+	log          *slog.Logger
+	grpcServer   *grpcserver.Server
+	httpServer   *httpserver.Server
+	keyRotator   *jwt.KeyRotator
+	loginHistory *service.LoginHistoryService // loginHistory purges the login events past their retention
+	cancel       context.CancelFunc
 }
 
 func New(
-	_ context.Context,
+	ctx context.Context,
 	cfg config.Config,
 	log *slog.Logger,
 ) (*App, error) {
@@ -40,12 +48,41 @@ func New(
 		return nil, err
 	}
 
+	keyring := jwt.NewKeyring(cfg.JWT.KeyRefreshInterval)
+	signingKeyRepo := postgres.NewSigningKeyRepository(log, db)
+
+	keyRotator, err := jwt.NewKeyRotator(log, cfg.JWT, keyring, signingKeyRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("loading jwt signing keys")
+	err = keyRotator.Rotate(ctx)
+	if err != nil {
+		log.Error("loading jwt signing keys", logger.Err(err))
+
+		return nil, err
+	}
+
 	jwtProvider := jwt.NewProvider(
-		cfg.JWT.SecretKey,
+		keyring,
 		cfg.JWT.AccessTokenTTL,
 		cfg.JWT.RefreshTokenTTL,
+		cfg.JWT.MFATokenTTL,
+		cfg.JWT.ImpersonationTTL,
+		cfg.JWT.PasswordChangeTTL,
+		cfg.JWT.LoginLinkTTL,
+		cfg.JWT.ElevatedTokenTTL,
+		cfg.JWT.LoginAlertTTL,
 	)
 
+	passwordHasher, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		log.Error("configuring password hasher", logger.Err(err))
+
+		return nil, err
+	}
+
 	validate := validator.New()
 	err = validate.RegisterValidation("min_age", validatecfg.MinAge)
 	if err != nil {
@@ -56,31 +93,166 @@ func New(
 		return nil, err
 	}
 
+	log.Info("loading breached passwords")
+	breachedPasswords, err := breached.Load(cfg.Breached)
+	if err != nil {
+		log.Error("loading breached passwords", logger.Err(err))
+
+		return nil, err
+	}
+	if breachedPasswords == nil {
+		log.Warn("no breached password file configured, passwords are not screened")
+	}
+	err = validate.RegisterValidation("not_breached", validatecfg.NotBreached(breachedPasswords))
+	if err != nil {
+		return nil, err
+	}
+
 	userRepo := postgres.NewUserRepository(log, db)
+	mfaRepo := postgres.NewMFARepository(log, db)
+	roleRepo := postgres.NewRoleRepository(log, db)
+	serviceClientRepo := postgres.NewServiceClientRepository(log, db)
+	apiKeyRepo := postgres.NewAPIKeyRepository(log, db)
+	auditRepo := postgres.NewAuditRepository(log, db)
+	passwordHistoryRepo := postgres.NewPasswordHistoryRepository(log, db)
+	knownDeviceRepo := postgres.NewKnownDeviceRepository(log, db)
+	loginEventRepo := postgres.NewLoginEventRepository(log, db)
+
+	totpSecretEncryptionKey, err := cfg.TOTP.EncryptionKey()
+	if err != nil {
+		return nil, err
+	}
 
 	redisConn := rediscfg.Client(cfg.Redis)
 	sessionRedisCache := redis.NewSessionRedisCache(redisConn, cfg.JWT.RefreshTokenTTL)
 	activationCodeRedisCache := redis.NewActivationCodeRedisCache(redisConn)
+	phoneVerificationRedisCache := redis.NewPhoneVerificationRedisCache(redisConn)
+	emailChangeRedisCache := redis.NewEmailChangeRedisCache(redisConn)
+	tokenRevocationRedisCache := redis.NewTokenRevocationRedisCache(redisConn, cfg.JWT.MaxTokenTTL())
+	loginAttemptRedisCache := redis.NewLoginAttemptRedisCache(redisConn)
+	passwordResetRedisCache := redis.NewPasswordResetRedisCache(redisConn)
+	loginCodeRedisCache := redis.NewLoginCodeRedisCache(redisConn, cfg.Passwordless.CodeTTL)
+	permissionVersionRedisCache := redis.NewPermissionVersionRedisCache(redisConn)
 
 	msMailer := mailer.New(cfg.Mailer)
 
-	userService := service.NewUserService(log, validate, jwtProvider, userRepo, activationCodeRedisCache, msMailer)
-	authService := service.NewAuthService(log, validate, jwtProvider, userService, sessionRedisCache)
+	smsSender, err := sms.NewConsoleSender(cfg.SMS)
+	if err != nil {
+		log.Error("opening sms output", logger.Err(err))
 
-	grpcServer := grpcserver.NewServer(cfg.GRPC, log, authService, userService, jwtProvider)
+		return nil, err
+	}
+
+	userService := service.NewUserService(
+		log,
+		validate,
+		jwtProvider,
+		passwordHasher,
+		userRepo,
+		roleRepo,
+		passwordHistoryRepo,
+		activationCodeRedisCache,
+		phoneVerificationRedisCache,
+		emailChangeRedisCache,
+		tokenRevocationRedisCache,
+		loginAttemptRedisCache,
+		msMailer,
+		smsSender,
+	)
+	mfaService := service.NewMFAService(
+		log,
+		validate,
+		userRepo,
+		mfaRepo,
+		roleRepo,
+		cfg.TOTP.Issuer,
+		totpSecretEncryptionKey,
+	)
+	serviceClientService := service.NewServiceClientService(
+		log,
+		validate,
+		jwtProvider,
+		serviceClientRepo,
+		tokenRevocationRedisCache,
+	)
+	apiKeyService := service.NewAPIKeyService(
+		log,
+		validate,
+		apiKeyRepo,
+		userService,
+	)
+	roleService := service.NewRoleService(
+		log,
+		validate,
+		roleRepo,
+		permissionVersionRedisCache,
+	)
+	loginHistoryService := service.NewLoginHistoryService(
+		log,
+		validate,
+		loginEventRepo,
+		cfg.LoginHistory,
+	)
+	authService := service.NewAuthService(
+		log,
+		validate,
+		jwtProvider,
+		userService,
+		mfaService,
+		loginHistoryService,
+		sessionRedisCache,
+		tokenRevocationRedisCache,
+		loginAttemptRedisCache,
+		passwordResetRedisCache,
+		loginCodeRedisCache,
+		auditRepo,
+		knownDeviceRepo,
+		msMailer,
+		cfg.Passwordless,
+		cfg.DeviceAlert,
+	)
+
+	grpcServer := grpcserver.NewServer(
+		cfg.GRPC,
+		log,
+		authService,
+		mfaService,
+		loginHistoryService,
+		serviceClientService,
+		apiKeyService,
+		roleService,
+		userService,
+		jwtProvider,
+		tokenRevocationRedisCache,
+		auditRepo,
+		roleRepo,
+		permissionVersionRedisCache,
+	)
+	httpServer := httpserver.NewServer(cfg.HTTP, log, authService)
 
 	return &App{
-		log:        log,
-		grpcServer: grpcServer,
+		log:          log,
+		grpcServer:   grpcServer,
+		httpServer:   httpServer,
+		keyRotator:   keyRotator,
+		loginHistory: loginHistoryService,
 	}, nil
 }
 
 func (a *App) stop() {
+	a.cancel()
 	a.grpcServer.Stop()
+	a.httpServer.Stop()
 }
 
 func (a *App) Run() {
+	var ctx context.Context
+	ctx, a.cancel = context.WithCancel(context.Background())
+
+	go a.keyRotator.Run(ctx)
+	go a.loginHistory.Run(ctx)
 	a.grpcServer.MustRun()
+	a.httpServer.MustRun()
 
 	shutdownCh := make(chan os.Signal, 1)
 	signal.Notify(shutdownCh, syscall.SIGINT, syscall.SIGTERM)
@@ -3,11 +3,19 @@ package config
 import (
 	"flag"
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/breached"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/devicealert"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/grpc"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/http"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/jwt"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/loginhistory"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/mailer"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/password"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/passwordless"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/postgres"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/redis"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/sms"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/totp"
 	"os"
 )
 
@@ -17,8 +25,17 @@ type (
 		Postgres postgres.Config `yaml:"postgres" env-required:"true"`
 		Redis    redis.Config    `yaml:"redis" env-required:"true"`
 		GRPC     grpc.Config     `yaml:"grpc" env-required:"true"`
+		HTTP     http.Config     `yaml:"http" env-required:"true"`
 		JWT      jwt.Config      `yaml:"jwt" env-required:"true"`
+		TOTP     totp.Config     `yaml:"totp" env-required:"true"`
+		Password password.Config `yaml:"password"`
+		Breached breached.Config `yaml:"breached_passwords"`
+		SMS      sms.Config      `yaml:"sms"`
 		Mailer   mailer.Config
+
+		Passwordless passwordless.Config `yaml:"passwordless"`
+		DeviceAlert  devicealert.Config  `yaml:"device_alert"`
+		LoginHistory loginhistory.Config `yaml:"login_history"`
 	}
 )
 
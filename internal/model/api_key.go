@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// APIKey lets partner scripts call the API without an interactive login.
+// A key acts either as its user or on behalf of an organization
+type APIKey struct {
+	ID           uint64
+	Name         string
+	Prefix       string // Prefix is the start of the key, shown to tell keys apart
+	KeyHash      []byte
+	UserID       *uint64
+	Organization *string
+	Scopes       []string // Scopes lists the full gRPC method names the key can call
+	ExpiresAt    *time.Time
+	RevokedAt    *time.Time
+	CreatedAt    time.Time
+}
+
+type APIKeyFilter struct {
+	UserID       *uint64
+	Organization *string
+}
+
+type APIKeyCreateData struct {
+	Name         string   `validate:"required,min=1,max=100"`
+	UserID       *uint64  `validate:"required_without=Organization"`
+	Organization *string  `validate:"required_without=UserID,excluded_with=UserID,omitempty,min=1,max=100"`
+	Scopes       []string `validate:"required,min=1,dive,startswith=/"`
+	ExpiresAt    *time.Time
+}
+
+type APIKeyUpdateData struct {
+	Scopes    *[]string `validate:"omitempty,min=1,dive,startswith=/"`
+	ExpiresAt *time.Time
+}
+
+// IssuedAPIKey is returned once when a key is issued, only the hash of the key is stored
+type IssuedAPIKey struct {
+	APIKey
+	Key string
+}
+
+type APIKeyUpdate struct {
+	Scopes    *[]string
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
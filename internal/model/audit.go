@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const (
+	AuditActionImpersonationStarted = "impersonation.started"
+	AuditActionImpersonatedCall     = "impersonation.call"
+)
+
+// AuditEvent records an action a user took, or a support agent took on behalf of a user
+type AuditEvent struct {
+	ID        uint64
+	ActorID   *uint64 // ActorID is set when the action was taken by someone other than UserID
+	UserID    *uint64
+	Action    string
+	Method    string
+	Reason    string
+	CreatedAt time.Time
+}
@@ -1,14 +1,74 @@
 package model
 
+import "time"
+
 type Credentials struct {
 	Email       string `validate:"required_without=PhoneNumber,omitempty,email"`
 	PhoneNumber string `validate:"required_without=Email,omitempty,e164"`
 	Password    string `validate:"required"`
 }
 
+// ReauthenticationData proves the identity of a logged-in user again with either the password or a TOTP code
+type ReauthenticationData struct {
+	Password string `validate:"required_without=Code"`
+	Code     string `validate:"required_without=Password"`
+}
+
+type PasswordChangeData struct {
+	CurrentPassword      string `validate:"required"`
+	Password             string `validate:"required,min=8,max=20,complex_password,not_breached,nefield=CurrentPassword"`
+	PasswordConfirmation string `validate:"required,eqfield=Password"`
+}
+
 type Token struct {
 	AccessToken           string
 	AccessTokenExpiresIn  int64
 	RefreshToken          string
 	RefreshTokenExpiresIn int64
 }
+
+// PasswordChangeChallenge is returned by a login which can only complete once the password is changed
+type PasswordChangeChallenge struct {
+	Token     string
+	ExpiresIn int64
+}
+
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+	TokenTypeMFA     TokenType = "mfa" // TokenTypeMFA is issued between the password and the second factor of a login
+	// TokenTypePasswordChange is issued by a login of a user whose password expired or has to be changed
+	TokenTypePasswordChange TokenType = "password_change"
+	// TokenTypeLoginLink is sent in the magic link of a passwordless login
+	TokenTypeLoginLink TokenType = "login_link"
+	// TokenTypeLoginAlert is sent in the "this wasn't me" link of a new device alert
+	TokenTypeLoginAlert TokenType = "login_alert"
+)
+
+// SubjectType tells whether a token was issued to a user or to a service client
+type SubjectType string
+
+const (
+	SubjectTypeUser         SubjectType = "user"
+	SubjectTypeClient       SubjectType = "client"
+	SubjectTypeOrganization SubjectType = "organization" // SubjectTypeOrganization is only used by API keys
+)
+
+// TokenClaims holds the claims carried by access and refresh tokens
+type TokenClaims struct {
+	UserID       uint64
+	ClientID     string // ClientID is the subject of service client tokens instead of UserID
+	Organization string // Organization is the subject of organization API keys instead of UserID
+	ActorID      uint64 // ActorID is the support agent acting as UserID in impersonation tokens
+	SubjectType  SubjectType
+	Roles        []string
+	Scopes       []string // Scopes lists the methods a service client token can call
+	SessionID    string   // SessionID identifies the login session the token belongs to
+	TokenID      string   // TokenID is unique for every issued token
+	Type         TokenType
+	AuthTime     time.Time // AuthTime is when the user last proved their identity, refreshing tokens keeps it
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// KnownDevice is a device a user has logged in on before. Devices are told apart
+// by a fingerprint of their user agent and device name
+type KnownDevice struct {
+	ID          uint64
+	UserID      uint64
+	Fingerprint []byte
+	DeviceName  string
+	UserAgent   string
+	IPRange     string // IPRange is the network of the last login on the device
+	CreatedAt   time.Time
+	LastSeenAt  time.Time
+}
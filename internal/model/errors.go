@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type ValidationErrors map[string]error
@@ -20,32 +21,77 @@ func (ve ValidationErrors) Error() string {
 	return strings.TrimSpace(buff.String())
 }
 
+// LockoutError is returned while logins are blocked after too many failed attempts
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e LockoutError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e LockoutError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
 var (
 	ErrMissingMetadata         = errors.New("missing metadata")
 	ErrInvalidToken            = errors.New("invalid token")
+	ErrRefreshTokenReused      = errors.New("refresh token reused")
+	ErrRevokedToken            = errors.New("token has been revoked")
 	ErrInsufficientPermissions = errors.New("insufficient permissions")
+	ErrTooManyLoginAttempts    = errors.New("too many failed login attempts")
 	ErrNotFound                = errors.New("resource not found")
 	ErrNoUpdateFields          = errors.New("no update fields set")
 	ErrEmptyFilter             = errors.New("filter is empty")
 
-	ErrRequiredField         = errors.New("required")
-	ErrPasswordsDoNotMatch   = errors.New("passwords do not match")
-	ErrNotAlphaNum           = errors.New("must only contain ascii letters and numbers")
-	ErrNotAlphaUnicode       = errors.New("must only contain letters")
-	ErrNotUppercase          = errors.New("must only contain uppercase letters")
-	ErrInvalidEmail          = errors.New("must be a valid email address")
-	ErrInvalidPhoneNumber    = errors.New("must be a valid 164 phone number")
-	ErrInvalidDateFormat     = errors.New("must be a valid date format")
-	ErrNotComplexPassword    = errors.New("must contain uppercase characters, lowercase characters, numbers, and special characters(!@#)")
-	ErrDuplicateEmail        = errors.New("user with this email already exists")
-	ErrInvalidRole           = errors.New("must be a valid role")
-	ErrInvalidJwtToken       = errors.New("must be a valid jwt token")
-	ErrActivatedUser         = errors.New("user is already activated")
-	ErrInvalidActivationCode = errors.New("invalid activation code")
+	ErrRequiredField            = errors.New("required")
+	ErrPasswordsDoNotMatch      = errors.New("passwords do not match")
+	ErrNotAlphaNum              = errors.New("must only contain ascii letters and numbers")
+	ErrNotAlphaUnicode          = errors.New("must only contain letters")
+	ErrNotUppercase             = errors.New("must only contain uppercase letters")
+	ErrInvalidEmail             = errors.New("must be a valid email address")
+	ErrInvalidPhoneNumber       = errors.New("must be a valid 164 phone number")
+	ErrInvalidDateFormat        = errors.New("must be a valid date format")
+	ErrNotComplexPassword       = errors.New("must contain uppercase characters, lowercase characters, numbers, and special characters(!@#)")
+	ErrBreachedPassword         = errors.New("was exposed in a data breach, choose another password")
+	ErrPasswordReused           = errors.New("must not be one of the recent passwords")
+	ErrDuplicateEmail           = errors.New("user with this email already exists")
+	ErrDuplicatePhoneNumber     = errors.New("user with this phone number already exists")
+	ErrInvalidRole              = errors.New("must be a valid role")
+	ErrDuplicateRole            = errors.New("role already exists")
+	ErrInvalidPermission        = errors.New("must be a valid permission")
+	ErrInvalidJwtToken          = errors.New("must be a valid jwt token")
+	ErrActivatedUser            = errors.New("user is already activated")
+	ErrInvalidActivationCode    = errors.New("invalid activation code")
+	ErrInvalidResetToken        = errors.New("invalid or expired password reset token")
+	ErrOwnPasswordUpdate        = errors.New("own password can only be changed with the current password")
+	ErrOwnEmailUpdate           = errors.New("own email can only be changed by confirming the new address")
+	ErrSameEmail                = errors.New("must be different from the current email")
+	ErrNoPendingEmailChange     = errors.New("no pending email change")
+	ErrInvalidEmailCode         = errors.New("invalid email confirmation code")
+	ErrNoPhoneNumber            = errors.New("user has no phone number")
+	ErrPhoneNumberVerified      = errors.New("phone number is already verified")
+	ErrNoPendingPhoneCode       = errors.New("no pending phone verification")
+	ErrInvalidPhoneCode         = errors.New("invalid phone verification code")
+	ErrMFAAlreadyEnabled        = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled            = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode           = errors.New("invalid two-factor authentication code")
+	ErrInvalidClientCredentials = errors.New("invalid client credentials")
+	ErrInvalidAPIKey            = errors.New("invalid api key")
+	ErrExpiredAPIKey            = errors.New("api key has expired")
+	ErrPastExpiry               = errors.New("must be in the future")
+	ErrImpersonationNotAllowed  = errors.New("user can not be impersonated")
+	ErrImpersonating            = errors.New("not allowed while impersonating a user")
+	ErrPasswordChangeRequired   = errors.New("password has to be changed first")
+	ErrPasswordlessDisabled     = errors.New("passwordless login is disabled")
+	ErrInvalidLoginCode         = errors.New("invalid or expired login code")
+	ErrReauthenticationRequired = errors.New("recent authentication is required")
+	ErrInvalidPageToken         = errors.New("invalid page token")
 
 	ErrRedis          = errors.New("redis error")
 	ErrSqlTransaction = errors.New("sql transaction error")
 	ErrSql            = errors.New("sql error")
 	ErrJwt            = errors.New("jwt error")
-	ErrBcrypt         = errors.New("bcrypt error")
+	ErrPasswordHash   = errors.New("password hash error")
 )
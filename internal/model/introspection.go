@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// TokenIntrospection describes a token to the services which received it.
+// Only Active and CacheTTL are set for tokens which are not active
+type TokenIntrospection struct {
+	Active          bool
+	SubjectType     SubjectType
+	UserID          uint64
+	ClientID        string
+	ActorID         uint64
+	Roles           []string
+	Scopes          []string
+	SessionID       string
+	Type            TokenType
+	IssuedAt        time.Time
+	ExpiresAt       time.Time
+	IsUserActive    bool
+	IsUserConfirmed bool
+	CacheTTL        time.Duration // CacheTTL is how long the caller can reuse the result
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// LoginMethod is how a user tried to log in
+type LoginMethod string
+
+const (
+	LoginMethodPassword  LoginMethod = "password"
+	LoginMethodMFA       LoginMethod = "mfa" // LoginMethodMFA is the second factor of a login
+	LoginMethodLoginCode LoginMethod = "login_code"
+	LoginMethodMagicLink LoginMethod = "magic_link"
+)
+
+// LoginOutcome is how a login attempt ended
+type LoginOutcome string
+
+const (
+	LoginOutcomeSucceeded              LoginOutcome = "succeeded"
+	LoginOutcomeMFARequired            LoginOutcome = "mfa_required"
+	LoginOutcomePasswordChangeRequired LoginOutcome = "password_change_required"
+	LoginOutcomeInvalidCredentials     LoginOutcome = "invalid_credentials"
+	LoginOutcomeInvalidInput           LoginOutcome = "invalid_input"
+	LoginOutcomeInvalidToken           LoginOutcome = "invalid_token"
+	LoginOutcomeUnknownUser            LoginOutcome = "unknown_user"
+	LoginOutcomeLockedOut              LoginOutcome = "locked_out"
+	LoginOutcomeError                  LoginOutcome = "error"
+)
+
+// LoginEvent records a login attempt. UserID is nil when the attempt named no existing user
+type LoginEvent struct {
+	ID        uint64
+	UserID    *uint64
+	Method    LoginMethod
+	Outcome   LoginOutcome
+	ClientIP  string
+	UserAgent string
+	RequestID string
+	CreatedAt time.Time
+}
+
+// LoginHistoryFilter selects a page of the login history of a user, newest first.
+// PageToken continues a previous page
+type LoginHistoryFilter struct {
+	UserID    uint64
+	PageSize  int
+	PageToken string
+}
+
+// LoginHistoryPage is a page of login events, NextPageToken is empty on the last page
+type LoginHistoryPage struct {
+	Events        []LoginEvent
+	NextPageToken string
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// TOTP is the authenticator app enrollment of a user
+type TOTP struct {
+	UserID       uint64
+	Secret       []byte // Secret is encrypted at rest
+	IsConfirmed  bool   // IsConfirmed is set once the user proved the app produces valid codes
+	LastUsedStep int64  // LastUsedStep is the time step of the last accepted code, codes are never accepted twice
+	CreatedAt    time.Time
+}
+
+// TOTPEnrollment is shown to the user to add the secret to an authenticator app
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAChallenge is returned by a login which needs a second factor to complete
+type MFAChallenge struct {
+	Token              string
+	ExpiresIn          int64
+	EnrollmentRequired bool // EnrollmentRequired is set when a role of the user requires 2FA which is not set up yet
+}
+
+// LoginResult holds either the issued tokens, the MFA challenge
+// or the password change challenge of a login
+type LoginResult struct {
+	Token                   Token
+	MFAChallenge            *MFAChallenge
+	PasswordChangeChallenge *PasswordChangeChallenge
+}
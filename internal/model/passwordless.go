@@ -0,0 +1,16 @@
+package model
+
+// PendingLoginCode is the one-time code and magic link sent to the user for a passwordless login
+type PendingLoginCode struct {
+	UserID      uint64
+	CodeHash    []byte
+	LinkTokenID string // LinkTokenID is the ID of the only login link token valid for the code
+}
+
+// LoginCodeCredentials complete a passwordless login either with the emailed code
+// or with the token of the magic link
+type LoginCodeCredentials struct {
+	Email     string `validate:"required_without=LinkToken,omitempty,email"`
+	Code      string `validate:"required_with=Email"`
+	LinkToken string `validate:"required_without=Email,omitempty,jwt"`
+}
@@ -0,0 +1,30 @@
+package model
+
+// Permission lets the roles it is granted to call the methods which require it.
+// The permissions are seeded by the migrations, which roles have them is changed at runtime
+type Permission string
+
+const (
+	PermissionAccountManage              Permission = "account.manage"
+	PermissionAccountActivate            Permission = "account.activate"
+	PermissionProfileRead                Permission = "profile.read"
+	PermissionLoginHistoryRead           Permission = "login_history.read"
+	PermissionLoginHistoryReadAny        Permission = "login_history.read_any"
+	PermissionTokensIntrospect           Permission = "tokens.introspect"
+	PermissionUsersImpersonate           Permission = "users.impersonate"
+	PermissionUsersCreate                Permission = "users.create"
+	PermissionUsersRead                  Permission = "users.read"
+	PermissionUsersReadAny               Permission = "users.read_any"
+	PermissionUsersList                  Permission = "users.list"
+	PermissionUsersUpdate                Permission = "users.update"
+	PermissionUsersUpdateAny             Permission = "users.update_any"
+	PermissionUsersDelete                Permission = "users.delete"
+	PermissionUsersUnlock                Permission = "users.unlock"
+	PermissionUsersRequirePasswordChange Permission = "users.require_password_change"
+	PermissionRolesManage                Permission = "roles.manage"
+	PermissionServiceClientsManage       Permission = "service_clients.manage"
+	PermissionAPIKeysManage              Permission = "api_keys.manage"
+)
+
+// RolePermissions are the permissions granted to a role
+type RolePermissions map[Role]map[Permission]bool
@@ -1,40 +1,31 @@
 package model
 
-type Role int32
+import "regexp"
+
+// Role is the name of a role of the roles table. The built-in roles are seeded by the migrations,
+// admins can create more at runtime
+type Role string
 
 const (
-	RoleUser                  Role = 1
-	RoleAdmin                 Role = 2
-	RoleTechSupport           Role = 3
-	RoleFinanceManager        Role = 4
-	RoleMaintenanceSpecialist Role = 5
+	RoleUser                  Role = "user"
+	RoleAdmin                 Role = "admin"
+	RoleTechSupport           Role = "tech_support"
+	RoleFinanceManager        Role = "finance_manager"
+	RoleMaintenanceSpecialist Role = "maintenance_specialist"
 )
 
-var roleName = map[Role]string{
-	RoleUser:                  "user",
-	RoleAdmin:                 "admin",
-	RoleTechSupport:           "tech_support",
-	RoleFinanceManager:        "finance_manager",
-	RoleMaintenanceSpecialist: "maintenance_specialist",
-}
-
-var nameRole = map[string]Role{
-	"user":                   RoleUser,
-	"admin":                  RoleAdmin,
-	"tech_support":           RoleTechSupport,
-	"finance_manager":        RoleFinanceManager,
-	"maintenance_specialist": RoleMaintenanceSpecialist,
-}
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
 
 func (role Role) String() string {
-	return roleName[role]
+	return string(role)
 }
 
+// FromStringToRole checks that the name is a well-formed role name.
+// Whether the role exists is only known by the database
 func FromStringToRole(s string) (Role, error) {
-	role, ok := nameRole[s]
-	if !ok {
-		return 0, ErrInvalidRole
+	if !roleNamePattern.MatchString(s) {
+		return "", ErrInvalidRole
 	}
 
-	return role, nil
+	return Role(s), nil
 }
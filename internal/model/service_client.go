@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ServiceClient is another platform service which authenticates with client credentials
+type ServiceClient struct {
+	ID             uint64
+	ClientID       string
+	Name           string
+	SecretHash     []byte
+	AllowedMethods []string // AllowedMethods lists the full gRPC method names the client can call
+	CreatedAt      time.Time
+
+	IsActive bool
+}
+
+type ServiceClientCreateData struct {
+	Name           string   `validate:"required,min=1,max=100"`
+	AllowedMethods []string `validate:"required,min=1,dive,startswith=/"`
+}
+
+// ServiceClientCredentials are returned once when a client is registered,
+// only the hash of the secret is stored
+type ServiceClientCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
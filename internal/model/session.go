@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Session is a login of a user on one device
+type Session struct {
+	ID         string
+	UserID     uint64
+	DeviceName string
+	UserAgent  string
+	ClientIP   string
+	CreatedAt  time.Time
+	LastUsedAt time.Time // LastUsedAt is when the tokens of the session were last refreshed
+	IsCurrent  bool      // IsCurrent marks the session of the request listing the sessions
+}
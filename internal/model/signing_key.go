@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// SigningKey is a token signing key as it is persisted.
+// PrivateKey holds the encrypted PKCS #8 encoding of the key
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey []byte
+	CreatedAt  time.Time
+}
+
+// JSONWebKey is the public part of a signing key in RFC 7517 form
+type JSONWebKey struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
@@ -14,8 +14,12 @@ type User struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 
-	IsActive    bool
-	IsConfirmed bool
+	PasswordChangedAt  time.Time
+	MustChangePassword bool // MustChangePassword is set by an admin to force a password change on the next login
+
+	IsActive        bool
+	IsConfirmed     bool
+	IsPhoneVerified bool
 }
 
 type UserFilter struct {
@@ -28,6 +32,8 @@ type UserFilter struct {
 
 	IsActive    *bool
 	IsConfirmed *bool
+
+	PasswordHash *[]byte // PasswordHash guards updates against a concurrent password change
 }
 
 type UserUpdate struct {
@@ -40,14 +46,18 @@ type UserUpdate struct {
 	Roles        *[]Role
 	UpdatedAt    time.Time
 
-	IsActive    *bool
-	IsConfirmed *bool
+	PasswordChangedAt  *time.Time
+	MustChangePassword *bool
+
+	IsActive        *bool
+	IsConfirmed     *bool
+	IsPhoneVerified *bool
 }
 
 type UserCreateData struct {
 	Email                string    `validate:"required,email"`
 	PhoneNumber          string    `validate:"omitempty,e164"`
-	Password             string    `validate:"required,min=8,max=20,complex_password"`
+	Password             string    `validate:"required,min=8,max=20,complex_password,not_breached"`
 	PasswordConfirmation string    `validate:"required,min=8,max=20,complex_password,eqfield=Password"`
 	FirstName            string    `validate:"required,min=1,max=100,alphaunicode"`
 	LastName             string    `validate:"required,min=1,max=100,alphaunicode"`
@@ -64,10 +74,24 @@ type UserUpdateData struct {
 	FirstName            *string    `validate:"omitempty,min=1,max=100,alphaunicode"`
 	LastName             *string    `validate:"omitempty,min=1,max=100,alphaunicode"`
 	BirthDate            *time.Time `validate:"omitempty,min_age=18"`
-	Password             *string    `validate:"omitempty,min=8,max=20,complex_password"`
+	Password             *string    `validate:"omitempty,min=8,max=20,complex_password,not_breached"`
 	PasswordConfirmation *string    `validate:"required_with=Password,omitempty,min=8,max=20,complex_password"`
 	Roles                *[]Role
 
 	IsActive    *bool
 	IsConfirmed *bool
 }
+
+// PendingEmailChange is an email address the user switches to once it is confirmed
+type PendingEmailChange struct {
+	UserID   uint64
+	Email    string
+	CodeHash []byte
+}
+
+// PendingPhoneVerification is a verification code sent to the phone number of the user
+type PendingPhoneVerification struct {
+	UserID      uint64
+	PhoneNumber string
+	CodeHash    []byte
+}
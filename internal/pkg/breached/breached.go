@@ -0,0 +1,155 @@
+package breached
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// magic starts every filter file, the last byte is the version of the format
+var magic = [4]byte{'C', 'R', 'B', 1}
+
+var (
+	ErrInvalidFile    = errors.New("invalid breached password filter file")
+	ErrInvalidFPRate  = errors.New("false positive rate must be between 0 and 1")
+	ErrInvalidEntries = errors.New("number of entries must be positive")
+)
+
+type Config struct {
+	FilePath string `yaml:"file_path" env:"BREACHED_PASSWORDS_FILE"`
+}
+
+// Filter is a Bloom filter of the SHA-1 hashes of breached passwords.
+// It never misses a password added to it, and reports a password which was not added
+// with the false positive rate it was built for. A nil Filter contains no passwords
+type Filter struct {
+	k    uint32 // k is the number of bits set for every password
+	m    uint64 // m is the size of the filter in bits
+	bits []uint64
+}
+
+// NewFilter sizes a filter for n passwords and the false positive rate fpRate
+func NewFilter(n uint64, fpRate float64) (*Filter, error) {
+	if n == 0 {
+		return nil, ErrInvalidEntries
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, ErrInvalidFPRate
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+
+	return &Filter{
+		k:    k,
+		m:    m,
+		bits: make([]uint64, (m+63)/64),
+	}, nil
+}
+
+// Load reads the filter file of the config. No filter is loaded if no file is configured
+func Load(cfg Config) (*Filter, error) {
+	if cfg.FilePath == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(cfg.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadFilter(bufio.NewReader(file))
+}
+
+// ReadFilter reads a filter written by Filter.WriteTo
+func ReadFilter(r io.Reader) (*Filter, error) {
+	var header struct {
+		Magic [4]byte
+		K     uint32
+		M     uint64
+	}
+
+	err := binary.Read(r, binary.LittleEndian, &header)
+	if err != nil || header.Magic != magic || header.K == 0 || header.M == 0 {
+		return nil, ErrInvalidFile
+	}
+
+	f := &Filter{
+		k:    header.K,
+		m:    header.M,
+		bits: make([]uint64, (header.M+63)/64),
+	}
+
+	err = binary.Read(r, binary.LittleEndian, f.bits)
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	return f, nil
+}
+
+// WriteTo writes the filter in the format ReadFilter reads
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	header := struct {
+		Magic [4]byte
+		K     uint32
+		M     uint64
+	}{magic, f.k, f.m}
+
+	err := binary.Write(w, binary.LittleEndian, header)
+	if err != nil {
+		return 0, err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, f.bits)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(binary.Size(header) + binary.Size(f.bits)), nil
+}
+
+func (f *Filter) Add(password string) {
+	f.AddSHA1(sha1.Sum([]byte(password)))
+}
+
+// AddSHA1 adds a password by its SHA-1 hash, the form breach corpora are published in
+func (f *Filter) AddSHA1(digest [sha1.Size]byte) {
+	h1, h2 := split(digest)
+
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (f *Filter) Contains(password string) bool {
+	if f == nil {
+		return false
+	}
+
+	h1, h2 := split(sha1.Sum([]byte(password)))
+
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// split derives the two hashes the bits of a password are picked with from its SHA-1 hash,
+// which is already uniformly distributed
+func split(digest [sha1.Size]byte) (uint64, uint64) {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+
+	return h1, h2
+}
@@ -0,0 +1,74 @@
+package breached
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	f, err := NewFilter(1000, 0.001)
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprintf("Password%d!", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !f.Contains(fmt.Sprintf("Password%d!", i)) {
+			t.Fatalf("Contains() = false for added password %d", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Contains(fmt.Sprintf("Unbreached%d?", i)) {
+			falsePositives++
+		}
+	}
+	// 10 false positives are expected, 50 would be far outside of the configured rate
+	if falsePositives > 50 {
+		t.Errorf("Contains() has %d false positives in 10000 passwords", falsePositives)
+	}
+}
+
+func TestFilter_WriteAndRead(t *testing.T) {
+	f, err := NewFilter(10, 0.01)
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+	f.Add("Password1!")
+
+	var buf bytes.Buffer
+	_, err = f.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	read, err := ReadFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadFilter() error = %v", err)
+	}
+
+	if !read.Contains("Password1!") {
+		t.Error("Contains() = false for a password added before writing")
+	}
+}
+
+func TestReadFilter_InvalidFile(t *testing.T) {
+	_, err := ReadFilter(bytes.NewReader([]byte("password1\npassword2\n")))
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("ReadFilter() error = %v, want %v", err, ErrInvalidFile)
+	}
+}
+
+func TestFilter_Nil(t *testing.T) {
+	var f *Filter
+
+	if f.Contains("Password1!") {
+		t.Error("Contains() = true for a nil filter")
+	}
+}
@@ -0,0 +1,7 @@
+package devicealert
+
+// Config of the security emails sent on logins from unknown devices.
+// ReportURL is the page of the frontend the token of a "this wasn't me" link is appended to
+type Config struct {
+	ReportURL string `yaml:"report_url" env:"DEVICE_ALERT_REPORT_URL"`
+}
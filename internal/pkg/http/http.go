@@ -0,0 +1,10 @@
+package http
+
+import "time"
+
+type Config struct {
+	Host         string        `yaml:"host" env:"HTTP_SERVER_HOST" env-required:"true"`
+	Port         int           `yaml:"port" env:"HTTP_SERVER_PORT" env-required:"true"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"HTTP_SERVER_READ_TIMEOUT" env-default:"5s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_SERVER_WRITE_TIMEOUT" env-default:"5s"`
+}
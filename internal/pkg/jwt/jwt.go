@@ -1,65 +1,314 @@
 package jwt
 
 import (
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
 	"time"
 )
 
+var ErrInvalidClaims = errors.New("invalid token claims")
+
 type Config struct {
-	SecretKey       string        `env:"JWT_SECRET_KEY" env-required:"true"`
-	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env:"JWT_ACCESS_TOKEN_TTL" env-default:"15m"`
-	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"JWT_REFRESH_TOKEN_TTL" env-default:"24h"`
+	Algorithm          string        `yaml:"algorithm" env:"JWT_ALGORITHM" env-default:"EdDSA"`
+	KeyEncryptionKey   string        `env:"JWT_KEY_ENCRYPTION_KEY" env-required:"true"`
+	KeyRotationPeriod  time.Duration `yaml:"key_rotation_period" env:"JWT_KEY_ROTATION_PERIOD" env-default:"720h"`
+	KeyRefreshInterval time.Duration `yaml:"key_refresh_interval" env:"JWT_KEY_REFRESH_INTERVAL" env-default:"5m"`
+	AccessTokenTTL     time.Duration `yaml:"access_token_ttl" env:"JWT_ACCESS_TOKEN_TTL" env-default:"15m"`
+	RefreshTokenTTL    time.Duration `yaml:"refresh_token_ttl" env:"JWT_REFRESH_TOKEN_TTL" env-default:"24h"`
+	MFATokenTTL        time.Duration `yaml:"mfa_token_ttl" env:"JWT_MFA_TOKEN_TTL" env-default:"5m"`
+	ImpersonationTTL   time.Duration `yaml:"impersonation_ttl" env:"JWT_IMPERSONATION_TTL" env-default:"10m"`
+	PasswordChangeTTL  time.Duration `yaml:"password_change_ttl" env:"JWT_PASSWORD_CHANGE_TTL" env-default:"10m"`
+	LoginLinkTTL       time.Duration `yaml:"login_link_ttl" env:"JWT_LOGIN_LINK_TTL" env-default:"10m"`
+	ElevatedTokenTTL   time.Duration `yaml:"elevated_token_ttl" env:"JWT_ELEVATED_TOKEN_TTL" env-default:"5m"`
+	LoginAlertTTL      time.Duration `yaml:"login_alert_ttl" env:"JWT_LOGIN_ALERT_TTL" env-default:"168h"`
+}
+
+// MaxTokenTTL is the lifetime of the longest living token signed with the keyring
+func (cfg Config) MaxTokenTTL() time.Duration {
+	return max(
+		cfg.AccessTokenTTL,
+		cfg.RefreshTokenTTL,
+		cfg.MFATokenTTL,
+		cfg.ImpersonationTTL,
+		cfg.PasswordChangeTTL,
+		cfg.LoginLinkTTL,
+		cfg.ElevatedTokenTTL,
+		cfg.LoginAlertTTL,
+	)
 }
 
 type Provider struct {
-	secretKey       string
-	accessTokenTTL  time.Duration
-	refreshTokenTTL time.Duration
+	keyring           *Keyring
+	accessTokenTTL    time.Duration
+	refreshTokenTTL   time.Duration
+	mfaTokenTTL       time.Duration
+	impersonationTTL  time.Duration
+	passwordChangeTTL time.Duration
+	loginLinkTTL      time.Duration
+	elevatedTokenTTL  time.Duration
+	loginAlertTTL     time.Duration
 }
 
 func NewProvider(
-	secretKey string,
+	keyring *Keyring,
 	accessTokenTTL time.Duration,
 	refreshTokenTTL time.Duration,
+	mfaTokenTTL time.Duration,
+	impersonationTTL time.Duration,
+	passwordChangeTTL time.Duration,
+	loginLinkTTL time.Duration,
+	elevatedTokenTTL time.Duration,
+	loginAlertTTL time.Duration,
 ) *Provider {
 	return &Provider{
-		secretKey:       secretKey,
-		accessTokenTTL:  accessTokenTTL,
-		refreshTokenTTL: refreshTokenTTL,
+		keyring:           keyring,
+		accessTokenTTL:    accessTokenTTL,
+		refreshTokenTTL:   refreshTokenTTL,
+		mfaTokenTTL:       mfaTokenTTL,
+		impersonationTTL:  impersonationTTL,
+		passwordChangeTTL: passwordChangeTTL,
+		loginLinkTTL:      loginLinkTTL,
+		elevatedTokenTTL:  elevatedTokenTTL,
+		loginAlertTTL:     loginAlertTTL,
 	}
 }
 
-func (jp *Provider) GenerateAccessToken(id uint64, roles []string) (string, time.Time, error) {
-	exp := time.Now().Add(jp.accessTokenTTL)
-	claims := jwt.MapClaims{
-		"sub":   id,
-		"roles": roles,
-		"iat":   time.Now().Unix(),
-		"exp":   exp.Unix(),
+func (jp *Provider) GenerateAccessToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypeAccess
+
+	return jp.generate(claims, jp.accessTokenTTL)
+}
+
+func (jp *Provider) GenerateRefreshToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypeRefresh
+
+	return jp.generate(claims, jp.refreshTokenTTL)
+}
+
+func (jp *Provider) GenerateMFAToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypeMFA
+
+	return jp.generate(claims, jp.mfaTokenTTL)
+}
+
+// GenerateImpersonationToken issues a short-lived access token of the user
+// which names the support agent acting as them in the act claim
+func (jp *Provider) GenerateImpersonationToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypeAccess
+
+	return jp.generate(claims, jp.impersonationTTL)
+}
+
+// GeneratePasswordChangeToken issues a token of a login which can only be used
+// to change the expired password of the user
+func (jp *Provider) GeneratePasswordChangeToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypePasswordChange
+
+	return jp.generate(claims, jp.passwordChangeTTL)
+}
+
+// GenerateLoginLinkToken issues the token of a magic link which completes a passwordless login
+func (jp *Provider) GenerateLoginLinkToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypeLoginLink
+
+	return jp.generate(claims, jp.loginLinkTTL)
+}
+
+// GenerateElevatedToken issues a short-lived access token of a user who just reauthenticated,
+// which can call the methods requiring a recent authentication
+func (jp *Provider) GenerateElevatedToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypeAccess
+
+	return jp.generate(claims, jp.elevatedTokenTTL)
+}
+
+// GenerateLoginAlertToken issues the token of the "this wasn't me" link
+// sent when a user logs in on an unknown device
+func (jp *Provider) GenerateLoginAlertToken(claims model.TokenClaims) (string, time.Time, error) {
+	claims.Type = model.TokenTypeLoginAlert
+
+	return jp.generate(claims, jp.loginAlertTTL)
+}
+
+func (jp *Provider) VerifyAndParseClaims(token string) (model.TokenClaims, error) {
+	jwtClaims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(
+		token,
+		jwtClaims,
+		jp.verificationKey,
+		jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}),
+	)
+	if err != nil {
+		return model.TokenClaims{}, err
+	}
+
+	// Tokens without a subject type were issued to users before service clients existed
+	subjectType := model.SubjectTypeUser
+	if jwtClaims["sty"] != nil {
+		sty, ok := jwtClaims["sty"].(string)
+		if !ok {
+			return model.TokenClaims{}, ErrInvalidClaims
+		}
+		subjectType = model.SubjectType(sty)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	var userID uint64
+	var clientID string
+	switch subjectType {
+	case model.SubjectTypeUser:
+		sub, ok := jwtClaims["sub"].(float64)
+		if !ok {
+			return model.TokenClaims{}, ErrInvalidClaims
+		}
+		userID = uint64(sub)
+	case model.SubjectTypeClient:
+		sub, ok := jwtClaims["sub"].(string)
+		if !ok || sub == "" {
+			return model.TokenClaims{}, ErrInvalidClaims
+		}
+		clientID = sub
+	default:
+		return model.TokenClaims{}, ErrInvalidClaims
+	}
 
-	tokenString, err := token.SignedString([]byte(jp.secretKey))
+	roles, err := stringSliceClaim(jwtClaims, "roles")
 	if err != nil {
-		return "", time.Time{}, err
+		return model.TokenClaims{}, err
+	}
+	scopes, err := stringSliceClaim(jwtClaims, "scp")
+	if err != nil {
+		return model.TokenClaims{}, err
+	}
+	var actorID uint64
+	if jwtClaims["act"] != nil {
+		act, ok := jwtClaims["act"].(map[string]interface{})
+		if !ok {
+			return model.TokenClaims{}, ErrInvalidClaims
+		}
+		actorSub, ok := act["sub"].(float64)
+		if !ok {
+			return model.TokenClaims{}, ErrInvalidClaims
+		}
+		actorID = uint64(actorSub)
+	}
+	sessionID, ok := jwtClaims["sid"].(string)
+	if !ok {
+		return model.TokenClaims{}, ErrInvalidClaims
+	}
+	tokenID, ok := jwtClaims["jti"].(string)
+	if !ok {
+		return model.TokenClaims{}, ErrInvalidClaims
+	}
+	tokenType, ok := jwtClaims["typ"].(string)
+	if !ok {
+		return model.TokenClaims{}, ErrInvalidClaims
+	}
+	// Tokens issued before auth_time existed count as authenticated long ago
+	var authTime time.Time
+	if jwtClaims["auth_time"] != nil {
+		at, ok := jwtClaims["auth_time"].(float64)
+		if !ok {
+			return model.TokenClaims{}, ErrInvalidClaims
+		}
+		authTime = time.Unix(int64(at), 0)
+	}
+	iat, err := jwtClaims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return model.TokenClaims{}, ErrInvalidClaims
+	}
+	// iat_ms tells apart tokens issued within the same second as a revocation
+	issuedAt := iat.Time
+	if jwtClaims["iat_ms"] != nil {
+		iatMs, ok := jwtClaims["iat_ms"].(float64)
+		if !ok {
+			return model.TokenClaims{}, ErrInvalidClaims
+		}
+		issuedAt = time.UnixMilli(int64(iatMs))
+	}
+	exp, err := jwtClaims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return model.TokenClaims{}, ErrInvalidClaims
 	}
 
-	return tokenString, exp, nil
+	return model.TokenClaims{
+		UserID:      userID,
+		ClientID:    clientID,
+		SubjectType: subjectType,
+		Roles:       roles,
+		Scopes:      scopes,
+		ActorID:     actorID,
+		SessionID:   sessionID,
+		TokenID:     tokenID,
+		Type:        model.TokenType(tokenType),
+		AuthTime:    authTime,
+		IssuedAt:    issuedAt,
+		ExpiresAt:   exp.Time,
+	}, nil
+}
+
+func stringSliceClaim(jwtClaims jwt.MapClaims, name string) ([]string, error) {
+	if jwtClaims[name] == nil {
+		return nil, nil
+	}
+
+	values, ok := jwtClaims[name].([]interface{})
+	if !ok {
+		return nil, ErrInvalidClaims
+	}
+
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i], ok = v.(string)
+		if !ok {
+			return nil, ErrInvalidClaims
+		}
+	}
+
+	return result, nil
 }
 
-func (jp *Provider) GenerateRefreshToken(id uint64, roles []string) (string, time.Time, error) {
-	exp := time.Now().Add(jp.refreshTokenTTL)
-	claims := jwt.MapClaims{
-		"sub":   id,
-		"roles": roles,
-		"iat":   time.Now().Unix(),
-		"exp":   exp.Unix(),
+func (jp *Provider) generate(claims model.TokenClaims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(ttl)
+	jwtClaims := jwt.MapClaims{
+		"sub":    claims.UserID,
+		"sty":    string(model.SubjectTypeUser),
+		"roles":  claims.Roles,
+		"sid":    claims.SessionID,
+		"jti":    claims.TokenID,
+		"typ":    string(claims.Type),
+		"iat":    now.Unix(),
+		"iat_ms": now.UnixMilli(),
+		"exp":    exp.Unix(),
+	}
+	if !claims.AuthTime.IsZero() {
+		jwtClaims["auth_time"] = claims.AuthTime.Unix()
+	}
+	if claims.ActorID != 0 {
+		jwtClaims["act"] = map[string]any{"sub": claims.ActorID}
+	}
+	if claims.SubjectType == model.SubjectTypeClient {
+		jwtClaims["sub"] = claims.ClientID
+		jwtClaims["sty"] = string(model.SubjectTypeClient)
+		jwtClaims["scp"] = claims.Scopes
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key, err := jp.keyring.signingKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
 
-	tokenString, err := token.SignedString([]byte(jp.secretKey))
+	method, err := key.method()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token := jwt.NewWithClaims(method, jwtClaims)
+	token.Header["kid"] = key.id
+
+	tokenString, err := token.SignedString(key.privateKey)
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -67,23 +316,25 @@ func (jp *Provider) GenerateRefreshToken(id uint64, roles []string) (string, tim
 	return tokenString, exp, nil
 }
 
-func (jp *Provider) VerifyAndParseClaims(token string) (uint64, []string, error) {
-	jwtClaims := jwt.MapClaims{}
+// JWKS returns the public keys tokens can be verified with
+func (jp *Provider) JWKS() []model.JSONWebKey {
+	return jp.keyring.JWKS()
+}
 
-	_, err := jwt.ParseWithClaims(token, jwtClaims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jp.secretKey), nil
-	})
-	if err != nil {
-		return 0, nil, err
+func (jp *Provider) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, ErrUnknownKey
 	}
 
-	id := uint64(jwtClaims["sub"].(float64))
-	roles := jwtClaims["roles"].([]interface{})
+	key, err := jp.keyring.verificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
 
-	roleStrings := make([]string, len(roles))
-	for i, v := range roles {
-		roleStrings[i] = v.(string)
+	if token.Method.Alg() != key.algorithm {
+		return nil, ErrUnsupportedAlgorithm
 	}
 
-	return id, roleStrings, nil
+	return key.publicKey(), nil
 }
@@ -0,0 +1,326 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type memoryKeyStore struct {
+	keys map[string]model.SigningKey
+}
+
+func newMemoryKeyStore() *memoryKeyStore {
+	return &memoryKeyStore{keys: make(map[string]model.SigningKey)}
+}
+
+func (s *memoryKeyStore) FindAll(_ context.Context) ([]model.SigningKey, error) {
+	var keys []model.SigningKey
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (s *memoryKeyStore) Insert(_ context.Context, key model.SigningKey) error {
+	s.keys[key.ID] = key
+
+	return nil
+}
+
+func (s *memoryKeyStore) Delete(_ context.Context, id string) error {
+	delete(s.keys, id)
+
+	return nil
+}
+
+func testConfig(algorithm string) Config {
+	return Config{
+		Algorithm:          algorithm,
+		KeyEncryptionKey:   base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		KeyRotationPeriod:  24 * time.Hour,
+		KeyRefreshInterval: 5 * time.Minute,
+		AccessTokenTTL:     15 * time.Minute,
+		RefreshTokenTTL:    time.Hour,
+		MFATokenTTL:        5 * time.Minute,
+		ImpersonationTTL:   10 * time.Minute,
+		PasswordChangeTTL:  10 * time.Minute,
+		LoginLinkTTL:       10 * time.Minute,
+		ElevatedTokenTTL:   5 * time.Minute,
+		LoginAlertTTL:      168 * time.Hour,
+	}
+}
+
+func setupProvider(t *testing.T, algorithm string) (*Provider, *KeyRotator, *memoryKeyStore) {
+	cfg := testConfig(algorithm)
+	store := newMemoryKeyStore()
+	keyring := NewKeyring(cfg.KeyRefreshInterval)
+
+	rotator, err := NewKeyRotator(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, keyring, store)
+	require.NoError(t, err)
+	require.NoError(t, rotator.Rotate(context.Background()))
+
+	return NewProvider(
+		keyring,
+		cfg.AccessTokenTTL,
+		cfg.RefreshTokenTTL,
+		cfg.MFATokenTTL,
+		cfg.ImpersonationTTL,
+		cfg.PasswordChangeTTL,
+		cfg.LoginLinkTTL,
+		cfg.ElevatedTokenTTL,
+		cfg.LoginAlertTTL,
+	), rotator, store
+}
+
+// age moves the creation time of every stored key into the past
+func (s *memoryKeyStore) age(d time.Duration) {
+	for id, key := range s.keys {
+		key.CreatedAt = key.CreatedAt.Add(-d)
+		s.keys[id] = key
+	}
+}
+
+func TestProvider_RoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			provider, _, _ := setupProvider(t, algorithm)
+
+			claims := model.TokenClaims{
+				UserID:    42,
+				Roles:     []string{"user"},
+				SessionID: "session",
+				TokenID:   "token",
+			}
+
+			token, exp, err := provider.GenerateAccessToken(claims)
+			require.NoError(t, err)
+
+			parsed, err := provider.VerifyAndParseClaims(token)
+			require.NoError(t, err)
+
+			assert.Equal(t, claims.UserID, parsed.UserID)
+			assert.Equal(t, claims.Roles, parsed.Roles)
+			assert.Equal(t, claims.SessionID, parsed.SessionID)
+			assert.Equal(t, claims.TokenID, parsed.TokenID)
+			assert.Equal(t, model.TokenTypeAccess, parsed.Type)
+			assert.Equal(t, exp.Unix(), parsed.ExpiresAt.Unix())
+
+			jwks := provider.JWKS()
+			require.Len(t, jwks, 1)
+			assert.Equal(t, algorithm, jwks[0].Algorithm)
+		})
+	}
+}
+
+func TestProvider_RejectsTokenOfUnknownKey(t *testing.T) {
+	provider, _, _ := setupProvider(t, AlgorithmEdDSA)
+	other, _, _ := setupProvider(t, AlgorithmEdDSA)
+
+	token, _, err := other.GenerateAccessToken(model.TokenClaims{UserID: 1, SessionID: "s", TokenID: "t"})
+	require.NoError(t, err)
+
+	_, err = provider.VerifyAndParseClaims(token)
+	assert.Error(t, err)
+}
+
+func TestProvider_RejectsSymmetricToken(t *testing.T) {
+	provider, _, _ := setupProvider(t, AlgorithmEdDSA)
+	kid := provider.JWKS()[0].KeyID
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   1,
+		"roles": []string{"admin"},
+		"sid":   "s",
+		"jti":   "t",
+		"typ":   "access",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = provider.VerifyAndParseClaims(tokenString)
+	assert.Error(t, err)
+}
+
+func TestKeyRotator_RotatesAfterPeriod(t *testing.T) {
+	provider, rotator, store := setupProvider(t, AlgorithmEdDSA)
+	ctx := context.Background()
+
+	oldToken, _, err := provider.GenerateAccessToken(model.TokenClaims{UserID: 1, SessionID: "s", TokenID: "t"})
+	require.NoError(t, err)
+	oldKID := provider.JWKS()[0].KeyID
+
+	store.age(25 * time.Hour)
+	require.NoError(t, rotator.Rotate(ctx))
+
+	jwks := provider.JWKS()
+	require.Len(t, jwks, 2)
+	assert.NotEqual(t, oldKID, jwks[0].KeyID)
+	assert.Equal(t, oldKID, jwks[1].KeyID)
+
+	// The new key is only published, tokens are still signed with the old one
+	newToken, _, err := provider.GenerateAccessToken(model.TokenClaims{UserID: 1, SessionID: "s", TokenID: "t"})
+	require.NoError(t, err)
+	header, err := tokenKeyID(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, oldKID, header)
+
+	_, err = provider.VerifyAndParseClaims(oldToken)
+	assert.NoError(t, err)
+}
+
+func TestKeyRotator_RemovesExpiredKeys(t *testing.T) {
+	provider, rotator, store := setupProvider(t, AlgorithmEdDSA)
+	ctx := context.Background()
+	oldKID := provider.JWKS()[0].KeyID
+
+	store.age(25 * time.Hour)
+	require.NoError(t, rotator.Rotate(ctx))
+	require.Len(t, store.keys, 2)
+
+	// The old key retired 25 minutes ago, tokens it signed are still valid
+	store.age(30 * time.Minute)
+	require.NoError(t, rotator.Rotate(ctx))
+
+	assert.Len(t, store.keys, 2)
+	assert.Len(t, provider.JWKS(), 2)
+
+	// Every token signed by the old key has expired by now, login alert tokens live the longest.
+	// The second key is due for rotation as well, it stays until its successor signs
+	store.age(168 * time.Hour)
+	require.NoError(t, rotator.Rotate(ctx))
+
+	assert.NotContains(t, store.keys, oldKID)
+	assert.Len(t, store.keys, 2)
+	assert.Len(t, provider.JWKS(), 2)
+}
+
+func TestKeyRotator_KeepsKeyOfLoginAlertTokens(t *testing.T) {
+	provider, rotator, store := setupProvider(t, AlgorithmEdDSA)
+	ctx := context.Background()
+
+	token, _, err := provider.GenerateLoginAlertToken(model.TokenClaims{
+		UserID:    42,
+		SessionID: "session",
+		TokenID:   "alert",
+	})
+	require.NoError(t, err)
+
+	store.age(25 * time.Hour)
+	require.NoError(t, rotator.Rotate(ctx))
+
+	// The key retired two days ago, far longer than access and refresh tokens live
+	store.age(48 * time.Hour)
+	require.NoError(t, rotator.Rotate(ctx))
+
+	parsed, err := provider.VerifyAndParseClaims(token)
+	require.NoError(t, err)
+	assert.Equal(t, model.TokenTypeLoginAlert, parsed.Type)
+}
+
+func tokenKeyID(token string) (string, error) {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return "", err
+	}
+
+	kid, _ := parsed.Header["kid"].(string)
+
+	return kid, nil
+}
+
+func TestProvider_ClientTokenRoundTrip(t *testing.T) {
+	provider, _, _ := setupProvider(t, AlgorithmEdDSA)
+
+	claims := model.TokenClaims{
+		ClientID:    "booking",
+		SubjectType: model.SubjectTypeClient,
+		Scopes:      []string{"/service.user.UserService/Get"},
+		TokenID:     "token",
+	}
+
+	token, _, err := provider.GenerateAccessToken(claims)
+	require.NoError(t, err)
+
+	parsed, err := provider.VerifyAndParseClaims(token)
+	require.NoError(t, err)
+
+	assert.Equal(t, model.SubjectTypeClient, parsed.SubjectType)
+	assert.Equal(t, "booking", parsed.ClientID)
+	assert.Zero(t, parsed.UserID)
+	assert.Equal(t, claims.Scopes, parsed.Scopes)
+	assert.Empty(t, parsed.Roles)
+}
+
+func TestProvider_ImpersonationTokenCarriesActor(t *testing.T) {
+	provider, _, _ := setupProvider(t, AlgorithmEdDSA)
+
+	token, exp, err := provider.GenerateImpersonationToken(model.TokenClaims{
+		UserID:    42,
+		ActorID:   7,
+		Roles:     []string{"user"},
+		SessionID: "session",
+		TokenID:   "token",
+	})
+	require.NoError(t, err)
+
+	parsed, err := provider.VerifyAndParseClaims(token)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(42), parsed.UserID)
+	assert.Equal(t, uint64(7), parsed.ActorID)
+	assert.Equal(t, model.TokenTypeAccess, parsed.Type)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), exp, time.Minute)
+}
+
+func TestProvider_ElevatedTokenCarriesAuthTime(t *testing.T) {
+	provider, _, _ := setupProvider(t, AlgorithmEdDSA)
+	authTime := time.Now().Truncate(time.Second)
+
+	token, exp, err := provider.GenerateElevatedToken(model.TokenClaims{
+		UserID:    42,
+		Roles:     []string{"user"},
+		SessionID: "session",
+		TokenID:   "token",
+		AuthTime:  authTime,
+	})
+	require.NoError(t, err)
+
+	parsed, err := provider.VerifyAndParseClaims(token)
+	require.NoError(t, err)
+
+	assert.Equal(t, model.TokenTypeAccess, parsed.Type)
+	assert.True(t, authTime.Equal(parsed.AuthTime))
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), exp, time.Minute)
+}
+
+func TestProvider_IssuedAtHasMilliseconds(t *testing.T) {
+	provider, _, _ := setupProvider(t, AlgorithmEdDSA)
+	// A revocation earlier in the same second must not reject the token
+	revokedAt := time.Now().Truncate(time.Millisecond)
+
+	token, _, err := provider.GenerateAccessToken(model.TokenClaims{
+		UserID:    42,
+		Roles:     []string{"user"},
+		SessionID: "session",
+		TokenID:   "token",
+	})
+	require.NoError(t, err)
+
+	parsed, err := provider.VerifyAndParseClaims(token)
+	require.NoError(t, err)
+
+	assert.False(t, parsed.IssuedAt.Before(revokedAt))
+	assert.WithinDuration(t, time.Now(), parsed.IssuedAt, time.Second)
+}
@@ -0,0 +1,154 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"math/big"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeyBits  = 2048
+	keyIDLength = 16
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrNoSigningKey         = errors.New("no signing key available")
+	ErrUnknownKey           = errors.New("unknown signing key")
+)
+
+type signingKey struct {
+	id         string
+	algorithm  string
+	privateKey crypto.Signer
+	createdAt  time.Time
+}
+
+func generateKey(algorithm string) (signingKey, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+
+	switch algorithm {
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return signingKey{}, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	return signingKey{
+		id:         security.RandomString(keyIDLength),
+		algorithm:  algorithm,
+		privateKey: privateKey,
+		createdAt:  time.Now().UTC(),
+	}, nil
+}
+
+// keyFromModel decrypts and parses a persisted key
+func keyFromModel(key model.SigningKey, encryptionKey []byte) (signingKey, error) {
+	der, err := security.Decrypt(encryptionKey, key.PrivateKey)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	privateKey, ok := parsed.(crypto.Signer)
+	if !ok {
+		return signingKey{}, ErrUnsupportedAlgorithm
+	}
+
+	sk := signingKey{
+		id:         key.ID,
+		algorithm:  key.Algorithm,
+		privateKey: privateKey,
+		createdAt:  key.CreatedAt,
+	}
+
+	if _, err = sk.method(); err != nil {
+		return signingKey{}, err
+	}
+
+	return sk, nil
+}
+
+// toModel encrypts the key for persisting
+func (k signingKey) toModel(encryptionKey []byte) (model.SigningKey, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.privateKey)
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+
+	encrypted, err := security.Encrypt(encryptionKey, der)
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+
+	return model.SigningKey{
+		ID:         k.id,
+		Algorithm:  k.algorithm,
+		PrivateKey: encrypted,
+		CreatedAt:  k.createdAt,
+	}, nil
+}
+
+func (k signingKey) method() (jwt.SigningMethod, error) {
+	switch k.algorithm {
+	case AlgorithmRS256:
+		if _, ok := k.privateKey.(*rsa.PrivateKey); ok {
+			return jwt.SigningMethodRS256, nil
+		}
+	case AlgorithmEdDSA:
+		if _, ok := k.privateKey.(ed25519.PrivateKey); ok {
+			return jwt.SigningMethodEdDSA, nil
+		}
+	}
+
+	return nil, ErrUnsupportedAlgorithm
+}
+
+func (k signingKey) publicKey() crypto.PublicKey {
+	return k.privateKey.Public()
+}
+
+func (k signingKey) jwk() model.JSONWebKey {
+	jwk := model.JSONWebKey{
+		KeyID:     k.id,
+		Algorithm: k.algorithm,
+		Use:       "sig",
+	}
+
+	switch pub := k.publicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return jwk
+}
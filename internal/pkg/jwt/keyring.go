@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Keyring holds every key whose tokens may still be in circulation.
+// A new key is published for publishDelay before it is used for signing,
+// so that other replicas and JWKS consumers learn about it first
+type Keyring struct {
+	mu           sync.RWMutex
+	keys         []signingKey // keys are sorted from the newest to the oldest
+	publishDelay time.Duration
+}
+
+func NewKeyring(publishDelay time.Duration) *Keyring {
+	return &Keyring{
+		publishDelay: publishDelay,
+	}
+}
+
+func (kr *Keyring) set(keys []signingKey) {
+	sorted := sortedByAge(keys)
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	kr.keys = sorted
+}
+
+// signingKey returns the newest published key. Until any key is published,
+// which only happens right after the very first key was generated, the newest key is used
+func (kr *Keyring) signingKey() (signingKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	if len(kr.keys) == 0 {
+		return signingKey{}, ErrNoSigningKey
+	}
+
+	publishedBefore := time.Now().Add(-kr.publishDelay)
+	for _, key := range kr.keys {
+		if !key.createdAt.After(publishedBefore) {
+			return key, nil
+		}
+	}
+
+	return kr.keys[0], nil
+}
+
+func (kr *Keyring) verificationKey(id string) (signingKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	for _, key := range kr.keys {
+		if key.id == id {
+			return key, nil
+		}
+	}
+
+	return signingKey{}, ErrUnknownKey
+}
+
+// JWKS returns the public keys of the keyring
+func (kr *Keyring) JWKS() []model.JSONWebKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	jwks := make([]model.JSONWebKey, len(kr.keys))
+	for i, key := range kr.keys {
+		jwks[i] = key.jwk()
+	}
+
+	return jwks
+}
+
+// sortedByAge returns a copy of the keys sorted from the newest to the oldest
+func sortedByAge(keys []signingKey) []signingKey {
+	sorted := make([]signingKey, len(keys))
+	copy(sorted, keys)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].createdAt.After(sorted[j].createdAt)
+	})
+
+	return sorted
+}
@@ -0,0 +1,144 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"log/slog"
+	"time"
+)
+
+var ErrInvalidEncryptionKey = errors.New("key encryption key must be 32 base64 encoded bytes")
+
+type KeyStore interface {
+	FindAll(ctx context.Context) ([]model.SigningKey, error)
+	Insert(ctx context.Context, key model.SigningKey) error
+	Delete(ctx context.Context, id string) error
+}
+
+// KeyRotator keeps the keyring in sync with the key store.
+// It generates a new key once the newest one is older than the rotation period
+// and removes keys which no token in circulation can be signed with anymore
+type KeyRotator struct {
+	log             *slog.Logger
+	keyring         *Keyring
+	store           KeyStore
+	algorithm       string
+	encryptionKey   []byte
+	rotationPeriod  time.Duration
+	refreshInterval time.Duration
+	maxTokenTTL     time.Duration
+}
+
+func NewKeyRotator(log *slog.Logger, cfg Config, keyring *Keyring, store KeyStore) (*KeyRotator, error) {
+	if cfg.Algorithm != AlgorithmRS256 && cfg.Algorithm != AlgorithmEdDSA {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	encryptionKey, err := base64.StdEncoding.DecodeString(cfg.KeyEncryptionKey)
+	if err != nil || len(encryptionKey) != 32 {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	return &KeyRotator{
+		log:             log,
+		keyring:         keyring,
+		store:           store,
+		algorithm:       cfg.Algorithm,
+		encryptionKey:   encryptionKey,
+		rotationPeriod:  cfg.KeyRotationPeriod,
+		refreshInterval: cfg.KeyRefreshInterval,
+		maxTokenTTL:     cfg.MaxTokenTTL(),
+	}, nil
+}
+
+// Run rotates the keys every refresh interval until the context is done
+func (r *KeyRotator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Rotate(ctx)
+			if err != nil {
+				r.log.Error("jwt: rotating signing keys", logger.Err(err))
+			}
+		}
+	}
+}
+
+func (r *KeyRotator) Rotate(ctx context.Context) error {
+	stored, err := r.store.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]signingKey, 0, len(stored))
+	for _, storedKey := range stored {
+		key, err := keyFromModel(storedKey, r.encryptionKey)
+		if err != nil {
+			r.log.Error(
+				"jwt: parsing signing key",
+				logger.Err(err),
+				slog.String("kid", storedKey.ID),
+			)
+
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+	keys = sortedByAge(keys)
+
+	now := time.Now()
+
+	if len(keys) == 0 || now.Sub(keys[0].createdAt) >= r.rotationPeriod {
+		key, err := generateKey(r.algorithm)
+		if err != nil {
+			return err
+		}
+
+		storedKey, err := key.toModel(r.encryptionKey)
+		if err != nil {
+			return err
+		}
+
+		err = r.store.Insert(ctx, storedKey)
+		if err != nil {
+			return err
+		}
+
+		r.log.Info("jwt: generated signing key", slog.String("kid", key.id))
+
+		keys = append([]signingKey{key}, keys...)
+	}
+
+	// A key stops signing once the next newer key is published.
+	// It has to be kept until every token it signed has expired
+	retained := keys[:1]
+	for i := 1; i < len(keys); i++ {
+		retiredAt := keys[i-1].createdAt.Add(r.keyring.publishDelay)
+
+		if now.Sub(retiredAt) <= r.maxTokenTTL {
+			retained = append(retained, keys[i])
+
+			continue
+		}
+
+		err = r.store.Delete(ctx, keys[i].id)
+		if err != nil {
+			return err
+		}
+
+		r.log.Info("jwt: removed expired signing key", slog.String("kid", keys[i].id))
+	}
+
+	r.keyring.set(retained)
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+const ctxRequestAttrsKey = "log-request-attrs"
+
+// requestAttrs are collected while a request is handled,
+// e.g. the actor of an impersonation token is only known once the token is authenticated
+type requestAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithRequestAttrs returns a context whose log records carry the attributes added to it with AddRequestAttrs,
+// including the ones added by the handlers further down the chain
+func WithRequestAttrs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxRequestAttrsKey, &requestAttrs{})
+}
+
+// AddRequestAttrs adds attributes to every log record of the request logged with a context method of slog.Logger
+func AddRequestAttrs(ctx context.Context, attrs ...slog.Attr) {
+	ra, ok := ctx.Value(ctxRequestAttrsKey).(*requestAttrs)
+	if !ok {
+		return
+	}
+
+	ra.mu.Lock()
+	ra.attrs = append(ra.attrs, attrs...)
+	ra.mu.Unlock()
+}
+
+// contextHandler adds the request attributes of the context to the records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ra, ok := ctx.Value(ctxRequestAttrsKey).(*requestAttrs); ok {
+		ra.mu.Lock()
+		r.AddAttrs(ra.attrs...)
+		ra.mu.Unlock()
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
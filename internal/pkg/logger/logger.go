@@ -19,15 +19,15 @@ func SetupLogger(env string) *slog.Logger {
 	case envLocal:
 		log = setupPrettyLogger()
 	case envDev:
-		log = slog.New(slog.NewJSONHandler(
+		log = slog.New(contextHandler{slog.NewJSONHandler(
 			os.Stdout,
 			&slog.HandlerOptions{Level: slog.LevelDebug},
-		))
+		)})
 	case envProd:
-		log = slog.New(slog.NewJSONHandler(
+		log = slog.New(contextHandler{slog.NewJSONHandler(
 			os.Stdout,
 			&slog.HandlerOptions{Level: slog.LevelInfo},
-		))
+		)})
 	}
 
 	return log
@@ -42,7 +42,7 @@ func setupPrettyLogger() *slog.Logger {
 
 	handler := opts.PrettyHandler(os.Stdout)
 
-	return slog.New(handler)
+	return slog.New(contextHandler{handler})
 }
 
 func Err(err error) slog.Attr {
@@ -0,0 +1,10 @@
+package loginhistory
+
+import "time"
+
+// Config of the login history. Events older than Retention are purged every PurgeInterval,
+// a Retention or a PurgeInterval of 0 keeps them forever
+type Config struct {
+	Retention     time.Duration `yaml:"retention" env:"LOGIN_HISTORY_RETENTION" env-default:"2160h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"LOGIN_HISTORY_PURGE_INTERVAL" env-default:"1h"`
+}
@@ -0,0 +1,202 @@
+package password
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+var (
+	ErrMismatchedHash   = errors.New("password does not match the hash")
+	ErrInvalidHash      = errors.New("invalid password hash")
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrInvalidParams    = errors.New("invalid password hash parameters")
+)
+
+type Config struct {
+	Algorithm         string `yaml:"algorithm" env:"PASSWORD_HASH_ALGORITHM" env-default:"argon2id"`
+	Argon2Memory      uint32 `yaml:"argon2_memory" env:"PASSWORD_ARGON2_MEMORY" env-default:"65536"` // Argon2Memory is in KiB
+	Argon2Iterations  uint32 `yaml:"argon2_iterations" env:"PASSWORD_ARGON2_ITERATIONS" env-default:"3"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env:"PASSWORD_ARGON2_PARALLELISM" env-default:"2"`
+	BcryptCost        int    `yaml:"bcrypt_cost" env:"PASSWORD_BCRYPT_COST" env-default:"10"`
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// Hasher hashes passwords with the configured algorithm into PHC strings
+// and verifies hashes of every supported algorithm, so that the algorithm
+// or its parameters can be changed without invalidating stored hashes
+type Hasher struct {
+	algorithm  string
+	argon2id   argon2idParams
+	bcryptCost int
+}
+
+func NewHasher(cfg Config) (*Hasher, error) {
+	switch cfg.Algorithm {
+	case AlgorithmArgon2id:
+		if cfg.Argon2Memory == 0 || cfg.Argon2Iterations == 0 || cfg.Argon2Parallelism == 0 {
+			return nil, ErrInvalidParams
+		}
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, ErrInvalidParams
+		}
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+
+	return &Hasher{
+		algorithm: cfg.Algorithm,
+		argon2id: argon2idParams{
+			memory:      cfg.Argon2Memory,
+			iterations:  cfg.Argon2Iterations,
+			parallelism: cfg.Argon2Parallelism,
+		},
+		bcryptCost: cfg.BcryptCost,
+	}, nil
+}
+
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		return bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	}
+
+	salt := security.Bytes(argon2idSaltLength)
+	key := argon2.IDKey(
+		[]byte(password),
+		salt,
+		h.argon2id.iterations,
+		h.argon2id.memory,
+		h.argon2id.parallelism,
+		argon2idKeyLength,
+	)
+
+	return []byte(encodeArgon2id(h.argon2id, salt, key)), nil
+}
+
+// Verify returns ErrMismatchedHash if the password does not match the hash
+func (h *Hasher) Verify(password string, hash []byte) error {
+	switch algorithmOf(hash) {
+	case AlgorithmArgon2id:
+		params, salt, key, err := decodeArgon2id(string(hash))
+		if err != nil {
+			return err
+		}
+
+		otherKey := argon2.IDKey(
+			[]byte(password),
+			salt,
+			params.iterations,
+			params.memory,
+			params.parallelism,
+			uint32(len(key)),
+		)
+		if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+			return ErrMismatchedHash
+		}
+
+		return nil
+	case AlgorithmBcrypt:
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedHash
+		}
+
+		return err
+	default:
+		return ErrUnknownAlgorithm
+	}
+}
+
+// NeedsRehash tells whether the hash was made with another algorithm or other parameters than configured
+func (h *Hasher) NeedsRehash(hash []byte) bool {
+	algorithm := algorithmOf(hash)
+	if algorithm != h.algorithm {
+		return true
+	}
+
+	if algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost(hash)
+
+		return err != nil || cost != h.bcryptCost
+	}
+
+	params, _, key, err := decodeArgon2id(string(hash))
+
+	return err != nil || params != h.argon2id || len(key) != argon2idKeyLength
+}
+
+func algorithmOf(hash []byte) string {
+	s := string(hash)
+
+	switch {
+	case strings.HasPrefix(s, "$argon2id$"):
+		return AlgorithmArgon2id
+	// bcrypt hashes predate PHC strings and use the modular crypt format
+	case strings.HasPrefix(s, "$2a$"), strings.HasPrefix(s, "$2b$"), strings.HasPrefix(s, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return ""
+	}
+}
+
+// encodeArgon2id formats the hash as $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func encodeArgon2id(params argon2idParams, salt, key []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.memory,
+		params.iterations,
+		params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(hash string) (argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	var params argon2idParams
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil {
+		return argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	return params, salt, key, nil
+}
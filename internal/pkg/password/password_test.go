@@ -0,0 +1,135 @@
+package password
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+// testConfig keeps argon2id cheap, the parameters do not matter for correctness
+var testConfig = Config{
+	Algorithm:         AlgorithmArgon2id,
+	Argon2Memory:      1024,
+	Argon2Iterations:  1,
+	Argon2Parallelism: 1,
+	BcryptCost:        bcrypt.MinCost,
+}
+
+func newTestHasher(t *testing.T, cfg Config) *Hasher {
+	t.Helper()
+
+	hasher, err := NewHasher(cfg)
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+
+	return hasher
+}
+
+func TestHasher_Argon2id(t *testing.T) {
+	hasher := newTestHasher(t, testConfig)
+
+	hash, err := hasher.Hash("StrongPass123!")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	if !strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Hash() = %s, want a PHC argon2id string", hash)
+	}
+	if err = hasher.Verify("StrongPass123!", hash); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err = hasher.Verify("WrongPass123!", hash); !errors.Is(err, ErrMismatchedHash) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrMismatchedHash)
+	}
+	if hasher.NeedsRehash(hash) {
+		t.Error("NeedsRehash() = true for a hash with the configured parameters")
+	}
+}
+
+func TestHasher_LongPasswords(t *testing.T) {
+	hasher := newTestHasher(t, testConfig)
+
+	prefix := strings.Repeat("a", 72)
+
+	hash, err := hasher.Hash(prefix + "first")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	if err = hasher.Verify(prefix+"second", hash); !errors.Is(err, ErrMismatchedHash) {
+		t.Errorf("Verify() error = %v, passwords sharing 72 bytes must not match", err)
+	}
+}
+
+func TestHasher_LegacyBcrypt(t *testing.T) {
+	hasher := newTestHasher(t, testConfig)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass123!"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+
+	if err = hasher.Verify("StrongPass123!", hash); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err = hasher.Verify("WrongPass123!", hash); !errors.Is(err, ErrMismatchedHash) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrMismatchedHash)
+	}
+	if !hasher.NeedsRehash(hash) {
+		t.Error("NeedsRehash() = false for a bcrypt hash")
+	}
+}
+
+func TestHasher_NeedsRehash_OutdatedParams(t *testing.T) {
+	hash, err := newTestHasher(t, testConfig).Hash("StrongPass123!")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	cfg := testConfig
+	cfg.Argon2Iterations = 2
+	hasher := newTestHasher(t, cfg)
+
+	if !hasher.NeedsRehash(hash) {
+		t.Error("NeedsRehash() = false for a hash with outdated parameters")
+	}
+	if err = hasher.Verify("StrongPass123!", hash); err != nil {
+		t.Errorf("Verify() error = %v, hashes with outdated parameters must still verify", err)
+	}
+}
+
+func TestHasher_InvalidHash(t *testing.T) {
+	hasher := newTestHasher(t, testConfig)
+
+	tests := []struct {
+		name string
+		hash string
+		want error
+	}{
+		{name: "unknown algorithm", hash: "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5", want: ErrUnknownAlgorithm},
+		{name: "missing key", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", want: ErrInvalidHash},
+		{name: "other version", hash: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5", want: ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := hasher.Verify("StrongPass123!", []byte(tt.hash))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHasher_InvalidConfig(t *testing.T) {
+	cfg := testConfig
+	cfg.Algorithm = "md5"
+
+	_, err := NewHasher(cfg)
+	if !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("NewHasher() error = %v, want %v", err, ErrUnknownAlgorithm)
+	}
+}
@@ -0,0 +1,12 @@
+package passwordless
+
+import "time"
+
+// Config switches the passwordless login with emailed one-time codes and magic links.
+// LinkURL is the page of the frontend the login token of a magic link is appended to
+type Config struct {
+	Enabled     bool          `yaml:"enabled" env:"PASSWORDLESS_ENABLED" env-default:"false"`
+	LinkURL     string        `yaml:"link_url" env:"PASSWORDLESS_LINK_URL"`
+	CodeTTL     time.Duration `yaml:"code_ttl" env:"PASSWORDLESS_CODE_TTL" env-default:"10m"`
+	MaxAttempts int64         `yaml:"max_attempts" env:"PASSWORDLESS_MAX_ATTEMPTS" env-default:"5"`
+}
@@ -2,6 +2,7 @@ package security
 
 import "golang.org/x/crypto/bcrypt"
 
+// HashString hashes short-lived verification codes. Passwords are hashed with password.Hasher
 func HashString(s string) ([]byte, error) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(s), bcrypt.DefaultCost)
 
@@ -1,6 +1,9 @@
 package security
 
-import "crypto/rand"
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
 
 func Bytes(n int) []byte {
 	b := make([]byte, n)
@@ -10,3 +13,8 @@ func Bytes(n int) []byte {
 	}
 	return b
 }
+
+// RandomString returns n random bytes encoded as an url-safe base64 string
+func RandomString(n int) string {
+	return base64.RawURLEncoding.EncodeToString(Bytes(n))
+}
@@ -0,0 +1,11 @@
+package security
+
+import "crypto/sha256"
+
+// SHA256 hashes high entropy secrets such as random codes and tokens, which
+// are looked up by their hash and do not need a slow password hash
+func SHA256(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+
+	return sum[:]
+}
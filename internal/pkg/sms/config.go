@@ -0,0 +1,7 @@
+package sms
+
+// Config selects where text messages are written. Messages go to stdout
+// unless FilePath is set, in which case they are appended to that file
+type Config struct {
+	FilePath string `yaml:"file_path" env:"SMS_FILE_PATH"`
+}
@@ -0,0 +1,112 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Period       = 30 * time.Second
+	Digits       = 6
+	skew         = 1 // skew is the number of periods before and after the current one a code is accepted for
+	secretLength = 20
+)
+
+var (
+	ErrInvalidEncryptionKey = errors.New("totp secret encryption key must be 32 base64 encoded bytes")
+	ErrInvalidSecret        = errors.New("invalid totp secret")
+)
+
+type Config struct {
+	Issuer              string `yaml:"issuer" env:"TOTP_ISSUER" env-default:"Car Rental"`
+	SecretEncryptionKey string `env:"TOTP_SECRET_ENCRYPTION_KEY" env-required:"true"`
+}
+
+// EncryptionKey decodes the key TOTP secrets are encrypted with at rest
+func (c Config) EncryptionKey() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(c.SecretEncryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	return key, nil
+}
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 encoded secret
+func GenerateSecret() string {
+	return encoding.EncodeToString(security.Bytes(secretLength))
+}
+
+// URI returns the otpauth URI authenticator apps are enrolled with, usually shown as a QR code
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+// Step returns the time step t falls into
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code returns the code of the secret for the time step as described in RFC 6238
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate checks the code against the periods around now and returns the time step it matched
+func Validate(secret, code string, now time.Time) (int64, bool) {
+	current := Step(now)
+
+	for step := current - skew; step <= current+skew; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,75 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 secret of the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "59", unix: 59, want: "287082"},
+		{name: "1111111109", unix: 1111111109, want: "081804"},
+		{name: "1111111111", unix: 1111111111, want: "050471"},
+		{name: "1234567890", unix: 1234567890, want: "005924"},
+		{name: "2000000000", unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Code() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	previous, _ := Code(rfcSecret, step-1)
+	tooOld, _ := Code(rfcSecret, step-2)
+
+	matched, ok := Validate(rfcSecret, previous, now)
+	if !ok || matched != step-1 {
+		t.Errorf("Validate() of previous period = (%d, %v), want (%d, true)", matched, ok, step-1)
+	}
+
+	_, ok = Validate(rfcSecret, tooOld, now)
+	if ok {
+		t.Error("Validate() accepted a code outside the allowed skew")
+	}
+
+	_, ok = Validate(rfcSecret, "not-a-code", now)
+	if ok {
+		t.Error("Validate() accepted an invalid code")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret := GenerateSecret()
+
+	_, err := Code(secret, 1)
+	if err != nil {
+		t.Fatalf("generated secret is not usable: %v", err)
+	}
+
+	uri := URI("Car Rental", "user@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Car%20Rental:user@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("URI() = %q", uri)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -12,6 +13,8 @@ var (
 	hasLowerRX   = regexp.MustCompile(`[a-z]`)
 	hasNumberRX  = regexp.MustCompile(`[0-9]`)
 	hasSpecialRX = regexp.MustCompile(`[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>?~]`)
+
+	phoneSeparatorsReplacer = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
 )
 
 func MinAge(fl validator.FieldLevel) bool {
@@ -45,3 +48,28 @@ func ComplexPassword(fl validator.FieldLevel) bool {
 
 	return hasUpper && hasLower && hasNumber && hasSpecial
 }
+
+// BreachedPasswords is a corpus of passwords exposed in data breaches
+type BreachedPasswords interface {
+	Contains(password string) bool
+}
+
+// NotBreached rejects passwords found in the breached password corpus
+func NotBreached(breachedPasswords BreachedPasswords) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return !breachedPasswords.Contains(fl.Field().String())
+	}
+}
+
+// NormalizePhoneNumber brings a phone number written in a common human form,
+// like "+1 (234) 567-89-00" or "00 1 234 567 89 00", to the E.164 form.
+// The result still has to be validated with the e164 tag
+func NormalizePhoneNumber(phoneNumber string) string {
+	normalized := phoneSeparatorsReplacer.Replace(strings.TrimSpace(phoneNumber))
+
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + strings.TrimPrefix(normalized, "00")
+	}
+
+	return normalized
+}
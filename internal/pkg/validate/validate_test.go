@@ -197,3 +197,72 @@ func TestComplexPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		phoneNumber string
+		want        string
+	}{
+		{
+			name:        "already normalized",
+			phoneNumber: "+77011234567",
+			want:        "+77011234567",
+		},
+		{
+			name:        "spaces, dashes and parentheses",
+			phoneNumber: "+7 (701) 123-45-67",
+			want:        "+77011234567",
+		},
+		{
+			name:        "international prefix",
+			phoneNumber: "00 44 20 7946 0958",
+			want:        "+442079460958",
+		},
+		{
+			name:        "dots and surrounding whitespace",
+			phoneNumber: "  +1.234.567.8900 ",
+			want:        "+12345678900",
+		},
+		{
+			name:        "empty",
+			phoneNumber: "",
+			want:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizePhoneNumber(tt.phoneNumber)
+
+			if got != tt.want {
+				t.Errorf("NormalizePhoneNumber(%q) = %q, want %q", tt.phoneNumber, got, tt.want)
+			}
+		})
+	}
+}
+
+type breachedPasswordsStub map[string]bool
+
+func (b breachedPasswordsStub) Contains(password string) bool {
+	return b[password]
+}
+
+func TestNotBreached(t *testing.T) {
+	validate := validator.New()
+	validate.RegisterValidation("not_breached", NotBreached(breachedPasswordsStub{"Password1!": true}))
+
+	type input struct {
+		Password *string `validate:"omitempty,not_breached"`
+	}
+
+	breachedPassword := "Password1!"
+	password := "CorrectHorse9?"
+
+	if err := validate.Struct(input{Password: &breachedPassword}); err == nil {
+		t.Error("NotBreached() accepted a breached password")
+	}
+	if err := validate.Struct(input{Password: &password}); err != nil {
+		t.Errorf("NotBreached() rejected a password which was not breached: %v", err)
+	}
+}
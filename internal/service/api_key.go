@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"time"
+)
+
+const (
+	apiKeyPrefix       = "crk_"
+	apiKeyLength       = 32
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+)
+
+// APIKeyService manages the API keys partners integrate with
+// and authenticates the requests made with them
+type APIKeyService struct {
+	log         *slog.Logger
+	validate    *validator.Validate
+	apiKeyRepo  APIKeyRepository
+	userService *UserService
+}
+
+func NewAPIKeyService(
+	log *slog.Logger,
+	validate *validator.Validate,
+	apiKeyRepo APIKeyRepository,
+	userService *UserService,
+) *APIKeyService {
+	return &APIKeyService{
+		log:         log,
+		validate:    validate,
+		apiKeyRepo:  apiKeyRepo,
+		userService: userService,
+	}
+}
+
+// Issue creates an API key bound to a user or an organization.
+// The key is only returned here, it can not be recovered later
+func (s *APIKeyService) Issue(ctx context.Context, data model.APIKeyCreateData) (model.IssuedAPIKey, error) {
+	err := validateInput(s.validate, data)
+	if err != nil {
+		return model.IssuedAPIKey{}, err
+	}
+
+	err = checkAPIKeyExpiry(data.ExpiresAt)
+	if err != nil {
+		return model.IssuedAPIKey{}, err
+	}
+
+	if data.UserID != nil {
+		_, err = s.userService.FindOne(ctx, model.UserFilter{ID: data.UserID})
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return model.IssuedAPIKey{}, model.ValidationErrors{
+					"userID": model.ErrNotFound,
+				}
+			}
+
+			return model.IssuedAPIKey{}, err
+		}
+	}
+
+	key := apiKeyPrefix + security.RandomString(apiKeyLength)
+	apiKey := model.APIKey{
+		Name:         data.Name,
+		Prefix:       key[:apiKeyPrefixLength],
+		KeyHash:      security.SHA256(key),
+		UserID:       data.UserID,
+		Organization: data.Organization,
+		Scopes:       data.Scopes,
+		ExpiresAt:    data.ExpiresAt,
+		CreatedAt:    time.Now(),
+	}
+
+	apiKey.ID, err = s.apiKeyRepo.Insert(ctx, apiKey)
+	if err != nil {
+		s.log.ErrorContext(ctx, "sql: inserting api key", logger.Err(err))
+
+		return model.IssuedAPIKey{}, err
+	}
+
+	s.log.InfoContext(ctx, "api key issued", slog.Uint64("apiKeyId", apiKey.ID), slog.String("prefix", apiKey.Prefix))
+
+	return model.IssuedAPIKey{
+		APIKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, filter model.APIKeyFilter) ([]model.APIKey, error) {
+	keys, err := s.apiKeyRepo.Find(ctx, filter)
+	if err != nil {
+		s.log.ErrorContext(ctx, "sql: finding api keys", logger.Err(err))
+
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Update changes the scopes or the expiry of a key
+func (s *APIKeyService) Update(ctx context.Context, id uint64, data model.APIKeyUpdateData) error {
+	err := validateInput(s.validate, data)
+	if err != nil {
+		return err
+	}
+
+	err = checkAPIKeyExpiry(data.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return s.apiKeyRepo.Update(ctx, id, model.APIKeyUpdate{
+		Scopes:    data.Scopes,
+		ExpiresAt: data.ExpiresAt,
+	})
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id uint64) error {
+	apiKey, err := s.apiKeyRepo.FindOne(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	err = s.apiKeyRepo.Update(ctx, id, model.APIKeyUpdate{RevokedAt: &now})
+	if err != nil {
+		s.log.ErrorContext(ctx, "sql: revoking api key", logger.Err(err), slog.Uint64("apiKeyId", id))
+
+		return err
+	}
+
+	s.log.InfoContext(ctx, "api key revoked", slog.Uint64("apiKeyId", id))
+
+	return nil
+}
+
+// Authenticate returns the claims a request made with the key is authorized with.
+// Keys of a user carry the current roles of the user and stop working once the user is deactivated
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (model.TokenClaims, error) {
+	apiKey, err := s.apiKeyRepo.FindByHash(ctx, security.SHA256(key))
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.TokenClaims{}, model.ErrInvalidAPIKey
+		}
+		s.log.ErrorContext(ctx, "sql: finding api key", logger.Err(err))
+
+		return model.TokenClaims{}, err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return model.TokenClaims{}, model.ErrRevokedToken
+	}
+	if apiKey.ExpiresAt != nil && !time.Now().Before(*apiKey.ExpiresAt) {
+		return model.TokenClaims{}, model.ErrExpiredAPIKey
+	}
+
+	if apiKey.UserID == nil {
+		return model.TokenClaims{
+			SubjectType:  model.SubjectTypeOrganization,
+			Organization: *apiKey.Organization,
+			Scopes:       apiKey.Scopes,
+		}, nil
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: apiKey.UserID})
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.TokenClaims{}, model.ErrInvalidAPIKey
+		}
+
+		return model.TokenClaims{}, err
+	}
+
+	if !user.IsActive {
+		return model.TokenClaims{}, model.ErrInvalidAPIKey
+	}
+
+	// A key must not outlive the password of its user, who has to log in and change it first
+	changeRequired, err := s.userService.passwordChangeRequired(ctx, user)
+	if err != nil {
+		return model.TokenClaims{}, err
+	}
+	if changeRequired {
+		return model.TokenClaims{}, model.ErrPasswordChangeRequired
+	}
+
+	return model.TokenClaims{
+		SubjectType: model.SubjectTypeUser,
+		UserID:      user.ID,
+		Roles:       toRoleStrings(user.Roles),
+		Scopes:      apiKey.Scopes,
+	}, nil
+}
+
+func checkAPIKeyExpiry(expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return model.ValidationErrors{
+			"expiresAt": model.ErrPastExpiry,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Insert(ctx context.Context, key model.APIKey) (uint64, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) FindOne(ctx context.Context, id uint64) (model.APIKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) FindByHash(ctx context.Context, keyHash []byte) (model.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	return args.Get(0).(model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Find(ctx context.Context, filter model.APIKeyFilter) ([]model.APIKey, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Update(ctx context.Context, id uint64, update model.APIKeyUpdate) error {
+	args := m.Called(ctx, id, update)
+	return args.Error(0)
+}
+
+func setupAPIKeyService() (*APIKeyService, *MockAPIKeyRepository, *MockUserRepository, *MockRoleRepository) {
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	validate := validator.New()
+	apiKeyRepo := new(MockAPIKeyRepository)
+	userRepo := new(MockUserRepository)
+	roleRepo := new(MockRoleRepository)
+
+	service := NewAPIKeyService(
+		log,
+		validate,
+		apiKeyRepo,
+		&UserService{
+			log:      log,
+			validate: validate,
+			userRepo: userRepo,
+			roleRepo: roleRepo,
+		},
+	)
+
+	return service, apiKeyRepo, userRepo, roleRepo
+}
+
+func TestAPIKeyService_Issue(t *testing.T) {
+	service, apiKeyRepo, userRepo, _ := setupAPIKeyService()
+	ctx := context.Background()
+	userID := uint64(7)
+
+	var stored model.APIKey
+	userRepo.On("FindOne", ctx, idFilter(7)).Return(model.User{ID: 7}, nil)
+	apiKeyRepo.On("Insert", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(model.APIKey)
+	}).Return(uint64(1), nil)
+
+	issued, err := service.Issue(ctx, model.APIKeyCreateData{
+		Name:   "franchise script",
+		UserID: &userID,
+		Scopes: []string{"/service.user.UserService/Get"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), issued.ID)
+	assert.True(t, strings.HasPrefix(issued.Key, stored.Prefix))
+	assert.Equal(t, security.SHA256(issued.Key), stored.KeyHash)
+}
+
+func TestAPIKeyService_Issue_UserAndOrganization(t *testing.T) {
+	service, apiKeyRepo, _, _ := setupAPIKeyService()
+	userID := uint64(7)
+	organization := "acme"
+
+	_, err := service.Issue(context.Background(), model.APIKeyCreateData{
+		Name:         "script",
+		UserID:       &userID,
+		Organization: &organization,
+		Scopes:       []string{"/service.user.UserService/Get"},
+	})
+
+	var ve model.ValidationErrors
+	require.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "organization")
+	apiKeyRepo.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+}
+
+func TestAPIKeyService_Issue_PastExpiry(t *testing.T) {
+	service, _, _, _ := setupAPIKeyService()
+	organization := "acme"
+	expiresAt := time.Now().Add(-time.Hour)
+
+	_, err := service.Issue(context.Background(), model.APIKeyCreateData{
+		Name:         "script",
+		Organization: &organization,
+		Scopes:       []string{"/service.user.UserService/Get"},
+		ExpiresAt:    &expiresAt,
+	})
+
+	assert.Equal(t, model.ValidationErrors{"expiresAt": model.ErrPastExpiry}, err)
+}
+
+func TestAPIKeyService_Authenticate_UserKey(t *testing.T) {
+	service, apiKeyRepo, userRepo, roleRepo := setupAPIKeyService()
+	ctx := context.Background()
+	userID := uint64(7)
+
+	apiKeyRepo.On("FindByHash", ctx, security.SHA256("crk_key")).Return(model.APIKey{
+		UserID: &userID,
+		Scopes: []string{"/service.user.UserService/Get"},
+	}, nil)
+	userRepo.On("FindOne", ctx, idFilter(7)).Return(model.User{
+		ID:       7,
+		Roles:    []model.Role{model.RoleAdmin},
+		IsActive: true,
+	}, nil)
+	roleRepo.On("PasswordMaxAge", ctx, []model.Role{model.RoleAdmin}).Return(0, nil)
+
+	claims, err := service.Authenticate(ctx, "crk_key")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.SubjectTypeUser, claims.SubjectType)
+	assert.Equal(t, uint64(7), claims.UserID)
+	assert.Equal(t, []string{model.RoleAdmin.String()}, claims.Roles)
+	assert.Equal(t, []string{"/service.user.UserService/Get"}, claims.Scopes)
+}
+
+func TestAPIKeyService_Authenticate_OrganizationKey(t *testing.T) {
+	service, apiKeyRepo, userRepo, _ := setupAPIKeyService()
+	ctx := context.Background()
+	organization := "acme"
+
+	apiKeyRepo.On("FindByHash", ctx, security.SHA256("crk_key")).Return(model.APIKey{
+		Organization: &organization,
+		Scopes:       []string{"/service.user.UserService/Get"},
+	}, nil)
+
+	claims, err := service.Authenticate(ctx, "crk_key")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.SubjectTypeOrganization, claims.SubjectType)
+	assert.Equal(t, "acme", claims.Organization)
+	userRepo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
+
+func TestAPIKeyService_Authenticate_Rejected(t *testing.T) {
+	organization := "acme"
+	past := time.Now().Add(-time.Minute)
+	userID := uint64(7)
+	passwordChangedAt := time.Now().AddDate(0, 0, -91)
+
+	tests := []struct {
+		name string
+		key  model.APIKey
+		user model.User
+		err  error
+	}{
+		{
+			name: "revoked",
+			key:  model.APIKey{Organization: &organization, RevokedAt: &past},
+			err:  model.ErrRevokedToken,
+		},
+		{
+			name: "expired",
+			key:  model.APIKey{Organization: &organization, ExpiresAt: &past},
+			err:  model.ErrExpiredAPIKey,
+		},
+		{
+			name: "inactive user",
+			key:  model.APIKey{UserID: &userID},
+			user: model.User{ID: 7},
+			err:  model.ErrInvalidAPIKey,
+		},
+		{
+			name: "user must change password",
+			key:  model.APIKey{UserID: &userID},
+			user: model.User{ID: 7, IsActive: true, MustChangePassword: true, PasswordChangedAt: time.Now()},
+			err:  model.ErrPasswordChangeRequired,
+		},
+		{
+			name: "expired user password",
+			key:  model.APIKey{UserID: &userID},
+			user: model.User{ID: 7, IsActive: true, PasswordChangedAt: passwordChangedAt},
+			err:  model.ErrPasswordChangeRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, apiKeyRepo, userRepo, roleRepo := setupAPIKeyService()
+			ctx := context.Background()
+
+			apiKeyRepo.On("FindByHash", ctx, mock.Anything).Return(tt.key, nil)
+			userRepo.On("FindOne", ctx, mock.Anything).Return(tt.user, nil)
+			roleRepo.On("PasswordMaxAge", ctx, mock.Anything).Return(90, nil)
+
+			_, err := service.Authenticate(ctx, "crk_key")
+
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestAPIKeyService_Authenticate_UnknownKey(t *testing.T) {
+	service, apiKeyRepo, _, _ := setupAPIKeyService()
+	ctx := context.Background()
+
+	apiKeyRepo.On("FindByHash", ctx, mock.Anything).Return(model.APIKey{}, model.ErrNotFound)
+
+	_, err := service.Authenticate(ctx, "crk_unknown")
+
+	assert.ErrorIs(t, err, model.ErrInvalidAPIKey)
+}
@@ -2,20 +2,40 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/devicealert"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/passwordless"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	validatecfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/validate"
 	"log/slog"
 	"time"
 )
 
+const (
+	sessionIDLength = 16
+	tokenIDLength   = 16
+)
+
 type AuthService struct {
-	log            *slog.Logger
-	validate       *validator.Validate
-	jwtProvider    JwtProvider
-	userService    *UserService
-	sessionStorage SessionStorage
+	log                    *slog.Logger
+	validate               *validator.Validate
+	jwtProvider            JwtProvider
+	userService            *UserService
+	mfaService             *MFAService
+	loginHistoryService    *LoginHistoryService
+	sessionStorage         SessionStorage
+	tokenRevocationStorage TokenRevocationStorage
+	loginAttemptStorage    LoginAttemptStorage
+	passwordResetStorage   PasswordResetStorage
+	loginCodeStorage       LoginCodeStorage
+	auditRepo              AuditRepository
+	knownDeviceRepo        KnownDeviceRepository
+	mailer                 Mailer
+	passwordless           passwordless.Config
+	deviceAlert            devicealert.Config
 }
 
 func NewAuthService(
@@ -23,18 +43,42 @@ func NewAuthService(
 	validate *validator.Validate,
 	jwtProvider JwtProvider,
 	userService *UserService,
+	mfaService *MFAService,
+	loginHistoryService *LoginHistoryService,
 	sessionStorage SessionStorage,
+	tokenRevocationStorage TokenRevocationStorage,
+	loginAttemptStorage LoginAttemptStorage,
+	passwordResetStorage PasswordResetStorage,
+	loginCodeStorage LoginCodeStorage,
+	auditRepo AuditRepository,
+	knownDeviceRepo KnownDeviceRepository,
+	mailer Mailer,
+	passwordless passwordless.Config,
+	deviceAlert devicealert.Config,
 ) *AuthService {
 	return &AuthService{
-		log:            log,
-		validate:       validate,
-		jwtProvider:    jwtProvider,
-		userService:    userService,
-		sessionStorage: sessionStorage,
+		log:                    log,
+		validate:               validate,
+		jwtProvider:            jwtProvider,
+		userService:            userService,
+		mfaService:             mfaService,
+		loginHistoryService:    loginHistoryService,
+		sessionStorage:         sessionStorage,
+		tokenRevocationStorage: tokenRevocationStorage,
+		loginAttemptStorage:    loginAttemptStorage,
+		passwordResetStorage:   passwordResetStorage,
+		loginCodeStorage:       loginCodeStorage,
+		auditRepo:              auditRepo,
+		knownDeviceRepo:        knownDeviceRepo,
+		mailer:                 mailer,
+		passwordless:           passwordless,
+		deviceAlert:            deviceAlert,
 	}
 }
 
 func (s *AuthService) Register(ctx context.Context, data model.UserCreateData) (uint64, error) {
+	data.PhoneNumber = validatecfg.NormalizePhoneNumber(data.PhoneNumber)
+
 	err := validateInput(s.validate, data)
 	if err != nil {
 		return 0, err
@@ -48,67 +92,221 @@ func (s *AuthService) Register(ctx context.Context, data model.UserCreateData) (
 	return createdID, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, cred model.Credentials) (model.Token, error) {
-	err := validateInput(s.validate, cred)
+func (s *AuthService) Login(ctx context.Context, cred model.Credentials) (result model.LoginResult, err error) {
+	event := newLoginEvent(ctx, model.LoginMethodPassword)
+	defer func() { s.recordLogin(ctx, event, result, err) }()
+
+	cred.PhoneNumber = validatecfg.NormalizePhoneNumber(cred.PhoneNumber)
+
+	err = validateInput(s.validate, cred)
 	if err != nil {
-		return model.Token{}, err
+		return model.LoginResult{}, err
 	}
-	filter := model.UserFilter{
-		Email: &cred.Email,
+
+	var filter model.UserFilter
+	if cred.Email != "" {
+		filter.Email = &cred.Email
+	} else {
+		filter.PhoneNumber = &cred.PhoneNumber
+	}
+
+	clientSubjects := clientLoginSubjects(ctx)
+
+	err = s.checkLoginLockout(ctx, clientSubjects...)
+	if err != nil {
+		return model.LoginResult{}, err
 	}
 
 	user, err := s.userService.FindOne(ctx, filter)
 	if err != nil {
-		return model.Token{}, err
+		if errors.Is(err, model.ErrNotFound) {
+			failureErr := s.addLoginFailure(ctx, clientSubjects...)
+			if failureErr != nil {
+				return model.LoginResult{}, failureErr
+			}
+		}
+
+		return model.LoginResult{}, err
 	}
+	event.UserID = &user.ID
 
-	err = security.CheckStringHash(cred.Password, user.PasswordHash)
+	accountSubject := accountLoginSubject(user.ID)
+
+	err = s.checkLoginLockout(ctx, accountSubject)
 	if err != nil {
-		return model.Token{}, model.ValidationErrors{
+		return model.LoginResult{}, err
+	}
+
+	err = s.userService.checkPassword(user, cred.Password)
+	if err != nil {
+		err = s.addLoginFailure(ctx, append(clientSubjects, accountSubject)...)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+
+		return model.LoginResult{}, model.ValidationErrors{
 			"password": model.ErrPasswordsDoNotMatch,
 		}
 	}
 
-	userRoles := toRoleStrings(user.Roles)
-	accessToken, accessTokenExp, err := s.jwtProvider.GenerateAccessToken(user.ID, userRoles)
+	s.userService.upgradePasswordHash(ctx, user, cred.Password)
+
+	return s.continueLogin(ctx, user)
+}
+
+// continueLogin asks for the second factor of a login whose first factor is verified,
+// or completes the login if the user needs none
+func (s *AuthService) continueLogin(ctx context.Context, user model.User) (model.LoginResult, error) {
+	claims := model.TokenClaims{
+		UserID:    user.ID,
+		Roles:     toRoleStrings(user.Roles),
+		SessionID: security.RandomString(sessionIDLength),
+		AuthTime:  time.Now(),
+	}
+
+	mfaEnabled, mfaRequired, err := s.mfaService.status(ctx, user)
 	if err != nil {
-		s.log.Error(
-			"jwt: generating access token",
+		s.log.ErrorContext(
+			ctx,
+			"mfa: checking status",
 			logger.Err(err),
 			slog.Uint64("userId", user.ID),
 		)
 
-		return model.Token{}, model.ErrJwt
+		return model.LoginResult{}, err
+	}
+
+	// The session is started and the failed attempts are reset
+	// by VerifyMFA once the second factor is provided
+	if mfaRequired {
+		challenge, err := s.generateMFAChallenge(ctx, claims, !mfaEnabled)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+
+		return model.LoginResult{MFAChallenge: &challenge}, nil
 	}
 
-	refreshToken, refreshTokenExp, err := s.jwtProvider.GenerateRefreshToken(user.ID, userRoles)
+	err = s.loginAttemptStorage.Reset(ctx, accountLoginSubject(user.ID).key)
 	if err != nil {
-		s.log.Error(
-			"jwt: generating refresh token",
+		s.log.ErrorContext(
+			ctx,
+			"login attempt storage: resetting account failures",
 			logger.Err(err),
 			slog.Uint64("userId", user.ID),
 		)
 
-		return model.Token{}, model.ErrJwt
+		return model.LoginResult{}, err
 	}
 
-	err = s.sessionStorage.Save(ctx, user.ID)
+	return s.completeLogin(ctx, user, claims)
+}
+
+// VerifyMFA completes a login with the MFA token returned by Login and
+// a TOTP or recovery code. Every MFA token completes a single login
+func (s *AuthService) VerifyMFA(ctx context.Context, mfaToken, code string) (result model.LoginResult, err error) {
+	event := newLoginEvent(ctx, model.LoginMethodMFA)
+	defer func() { s.recordLogin(ctx, event, result, err) }()
+
+	err = validateInput(s.validate, mfaTokenValidation{MfaToken: mfaToken})
 	if err != nil {
-		s.log.Error(
-			"token storage: saving session",
+		return model.LoginResult{}, err
+	}
+
+	claims, err := s.jwtProvider.VerifyAndParseClaims(mfaToken)
+	if err != nil || claims.Type != model.TokenTypeMFA {
+		return model.LoginResult{}, model.ErrInvalidToken
+	}
+	event.UserID = &claims.UserID
+
+	revoked, err := s.tokenRevocationStorage.IsTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: checking mfa token",
 			logger.Err(err),
-			slog.Uint64("userId", user.ID),
+			slog.Uint64("userId", claims.UserID),
 		)
 
-		return model.Token{}, err
+		return model.LoginResult{}, err
+	}
+	if revoked {
+		return model.LoginResult{}, model.ErrInvalidToken
 	}
 
-	return model.Token{
-		AccessToken:           accessToken,
-		AccessTokenExpiresIn:  int64(time.Until(accessTokenExp).Seconds()),
-		RefreshToken:          refreshToken,
-		RefreshTokenExpiresIn: int64(time.Until(refreshTokenExp).Seconds()),
-	}, nil
+	validAfter, err := s.tokenRevocationStorage.UserTokensValidAfter(ctx, claims.UserID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: getting user tokens revocation time",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.LoginResult{}, err
+	}
+	if claims.IssuedAt.Before(validAfter) {
+		return model.LoginResult{}, model.ErrInvalidToken
+	}
+
+	accountSubject := accountLoginSubject(claims.UserID)
+
+	err = s.checkLoginLockout(ctx, accountSubject)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	err = s.mfaService.verify(ctx, claims.UserID, code)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidMFACode) {
+			failureErr := s.addLoginFailure(ctx, append(clientLoginSubjects(ctx), accountSubject)...)
+			if failureErr != nil {
+				return model.LoginResult{}, failureErr
+			}
+
+			return model.LoginResult{}, model.ValidationErrors{
+				"code": model.ErrInvalidMFACode,
+			}
+		}
+
+		return model.LoginResult{}, err
+	}
+
+	err = s.tokenRevocationStorage.RevokeToken(ctx, claims.TokenID, claims.ExpiresAt)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: revoking mfa token",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.LoginResult{}, err
+	}
+
+	err = s.loginAttemptStorage.Reset(ctx, accountSubject.key)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"login attempt storage: resetting account failures",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.LoginResult{}, err
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: &claims.UserID})
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	return s.completeLogin(ctx, user, model.TokenClaims{
+		UserID:    claims.UserID,
+		Roles:     claims.Roles,
+		SessionID: claims.SessionID,
+		AuthTime:  time.Now(),
+	})
 }
 
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (model.Token, error) {
@@ -120,9 +318,10 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (mo
 		return model.Token{}, err
 	}
 
-	id, roles, err := s.jwtProvider.VerifyAndParseClaims(refreshToken)
+	claims, err := s.jwtProvider.VerifyAndParseClaims(refreshToken)
 	if err != nil {
-		s.log.Error(
+		s.log.ErrorContext(
+			ctx,
 			"jwt: verifying refresh token",
 			logger.Err(err),
 			slog.String("refreshToken", refreshToken),
@@ -131,83 +330,201 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (mo
 		return model.Token{}, model.ErrInvalidToken
 	}
 
-	exists, err := s.sessionStorage.Exists(ctx, id)
-	if !exists {
-		s.log.Error(
-			"token storage: checking session",
+	if claims.Type != model.TokenTypeRefresh {
+		return model.Token{}, model.ErrInvalidToken
+	}
+
+	validAfter, err := s.tokenRevocationStorage.UserTokensValidAfter(ctx, claims.UserID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: getting user tokens revocation time",
 			logger.Err(err),
-			slog.Uint64("userID", id),
+			slog.Uint64("userId", claims.UserID),
 		)
 
+		return model.Token{}, err
+	}
+	if claims.IssuedAt.Before(validAfter) {
 		return model.Token{}, model.ErrInvalidToken
 	}
 
-	newAccessToken, newAccessTokenExp, err := s.jwtProvider.GenerateAccessToken(id, roles)
+	newClaims := model.TokenClaims{
+		UserID:    claims.UserID,
+		Roles:     claims.Roles,
+		SessionID: claims.SessionID,
+		AuthTime:  claims.AuthTime,
+	}
+
+	token, newRefreshTokenID, err := s.generateToken(ctx, newClaims)
 	if err != nil {
-		s.log.Error(
-			"jwt: generating access token",
+		return model.Token{}, err
+	}
+
+	// Every refresh token can be used once. Presenting an already rotated one
+	// means it was leaked, so the whole session it belongs to is revoked
+	err = s.sessionStorage.Rotate(ctx, claims.UserID, claims.SessionID, claims.TokenID, newRefreshTokenID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRefreshTokenReused):
+			s.log.WarnContext(
+				ctx,
+				"token storage: refresh token reuse detected, revoking session",
+				slog.Uint64("userId", claims.UserID),
+				slog.String("sessionId", claims.SessionID),
+			)
+
+			err = s.sessionStorage.Delete(ctx, claims.UserID, claims.SessionID)
+			if err != nil {
+				s.log.ErrorContext(
+					ctx,
+					"token storage: deleting session",
+					logger.Err(err),
+					slog.Uint64("userId", claims.UserID),
+					slog.String("sessionId", claims.SessionID),
+				)
+			}
+
+			// The access tokens issued for the session may be in the hands of whoever replayed it
+			err = s.tokenRevocationStorage.RevokeSession(ctx, claims.SessionID)
+			if err != nil {
+				s.log.ErrorContext(
+					ctx,
+					"token revocation storage: revoking session",
+					logger.Err(err),
+					slog.Uint64("userId", claims.UserID),
+					slog.String("sessionId", claims.SessionID),
+				)
+			}
+
+			return model.Token{}, model.ErrInvalidToken
+		case errors.Is(err, model.ErrNotFound):
+			return model.Token{}, model.ErrInvalidToken
+		default:
+			s.log.ErrorContext(
+				ctx,
+				"token storage: rotating refresh token",
+				logger.Err(err),
+				slog.Uint64("userId", claims.UserID),
+				slog.String("sessionId", claims.SessionID),
+			)
+
+			return model.Token{}, err
+		}
+	}
+
+	return token, nil
+}
+
+// Logout ends the session of the refresh token. The access token of the same session
+// is revoked right away if the caller provides it
+func (s *AuthService) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	input := refreshTokenValidation{
+		RefreshToken: refreshToken,
+	}
+	err := validateInput(s.validate, input)
+	if err != nil {
+		return err
+	}
+
+	claims, err := s.jwtProvider.VerifyAndParseClaims(refreshToken)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: verifying refresh token",
 			logger.Err(err),
-			slog.Uint64("userId", id),
+			slog.String("refreshToken", refreshToken),
 		)
 
-		return model.Token{}, model.ErrJwt
+		return model.ErrInvalidToken
+	}
+
+	if claims.Type != model.TokenTypeRefresh {
+		return model.ErrInvalidToken
 	}
-	newRefreshToken, newRefreshTokenExp, err := s.jwtProvider.GenerateRefreshToken(id, roles)
+
+	err = s.sessionStorage.Delete(ctx, claims.UserID, claims.SessionID)
 	if err != nil {
-		s.log.Error(
-			"jwt: generating refresh token",
+		s.log.ErrorContext(
+			ctx,
+			"token storage: deleting session",
 			logger.Err(err),
-			slog.Uint64("userId", id),
+			slog.Uint64("userId", claims.UserID),
+			slog.String("sessionId", claims.SessionID),
 		)
 
-		return model.Token{}, model.ErrJwt
+		return err
+	}
+
+	if accessToken == "" {
+		return nil
+	}
+
+	accessClaims, err := s.jwtProvider.VerifyAndParseClaims(accessToken)
+	if err != nil ||
+		accessClaims.Type != model.TokenTypeAccess ||
+		accessClaims.UserID != claims.UserID ||
+		accessClaims.SessionID != claims.SessionID {
+		return nil
 	}
 
-	err = s.sessionStorage.Save(ctx, id)
+	err = s.tokenRevocationStorage.RevokeToken(ctx, accessClaims.TokenID, accessClaims.ExpiresAt)
 	if err != nil {
-		s.log.Error(
-			"token storage: saving new session",
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: revoking access token",
 			logger.Err(err),
-			slog.Uint64("userId", id),
+			slog.Uint64("userId", claims.UserID),
+			slog.String("sessionId", claims.SessionID),
 		)
 
-		return model.Token{}, err
+		return err
 	}
 
-	return model.Token{
-		AccessToken:           newAccessToken,
-		AccessTokenExpiresIn:  int64(time.Until(newAccessTokenExp).Seconds()),
-		RefreshToken:          newRefreshToken,
-		RefreshTokenExpiresIn: int64(time.Until(newRefreshTokenExp).Seconds()),
-	}, nil
+	return nil
 }
 
-func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
-	input := refreshTokenValidation{
-		RefreshToken: refreshToken,
+// RequestPasswordReset emails a password reset token to the user. It succeeds
+// for unknown emails as well, so that it can not be used to find registered users
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	err := validateInput(s.validate, passwordResetRequestValidation{Email: email})
+	if err != nil {
+		return err
 	}
-	err := validateInput(s.validate, input)
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{Email: &email})
 	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+
 		return err
 	}
 
-	id, _, err := s.jwtProvider.VerifyAndParseClaims(refreshToken)
+	return s.sendPasswordReset(ctx, user)
+}
+
+// sendPasswordReset emails the user a token to reset the password with
+func (s *AuthService) sendPasswordReset(ctx context.Context, user model.User) error {
+	token, err := s.passwordResetStorage.Save(ctx, user.ID)
 	if err != nil {
-		s.log.Error(
-			"jwt: verifying refresh token",
+		s.log.ErrorContext(
+			ctx,
+			"password reset storage: saving token",
 			logger.Err(err),
-			slog.String("refreshToken", refreshToken),
+			slog.Uint64("userId", user.ID),
 		)
 
-		return model.ErrInvalidToken
+		return err
 	}
 
-	err = s.sessionStorage.Delete(ctx, id)
+	err = s.mailer.SendPasswordResetToken(ctx, user.Email, token)
 	if err != nil {
-		s.log.Error(
-			"token storage: deleting session",
+		s.log.ErrorContext(
+			ctx,
+			"mailer: sending password reset token",
 			logger.Err(err),
-			slog.Uint64("userId", id),
+			slog.Uint64("userId", user.ID),
 		)
 
 		return err
@@ -215,3 +532,326 @@ func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
 
 	return nil
 }
+
+// ResetPassword sets a new password with a token sent by RequestPasswordReset
+// and ends all sessions of the user
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	err := validateInput(s.validate, passwordResetValidation{
+		Token:    token,
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.passwordResetStorage.Find(ctx, token)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrInvalidResetToken
+		}
+
+		s.log.ErrorContext(ctx, "password reset storage: finding token", logger.Err(err))
+
+		return err
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: &userID})
+	if err != nil {
+		return err
+	}
+
+	// A reused password is rejected before the token is used up, so another one can be tried
+	err = s.userService.checkPasswordHistory(ctx, user, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.passwordResetStorage.Consume(ctx, token)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrInvalidResetToken
+		}
+
+		s.log.ErrorContext(ctx, "password reset storage: consuming token", logger.Err(err))
+
+		return err
+	}
+
+	err = s.userService.setPassword(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+
+	err = s.sessionStorage.DeleteAll(ctx, userID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token storage: deleting all sessions",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	// Proving access to the email is enough to lift a lockout of the account
+	err = s.loginAttemptStorage.Reset(ctx, accountLoginSubject(userID).key)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"login attempt storage: resetting account failures",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// ChangePassword replaces the password of the authenticated user after checking the current one.
+// All other sessions are ended and new tokens are issued for the current session
+func (s *AuthService) ChangePassword(ctx context.Context, data model.PasswordChangeData) (model.Token, error) {
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return model.Token{}, err
+	}
+	sessionID, err := sessionIDFromCtx(ctx)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	err = validateInput(s.validate, data)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: &id})
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	accountSubject := accountLoginSubject(user.ID)
+
+	err = s.checkLoginLockout(ctx, accountSubject)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	err = s.userService.checkPassword(user, data.CurrentPassword)
+	if err != nil {
+		err = s.addLoginFailure(ctx, accountSubject)
+		if err != nil {
+			return model.Token{}, err
+		}
+
+		return model.Token{}, model.ValidationErrors{
+			"currentPassword": model.ErrPasswordsDoNotMatch,
+		}
+	}
+
+	err = s.userService.checkPasswordHistory(ctx, user, data.Password)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	err = s.userService.setPassword(ctx, user.ID, data.Password)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	err = s.sessionStorage.DeleteAll(ctx, user.ID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token storage: deleting all sessions",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return model.Token{}, err
+	}
+
+	token, err := s.startSession(ctx, model.TokenClaims{
+		UserID:    user.ID,
+		Roles:     toRoleStrings(user.Roles),
+		SessionID: sessionID,
+		AuthTime:  time.Now(),
+	})
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	// The password is already changed, a failed notification is not reported to the caller
+	err = s.mailer.SendPasswordChangedNotification(ctx, user.Email)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"mailer: sending password changed notification",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+	}
+
+	return token, nil
+}
+
+// JWKS returns the public keys other services can verify issued tokens with
+func (s *AuthService) JWKS(_ context.Context) []model.JSONWebKey {
+	return s.jwtProvider.JWKS()
+}
+
+// recordLogin saves the login attempt of the event with the outcome of the login
+func (s *AuthService) recordLogin(ctx context.Context, event model.LoginEvent, result model.LoginResult, err error) {
+	event.Outcome = loginOutcome(result, err)
+
+	s.loginHistoryService.record(ctx, event)
+}
+
+// startSession issues the tokens of a new session and saves the session
+// along with the device it is started on
+func (s *AuthService) startSession(ctx context.Context, claims model.TokenClaims) (model.Token, error) {
+	token, refreshTokenID, err := s.generateToken(ctx, claims)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	now := time.Now()
+	session := model.Session{
+		ID:         claims.SessionID,
+		UserID:     claims.UserID,
+		DeviceName: deviceNameFromCtx(ctx),
+		UserAgent:  userAgentFromCtx(ctx),
+		ClientIP:   clientIPFromCtx(ctx),
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+
+	err = s.sessionStorage.Save(ctx, session, refreshTokenID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token storage: saving session",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.Token{}, err
+	}
+
+	return token, nil
+}
+
+// generateMFAChallenge issues the MFA token a login is completed with
+func (s *AuthService) generateMFAChallenge(ctx context.Context, claims model.TokenClaims, enrollmentRequired bool) (model.MFAChallenge, error) {
+	claims.TokenID = security.RandomString(tokenIDLength)
+
+	mfaToken, mfaTokenExp, err := s.jwtProvider.GenerateMFAToken(claims)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating mfa token",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.MFAChallenge{}, model.ErrJwt
+	}
+
+	return model.MFAChallenge{
+		Token:              mfaToken,
+		ExpiresIn:          int64(time.Until(mfaTokenExp).Seconds()),
+		EnrollmentRequired: enrollmentRequired,
+	}, nil
+}
+
+// completeLogin starts the session of a login once every factor is verified.
+// If the password of the user expired or has to be changed, only a password change token
+// is issued instead, and the session starts once the password is changed
+func (s *AuthService) completeLogin(ctx context.Context, user model.User, claims model.TokenClaims) (model.LoginResult, error) {
+	changeRequired, err := s.userService.passwordChangeRequired(ctx, user)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	if changeRequired {
+		challenge, err := s.generatePasswordChangeChallenge(ctx, claims)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+
+		return model.LoginResult{PasswordChangeChallenge: &challenge}, nil
+	}
+
+	token, err := s.startSession(ctx, claims)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	s.recordLoginDevice(ctx, user, claims.SessionID)
+
+	return model.LoginResult{Token: token}, nil
+}
+
+func (s *AuthService) generatePasswordChangeChallenge(ctx context.Context, claims model.TokenClaims) (model.PasswordChangeChallenge, error) {
+	claims.TokenID = security.RandomString(tokenIDLength)
+
+	passwordChangeToken, passwordChangeTokenExp, err := s.jwtProvider.GeneratePasswordChangeToken(claims)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating password change token",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.PasswordChangeChallenge{}, model.ErrJwt
+	}
+
+	return model.PasswordChangeChallenge{
+		Token:     passwordChangeToken,
+		ExpiresIn: int64(time.Until(passwordChangeTokenExp).Seconds()),
+	}, nil
+}
+
+// generateToken issues an access and refresh token pair for the claims
+// and returns it together with the ID of the refresh token
+func (s *AuthService) generateToken(ctx context.Context, claims model.TokenClaims) (model.Token, string, error) {
+	accessClaims := claims
+	accessClaims.TokenID = security.RandomString(tokenIDLength)
+
+	accessToken, accessTokenExp, err := s.jwtProvider.GenerateAccessToken(accessClaims)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating access token",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.Token{}, "", model.ErrJwt
+	}
+
+	refreshClaims := claims
+	refreshClaims.TokenID = security.RandomString(tokenIDLength)
+
+	refreshToken, refreshTokenExp, err := s.jwtProvider.GenerateRefreshToken(refreshClaims)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating refresh token",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return model.Token{}, "", model.ErrJwt
+	}
+
+	return model.Token{
+		AccessToken:           accessToken,
+		AccessTokenExpiresIn:  int64(time.Until(accessTokenExp).Seconds()),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresIn: int64(time.Until(refreshTokenExp).Seconds()),
+	}, refreshClaims.TokenID, nil
+}
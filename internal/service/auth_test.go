@@ -5,9 +5,16 @@ import (
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/breached"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/devicealert"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/loginhistory"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/password"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/passwordless"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
 	validatecfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/validate"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 	"log/slog"
 	"os"
 	"testing"
@@ -52,44 +59,556 @@ type MockJWTProvider struct {
 	mock.Mock
 }
 
-func (m *MockJWTProvider) GenerateAccessToken(userID uint64, roles []string) (string, error) {
-	args := m.Called(userID, roles)
+func (m *MockJWTProvider) GenerateAccessToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(15 * time.Minute), args.Error(1)
+}
+
+func (m *MockJWTProvider) GenerateRefreshToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(24 * time.Hour), args.Error(1)
+}
+
+func (m *MockJWTProvider) GenerateMFAToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(5 * time.Minute), args.Error(1)
+}
+
+func (m *MockJWTProvider) GenerateImpersonationToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(10 * time.Minute), args.Error(1)
+}
+
+func (m *MockJWTProvider) GeneratePasswordChangeToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(10 * time.Minute), args.Error(1)
+}
+
+func (m *MockJWTProvider) GenerateLoginLinkToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(10 * time.Minute), args.Error(1)
+}
+
+func (m *MockJWTProvider) GenerateElevatedToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(5 * time.Minute), args.Error(1)
+}
+
+func (m *MockJWTProvider) GenerateLoginAlertToken(claims model.TokenClaims) (string, time.Time, error) {
+	args := m.Called(claims)
+	return args.String(0), time.Now().Add(168 * time.Hour), args.Error(1)
+}
+
+func (m *MockJWTProvider) VerifyAndParseClaims(token string) (model.TokenClaims, error) {
+	args := m.Called(token)
+	return args.Get(0).(model.TokenClaims), args.Error(1)
+}
+
+func (m *MockJWTProvider) JWKS() []model.JSONWebKey {
+	args := m.Called()
+	return args.Get(0).([]model.JSONWebKey)
+}
+
+type MockSessionStorage struct {
+	mock.Mock
+}
+
+func (m *MockSessionStorage) Save(ctx context.Context, session model.Session, refreshTokenID string) error {
+	args := m.Called(ctx, session, refreshTokenID)
+	return args.Error(0)
+}
+
+func (m *MockSessionStorage) Rotate(ctx context.Context, userID uint64, sessionID, refreshTokenID, newRefreshTokenID string) error {
+	args := m.Called(ctx, userID, sessionID, refreshTokenID, newRefreshTokenID)
+	return args.Error(0)
+}
+
+func (m *MockSessionStorage) Exists(ctx context.Context, userID uint64, sessionID string) (bool, error) {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionStorage) Find(ctx context.Context, userID uint64) ([]model.Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]model.Session), args.Error(1)
+}
+
+func (m *MockSessionStorage) DeleteAll(ctx context.Context, userID uint64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockSessionStorage) Delete(ctx context.Context, userID uint64, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+type MockTokenRevocationStorage struct {
+	mock.Mock
+}
+
+func (m *MockTokenRevocationStorage) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	args := m.Called(ctx, tokenID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationStorage) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	args := m.Called(ctx, tokenID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRevocationStorage) RevokeSession(ctx context.Context, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationStorage) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRevocationStorage) RevokeUserTokens(ctx context.Context, userID uint64, revokedAt time.Time) error {
+	args := m.Called(ctx, userID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationStorage) UserTokensValidAfter(ctx context.Context, userID uint64) (time.Time, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockTokenRevocationStorage) RevokeClientTokens(ctx context.Context, clientID string, revokedAt time.Time) error {
+	args := m.Called(ctx, clientID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationStorage) ClientTokensValidAfter(ctx context.Context, clientID string) (time.Time, error) {
+	args := m.Called(ctx, clientID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+type MockLoginAttemptStorage struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptStorage) AddFailure(ctx context.Context, subject string) (int64, error) {
+	args := m.Called(ctx, subject)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLoginAttemptStorage) Lock(ctx context.Context, subject string, duration time.Duration) error {
+	args := m.Called(ctx, subject, duration)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptStorage) LockedFor(ctx context.Context, subject string) (time.Duration, error) {
+	args := m.Called(ctx, subject)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockLoginAttemptStorage) Reset(ctx context.Context, subject string) error {
+	args := m.Called(ctx, subject)
+	return args.Error(0)
+}
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) FindTOTP(ctx context.Context, userID uint64) (model.TOTP, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.TOTP), args.Error(1)
+}
+
+func (m *MockMFARepository) SaveTOTP(ctx context.Context, totp model.TOTP) error {
+	args := m.Called(ctx, totp)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ConfirmTOTP(ctx context.Context, userID uint64, recoveryCodeHashes [][]byte, confirmedAt time.Time) error {
+	args := m.Called(ctx, userID, recoveryCodeHashes, confirmedAt)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseTOTPStep(ctx context.Context, userID uint64, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID uint64, codeHash []byte) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) IsMFARequired(ctx context.Context, roles []model.Role) (bool, error) {
+	args := m.Called(ctx, roles)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoleRepository) SetMFARequired(ctx context.Context, role model.Role, required bool) error {
+	args := m.Called(ctx, role, required)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) PasswordMaxAge(ctx context.Context, roles []model.Role) (int, error) {
+	args := m.Called(ctx, roles)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRoleRepository) SetPasswordMaxAge(ctx context.Context, role model.Role, days int) error {
+	args := m.Called(ctx, role, days)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) PasswordHistorySize(ctx context.Context, roles []model.Role) (int, error) {
+	args := m.Called(ctx, roles)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRoleRepository) SetPasswordHistorySize(ctx context.Context, role model.Role, size int) error {
+	args := m.Called(ctx, role, size)
+	return args.Error(0)
+}
+
+type MockPasswordHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordHistoryRepository) Insert(
+	ctx context.Context,
+	userID uint64,
+	passwordHash []byte,
+	createdAt time.Time,
+) error {
+	args := m.Called(ctx, userID, passwordHash, createdAt)
+	return args.Error(0)
+}
+
+func (m *MockPasswordHistoryRepository) FindRecent(ctx context.Context, userID uint64, limit int) ([][]byte, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([][]byte), args.Error(1)
+}
+
+type MockKnownDeviceRepository struct {
+	mock.Mock
+}
+
+func (m *MockKnownDeviceRepository) Insert(ctx context.Context, device model.KnownDevice) (uint64, error) {
+	args := m.Called(ctx, device)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockKnownDeviceRepository) Find(ctx context.Context, userID uint64) ([]model.KnownDevice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.KnownDevice), args.Error(1)
+}
+
+func (m *MockKnownDeviceRepository) UpdateLastSeen(ctx context.Context, id uint64, ipRange string, lastSeenAt time.Time) error {
+	args := m.Called(ctx, id, ipRange, lastSeenAt)
+	return args.Error(0)
+}
+
+func (m *MockKnownDeviceRepository) Delete(ctx context.Context, userID, id uint64) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+type MockLoginEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginEventRepository) Insert(ctx context.Context, event model.LoginEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockLoginEventRepository) Find(ctx context.Context, userID, beforeID uint64, limit int) ([]model.LoginEvent, error) {
+	args := m.Called(ctx, userID, beforeID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.LoginEvent), args.Error(1)
+}
+
+func (m *MockLoginEventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockPasswordResetStorage struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetStorage) Save(ctx context.Context, userID uint64) (string, error) {
+	args := m.Called(ctx, userID)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockJWTProvider) GenerateRefreshToken(userID uint64, roles []string) (string, error) {
-	args := m.Called(userID, roles)
+func (m *MockPasswordResetStorage) Find(ctx context.Context, token string) (uint64, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockPasswordResetStorage) Consume(ctx context.Context, token string) (uint64, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+type MockLoginCodeStorage struct {
+	mock.Mock
+}
+
+func (m *MockLoginCodeStorage) Save(ctx context.Context, userID uint64, linkTokenID string) (string, error) {
+	args := m.Called(ctx, userID, linkTokenID)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockJWTProvider) VerifyAndParseClaims(token string) (uint64, []string, error) {
-	args := m.Called(token)
-	return args.Get(0).(uint64), args.Get(1).([]string), args.Error(2)
+func (m *MockLoginCodeStorage) Get(ctx context.Context, userID uint64) (model.PendingLoginCode, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.PendingLoginCode), args.Error(1)
+}
+
+func (m *MockLoginCodeStorage) AddFailure(ctx context.Context, userID uint64) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func setupAuthService() (*AuthService, *MockUserRepository, *MockJWTProvider) {
+func (m *MockLoginCodeStorage) Consume(ctx context.Context, userID uint64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) SendActivationCode(ctx context.Context, receiver, code string) error {
+	args := m.Called(ctx, receiver, code)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendPasswordChangedNotification(ctx context.Context, receiver string) error {
+	args := m.Called(ctx, receiver)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendNewDeviceAlert(ctx context.Context, receiver string, device model.KnownDevice, reportLink string) error {
+	args := m.Called(ctx, receiver, device, reportLink)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendEmailChangeCode(ctx context.Context, receiver, code string) error {
+	args := m.Called(ctx, receiver, code)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendEmailChangeNotice(ctx context.Context, receiver, newEmail, cancelToken string) error {
+	args := m.Called(ctx, receiver, newEmail, cancelToken)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendPasswordResetToken(ctx context.Context, receiver, token string) error {
+	args := m.Called(ctx, receiver, token)
+	return args.Error(0)
+}
+
+func (m *MockMailer) SendLoginCode(ctx context.Context, receiver, code, link string) error {
+	args := m.Called(ctx, receiver, code, link)
+	return args.Error(0)
+}
+
+// testSecretEncryptionKey encrypts TOTP secrets in tests
+var testSecretEncryptionKey = make([]byte, 32)
+
+// testBreachedPasswords has the only password tests treat as breached
+var testBreachedPasswords = newTestBreachedPasswords()
+
+func newTestBreachedPasswords() *breached.Filter {
+	filter, _ := breached.NewFilter(1, 0.001)
+	filter.Add("Password1!")
+
+	return filter
+}
+
+// testPasswordHasher hashes with bcrypt at the cost of the password hash fixtures,
+// so that logging in with them does not upgrade their hash
+var testPasswordHasher, _ = password.NewHasher(password.Config{
+	Algorithm:  password.AlgorithmBcrypt,
+	BcryptCost: bcrypt.DefaultCost,
+})
+
+type authServiceMocks struct {
+	repo            *MockUserRepository
+	jwt             *MockJWTProvider
+	sessions        *MockSessionStorage
+	revocations     *MockTokenRevocationStorage
+	loginAttempts   *MockLoginAttemptStorage
+	mfa             *MockMFARepository
+	roles           *MockRoleRepository
+	passwordHistory *MockPasswordHistoryRepository
+	passwordReset   *MockPasswordResetStorage
+	loginCodes      *MockLoginCodeStorage
+	emailChange     *MockEmailChangeStorage
+	phoneCodes      *MockPhoneVerificationStorage
+	audit           *MockAuditRepository
+	knownDevices    *MockKnownDeviceRepository
+	loginEvents     *MockLoginEventRepository
+	mailer          *MockMailer
+	sms             *MockSmsSender
+}
+
+func withoutLockout(mocks authServiceMocks) {
+	mocks.loginAttempts.On("LockedFor", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+}
+
+func withoutRevokedTokens(mocks authServiceMocks) {
+	mocks.revocations.On("UserTokensValidAfter", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+}
+
+func withoutMFA(mocks authServiceMocks) {
+	mocks.mfa.On("FindTOTP", mock.Anything, mock.Anything).Return(model.TOTP{}, model.ErrNotFound)
+	mocks.roles.On("IsMFARequired", mock.Anything, mock.Anything).Return(false, nil)
+}
+
+func withoutPasswordExpiry(mocks authServiceMocks) {
+	mocks.roles.On("PasswordMaxAge", mock.Anything, mock.Anything).Return(0, nil)
+}
+
+func withoutPasswordHistory(mocks authServiceMocks) {
+	mocks.roles.On("PasswordHistorySize", mock.Anything, mock.Anything).Return(0, nil)
+}
+
+func newAuthServiceWithMocks() (*AuthService, authServiceMocks) {
 	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	validate := validator.New()
 	validate.RegisterValidation("min_age", validatecfg.MinAge)
 	validate.RegisterValidation("complex_password", validatecfg.ComplexPassword)
-	mockRepo := new(MockUserRepository)
-	mockJWT := new(MockJWTProvider)
+	validate.RegisterValidation("not_breached", validatecfg.NotBreached(testBreachedPasswords))
+	mocks := authServiceMocks{
+		repo:            new(MockUserRepository),
+		jwt:             new(MockJWTProvider),
+		sessions:        new(MockSessionStorage),
+		revocations:     new(MockTokenRevocationStorage),
+		loginAttempts:   new(MockLoginAttemptStorage),
+		mfa:             new(MockMFARepository),
+		roles:           new(MockRoleRepository),
+		passwordHistory: new(MockPasswordHistoryRepository),
+		passwordReset:   new(MockPasswordResetStorage),
+		loginCodes:      new(MockLoginCodeStorage),
+		emailChange:     new(MockEmailChangeStorage),
+		phoneCodes:      new(MockPhoneVerificationStorage),
+		audit:           new(MockAuditRepository),
+		knownDevices:    new(MockKnownDeviceRepository),
+		loginEvents:     new(MockLoginEventRepository),
+		mailer:          new(MockMailer),
+		sms:             new(MockSmsSender),
+	}
+	// Recording a password only logs failures, tests check it where it matters
+	mocks.passwordHistory.On("Insert", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	// Every login is the first one of the user, which is not alerted about
+	mocks.knownDevices.On("Find", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	mocks.knownDevices.On("Insert", mock.Anything, mock.Anything).Return(uint64(1), nil).Maybe()
+	// Recording a login only logs failures, tests check it where it matters
+	mocks.loginEvents.On("Insert", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	userService := &UserService{
+		log:                      log,
+		validate:                 validate,
+		jwtProvider:              mocks.jwt,
+		passwordHasher:           testPasswordHasher,
+		userRepo:                 mocks.repo,
+		roleRepo:                 mocks.roles,
+		passwordHistoryRepo:      mocks.passwordHistory,
+		phoneVerificationStorage: mocks.phoneCodes,
+		emailChangeStorage:       mocks.emailChange,
+		tokenRevocationStorage:   mocks.revocations,
+		loginAttemptStorage:      mocks.loginAttempts,
+		mailer:                   mocks.mailer,
+		smsSender:                mocks.sms,
+	}
+
+	mfaService := &MFAService{
+		log:                 log,
+		validate:            validate,
+		userRepo:            mocks.repo,
+		mfaRepo:             mocks.mfa,
+		roleRepo:            mocks.roles,
+		issuer:              "Car Rental",
+		secretEncryptionKey: testSecretEncryptionKey,
+	}
+
+	loginHistoryService := &LoginHistoryService{
+		log:            log,
+		validate:       validate,
+		loginEventRepo: mocks.loginEvents,
+		cfg:            loginhistory.Config{Retention: 90 * 24 * time.Hour},
+	}
 
 	service := &AuthService{
-		log:         log,
-		validate:    validate,
-		jwtProvider: mockJWT,
-		userRepo:    mockRepo,
+		log:                    log,
+		validate:               validate,
+		jwtProvider:            mocks.jwt,
+		userService:            userService,
+		mfaService:             mfaService,
+		loginHistoryService:    loginHistoryService,
+		sessionStorage:         mocks.sessions,
+		tokenRevocationStorage: mocks.revocations,
+		loginAttemptStorage:    mocks.loginAttempts,
+		passwordResetStorage:   mocks.passwordReset,
+		loginCodeStorage:       mocks.loginCodes,
+		auditRepo:              mocks.audit,
+		knownDeviceRepo:        mocks.knownDevices,
+		mailer:                 mocks.mailer,
+		passwordless: passwordless.Config{
+			Enabled:     true,
+			LinkURL:     "https://rental.example.com/login",
+			MaxAttempts: 3,
+		},
+		deviceAlert: devicealert.Config{ReportURL: "https://rental.example.com/not-me"},
 	}
 
-	return service, mockRepo, mockJWT
+	return service, mocks
+}
+
+func claimsForUser(userID uint64, roles []string) any {
+	return mock.MatchedBy(func(c model.TokenClaims) bool {
+		return c.UserID == userID && assert.ObjectsAreEqual(roles, c.Roles) && c.SessionID != "" && c.TokenID != ""
+	})
+}
+
+func claimsForSession(userID uint64, sessionID string) any {
+	return mock.MatchedBy(func(c model.TokenClaims) bool {
+		return c.UserID == userID && c.SessionID == sessionID && c.TokenID != ""
+	})
+}
+
+func refreshTokenClaims() model.TokenClaims {
+	return model.TokenClaims{
+		UserID:    123,
+		Roles:     []string{"user"},
+		SessionID: "session_123",
+		TokenID:   "refresh_123",
+		Type:      model.TokenTypeRefresh,
+		IssuedAt:  time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
 }
 
 func TestAuthService_Register_Success(t *testing.T) {
-	service, mockRepo, _ := setupAuthService()
+	service, mocks := newAuthServiceWithMocks()
 	ctx := context.Background()
 
-	cred := model.Credentials{
+	data := model.UserCreateData{
 		Email:                "test@example.com",
 		PhoneNumber:          "+1234567890",
 		Password:             "StrongPass123!",
@@ -100,20 +619,50 @@ func TestAuthService_Register_Success(t *testing.T) {
 	}
 
 	expectedID := uint64(123)
-	mockRepo.On("Insert", ctx, mock.AnythingOfType("model.User")).Return(expectedID, nil)
+	mocks.repo.On("FindOne", ctx, mock.AnythingOfType("model.UserFilter")).Return(model.User{}, model.ErrNotFound)
+	mocks.repo.On("Insert", ctx, mock.AnythingOfType("model.User")).Return(expectedID, nil)
 
-	userID, err := service.Register(ctx, cred)
+	userID, err := service.Register(ctx, data)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedID, userID)
-	mockRepo.AssertExpectations(t)
+	mocks.repo.AssertExpectations(t)
+}
+
+func TestAuthService_Register_DuplicatePhoneNumber(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	data := model.UserCreateData{
+		Email:                "test@example.com",
+		PhoneNumber:          "+1 234 567 890",
+		Password:             "StrongPass123!",
+		PasswordConfirmation: "StrongPass123!",
+		FirstName:            "John",
+		LastName:             "Doe",
+		BirthDate:            time.Now().AddDate(-25, 0, 0),
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.Email != nil
+	})).Return(model.User{}, model.ErrNotFound)
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.PhoneNumber != nil && *f.PhoneNumber == "+1234567890"
+	})).Return(model.User{ID: 7}, nil)
+
+	userID, err := service.Register(ctx, data)
+
+	assert.Equal(t, model.ErrDuplicatePhoneNumber, err)
+	assert.Equal(t, uint64(0), userID)
+	mocks.repo.AssertExpectations(t)
+	mocks.repo.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
 }
 
 func TestAuthService_Register_ValidationError_PasswordMismatch(t *testing.T) {
-	service, _, _ := setupAuthService()
+	service, _ := newAuthServiceWithMocks()
 	ctx := context.Background()
 
-	cred := model.Credentials{
+	data := model.UserCreateData{
 		Email:                "test@example.com",
 		PhoneNumber:          "+1234567890",
 		Password:             "StrongPass123!",
@@ -123,17 +672,37 @@ func TestAuthService_Register_ValidationError_PasswordMismatch(t *testing.T) {
 		BirthDate:            time.Now().AddDate(-25, 0, 0),
 	}
 
-	userID, err := service.Register(ctx, cred)
+	userID, err := service.Register(ctx, data)
 
 	assert.Error(t, err)
 	assert.Equal(t, uint64(0), userID)
 }
 
+func TestAuthService_Register_ValidationError_BreachedPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	data := model.UserCreateData{
+		Email:                "test@example.com",
+		PhoneNumber:          "+1234567890",
+		Password:             "Password1!",
+		PasswordConfirmation: "Password1!",
+		FirstName:            "John",
+		LastName:             "Doe",
+		BirthDate:            time.Now().AddDate(-25, 0, 0),
+	}
+
+	_, err := service.Register(ctx, data)
+
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrBreachedPassword}, err)
+	mocks.repo.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+}
+
 func TestAuthService_Register_ValidationError_InvalidEmail(t *testing.T) {
-	service, _, _ := setupAuthService()
+	service, _ := newAuthServiceWithMocks()
 	ctx := context.Background()
 
-	cred := model.Credentials{
+	data := model.UserCreateData{
 		Email:                "invalid-email",
 		PhoneNumber:          "+1234567890",
 		Password:             "StrongPass123!",
@@ -143,43 +712,235 @@ func TestAuthService_Register_ValidationError_InvalidEmail(t *testing.T) {
 		BirthDate:            time.Now().AddDate(-25, 0, 0),
 	}
 
-	userID, err := service.Register(ctx, cred)
+	userID, err := service.Register(ctx, data)
+
+	assert.Error(t, err)
+	assert.Equal(t, uint64(0), userID)
+}
+
+func TestAuthService_Register_RepositoryError(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	data := model.UserCreateData{
+		Email:                "test@example.com",
+		PhoneNumber:          "+1234567890",
+		Password:             "StrongPass123!",
+		PasswordConfirmation: "StrongPass123!",
+		FirstName:            "John",
+		LastName:             "Doe",
+		BirthDate:            time.Now().AddDate(-25, 0, 0),
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.AnythingOfType("model.UserFilter")).Return(model.User{}, model.ErrNotFound)
+	mocks.repo.On("Insert", ctx, mock.AnythingOfType("model.User")).Return(uint64(0), model.ErrSql)
+
+	userID, err := service.Register(ctx, data)
 
 	assert.Error(t, err)
+	assert.Equal(t, model.ErrSql, err)
 	assert.Equal(t, uint64(0), userID)
+	mocks.repo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_Success_WithEmail(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := context.Background()
+
+	cred := model.Credentials{
+		Email:    "test@example.com",
+		Password: "StrongPass123!",
+	}
+
+	expectedUser := model.User{
+		ID:           123,
+		Email:        "test@example.com",
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"), // hash of "StrongPass123!"
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	email := cred.Email
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.Email != nil && *f.Email == email
+	})).Return(expectedUser, nil)
+
+	mocks.jwt.On("GenerateAccessToken", claimsForUser(123, []string{"user"})).Return("access_token_123", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForUser(123, []string{"user"})).Return("refresh_token_123", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.AnythingOfType("string")).Return(nil)
+
+	result, err := service.Login(ctx, cred)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token_123", result.Token.AccessToken)
+	assert.Equal(t, "refresh_token_123", result.Token.RefreshToken)
+	mocks.repo.AssertExpectations(t)
+	mocks.jwt.AssertExpectations(t)
+	mocks.sessions.AssertExpectations(t)
+}
+
+func TestAuthService_Login_CreatesSessionPerLogin(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := context.Background()
+
+	cred := model.Credentials{
+		Email:    "test@example.com",
+		Password: "StrongPass123!",
+	}
+
+	expectedUser := model.User{
+		ID:           123,
+		Email:        "test@example.com",
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.AnythingOfType("model.UserFilter")).Return(expectedUser, nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token_123", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token_123", nil)
+
+	var sessionIDs []string
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) {
+			sessionIDs = append(sessionIDs, args.Get(1).(model.Session).ID)
+		}).
+		Return(nil)
+
+	_, err := service.Login(ctx, cred)
+	assert.NoError(t, err)
+	_, err = service.Login(ctx, cred)
+	assert.NoError(t, err)
+
+	assert.Len(t, sessionIDs, 2)
+	assert.NotEqual(t, sessionIDs[0], sessionIDs[1])
+}
+
+func TestAuthService_Login_Success_WithPhoneNumber(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := context.Background()
+
+	cred := model.Credentials{
+		PhoneNumber: "+1234567890",
+		Password:    "StrongPass123!",
+	}
+
+	expectedUser := model.User{
+		ID:           123,
+		PhoneNumber:  "+1234567890",
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	phoneNumber := cred.PhoneNumber
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.Email == nil && f.PhoneNumber != nil && *f.PhoneNumber == phoneNumber
+	})).Return(expectedUser, nil)
+
+	mocks.jwt.On("GenerateAccessToken", claimsForUser(123, []string{"user"})).Return("access_token_123", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForUser(123, []string{"user"})).Return("refresh_token_123", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.AnythingOfType("string")).Return(nil)
+
+	result, err := service.Login(ctx, cred)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token_123", result.Token.AccessToken)
+	assert.Equal(t, "refresh_token_123", result.Token.RefreshToken)
+	mocks.repo.AssertExpectations(t)
+	mocks.jwt.AssertExpectations(t)
+}
+
+func TestAuthService_Login_NormalizesPhoneNumber(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := context.Background()
+
+	cred := model.Credentials{
+		PhoneNumber: "+1 (234) 567-890",
+		Password:    "StrongPass123!",
+	}
+
+	expectedUser := model.User{
+		ID:           123,
+		PhoneNumber:  "+1234567890",
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.PhoneNumber != nil && *f.PhoneNumber == "+1234567890"
+	})).Return(expectedUser, nil)
+
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token_123", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token_123", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.AnythingOfType("string")).Return(nil)
+
+	_, err := service.Login(ctx, cred)
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_ValidationError(t *testing.T) {
+	service, _ := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	cred := model.Credentials{
+		Email:    "", // Missing both email and phone
+		Password: "StrongPass123!",
+	}
+
+	result, err := service.Login(ctx, cred)
+
+	assert.Error(t, err)
+	assert.Empty(t, result.Token.AccessToken)
+	assert.Empty(t, result.Token.RefreshToken)
 }
 
-func TestAuthService_Register_RepositoryError(t *testing.T) {
-	service, mockRepo, _ := setupAuthService()
+func TestAuthService_Login_UserNotFound(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
 	ctx := context.Background()
 
 	cred := model.Credentials{
-		Email:                "test@example.com",
-		PhoneNumber:          "+1234567890",
-		Password:             "StrongPass123!",
-		PasswordConfirmation: "StrongPass123!",
-		FirstName:            "John",
-		LastName:             "Doe",
-		BirthDate:            time.Now().AddDate(-25, 0, 0),
+		Email:    "notfound@example.com",
+		Password: "StrongPass123!",
 	}
 
-	mockRepo.On("Insert", ctx, mock.AnythingOfType("model.User")).Return(uint64(0), errors.New("database error"))
+	email := cred.Email
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.Email != nil && *f.Email == email
+	})).Return(model.User{}, model.ErrNotFound)
 
-	userID, err := service.Register(ctx, cred)
+	result, err := service.Login(ctx, cred)
 
 	assert.Error(t, err)
-	assert.Equal(t, model.ErrSql, err)
-	assert.Equal(t, uint64(0), userID)
-	mockRepo.AssertExpectations(t)
+	assert.Equal(t, model.ErrNotFound, err)
+	assert.Empty(t, result.Token.AccessToken)
+	mocks.repo.AssertExpectations(t)
 }
 
-func TestAuthService_Login_Success_WithEmail(t *testing.T) {
-	service, mockRepo, mockJWT := setupAuthService()
+func TestAuthService_Login_WrongPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("AddFailure", mock.Anything, mock.Anything).Return(int64(1), nil)
 	ctx := context.Background()
 
 	cred := model.Credentials{
 		Email:    "test@example.com",
-		Password: "StrongPass123!",
+		Password: "WrongPassword123!",
 	}
 
 	expectedUser := model.User{
@@ -190,124 +951,147 @@ func TestAuthService_Login_Success_WithEmail(t *testing.T) {
 	}
 
 	email := cred.Email
-	mockRepo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
 		return f.Email != nil && *f.Email == email
 	})).Return(expectedUser, nil)
 
-	mockJWT.On("GenerateAccessToken", uint64(123), []string{"user"}).Return("access_token_123", nil)
-	mockJWT.On("GenerateRefreshToken", uint64(123), []string{"user"}).Return("refresh_token_123", nil)
+	result, err := service.Login(ctx, cred)
 
-	token, err := service.Login(ctx, cred)
-
-	assert.NoError(t, err)
-	assert.Equal(t, "access_token_123", token.AccessToken)
-	assert.Equal(t, "refresh_token_123", token.RefreshToken)
-	mockRepo.AssertExpectations(t)
-	mockJWT.AssertExpectations(t)
+	assert.Error(t, err)
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrPasswordsDoNotMatch}, err)
+	assert.Empty(t, result.Token.AccessToken)
+	mocks.repo.AssertExpectations(t)
 }
 
-func TestAuthService_Login_Success_WithPhoneNumber(t *testing.T) {
-	service, mockRepo, mockJWT := setupAuthService()
-	ctx := context.Background()
+func TestAuthService_Login_WrongPasswordLocksAccountAfterFreeAttempts(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "client-ip", "10.0.0.1")
 
 	cred := model.Credentials{
-		PhoneNumber: "+1234567890",
-		Password:    "StrongPass123!",
+		Email:    "test@example.com",
+		Password: "WrongPassword123!",
 	}
 
 	expectedUser := model.User{
 		ID:           123,
-		PhoneNumber:  "+1234567890",
+		Email:        "test@example.com",
 		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
 		Roles:        []model.Role{model.RoleUser},
 	}
 
-	phoneNumber := cred.PhoneNumber
-	mockRepo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
-		return f.PhoneNumber != nil && *f.PhoneNumber == phoneNumber
-	})).Return(expectedUser, nil)
-
-	mockJWT.On("GenerateAccessToken", uint64(123), []string{"user"}).Return("access_token_123", nil)
-	mockJWT.On("GenerateRefreshToken", uint64(123), []string{"user"}).Return("refresh_token_123", nil)
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(expectedUser, nil)
+	mocks.loginAttempts.On("LockedFor", ctx, mock.Anything).Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("AddFailure", ctx, "ip:10.0.0.1").Return(int64(3), nil)
+	mocks.loginAttempts.On("AddFailure", ctx, "account:123").Return(int64(accountFreeLoginAttempts+2), nil)
+	mocks.loginAttempts.On("Lock", ctx, "account:123", 2*loginBackoffBase).Return(nil)
 
-	token, err := service.Login(ctx, cred)
+	_, err := service.Login(ctx, cred)
 
-	assert.NoError(t, err)
-	assert.Equal(t, "access_token_123", token.AccessToken)
-	assert.Equal(t, "refresh_token_123", token.RefreshToken)
-	mockRepo.AssertExpectations(t)
-	mockJWT.AssertExpectations(t)
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrPasswordsDoNotMatch}, err)
+	mocks.loginAttempts.AssertExpectations(t)
+	mocks.loginAttempts.AssertNotCalled(t, "Lock", ctx, "ip:10.0.0.1", mock.Anything)
 }
 
-func TestAuthService_Login_ValidationError(t *testing.T) {
-	service, _, _ := setupAuthService()
+func TestAuthService_Login_LockedAccount(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
 	ctx := context.Background()
 
 	cred := model.Credentials{
-		Email:    "", // Missing both email and phone
+		Email:    "test@example.com",
 		Password: "StrongPass123!",
 	}
 
-	token, err := service.Login(ctx, cred)
+	expectedUser := model.User{
+		ID:           123,
+		Email:        "test@example.com",
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(expectedUser, nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Minute, nil)
 
-	assert.Error(t, err)
-	assert.Empty(t, token.AccessToken)
-	assert.Empty(t, token.RefreshToken)
+	result, err := service.Login(ctx, cred)
+
+	assert.ErrorIs(t, err, model.ErrTooManyLoginAttempts)
+	assert.Equal(t, model.LockoutError{RetryAfter: time.Minute}, err)
+	assert.Empty(t, result.Token.AccessToken)
+	mocks.loginAttempts.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
 }
 
-func TestAuthService_Login_UserNotFound(t *testing.T) {
-	service, mockRepo, _ := setupAuthService()
-	ctx := context.Background()
+func TestAuthService_Login_LockedClientSkipsLookup(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "client-ip", "10.0.0.1")
 
 	cred := model.Credentials{
-		Email:    "notfound@example.com",
+		Email:    "test@example.com",
 		Password: "StrongPass123!",
 	}
 
-	email := cred.Email
-	mockRepo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
-		return f.Email != nil && *f.Email == email
-	})).Return(model.User{}, errors.New("not found"))
+	mocks.loginAttempts.On("LockedFor", ctx, "ip:10.0.0.1").Return(30*time.Second, nil)
 
-	token, err := service.Login(ctx, cred)
+	_, err := service.Login(ctx, cred)
 
-	assert.Error(t, err)
-	assert.Equal(t, model.ErrNotFound, err)
-	assert.Empty(t, token.AccessToken)
-	mockRepo.AssertExpectations(t)
+	assert.Equal(t, model.LockoutError{RetryAfter: 30 * time.Second}, err)
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
 }
 
-func TestAuthService_Login_WrongPassword(t *testing.T) {
-	service, mockRepo, _ := setupAuthService()
+func TestAuthService_Login_SuccessResetsAccountFailures(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	mocks.sessions.On("Save", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
 	ctx := context.Background()
 
 	cred := model.Credentials{
 		Email:    "test@example.com",
-		Password: "WrongPassword123!",
+		Password: "StrongPass123!",
 	}
 
 	expectedUser := model.User{
 		ID:           123,
 		Email:        "test@example.com",
-		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"), // hash of "StrongPass123!"
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
 		Roles:        []model.Role{model.RoleUser},
 	}
 
-	email := cred.Email
-	mockRepo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
-		return f.Email != nil && *f.Email == email
-	})).Return(expectedUser, nil)
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(expectedUser, nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
 
-	token, err := service.Login(ctx, cred)
+	_, err := service.Login(ctx, cred)
 
-	assert.Error(t, err)
-	assert.Equal(t, model.ErrPasswordsDoNotMatch, err)
-	assert.Empty(t, token.AccessToken)
-	mockRepo.AssertExpectations(t)
+	assert.NoError(t, err)
+	mocks.loginAttempts.AssertExpectations(t)
+}
+
+func TestLoginBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int64
+		want     time.Duration
+	}{
+		{name: "within free attempts", failures: accountFreeLoginAttempts, want: 0},
+		{name: "first failure after free attempts", failures: accountFreeLoginAttempts + 1, want: loginBackoffBase},
+		{name: "doubles with every failure", failures: accountFreeLoginAttempts + 3, want: 4 * loginBackoffBase},
+		{name: "capped at max lockout", failures: accountFreeLoginAttempts + 100, want: maxLoginLockout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, loginBackoff(tt.failures, accountFreeLoginAttempts))
+		})
+	}
 }
 
 func TestAuthService_Login_AccessTokenGenerationError(t *testing.T) {
-	service, mockRepo, mockJWT := setupAuthService()
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
 	ctx := context.Background()
 
 	cred := model.Credentials{
@@ -323,23 +1107,27 @@ func TestAuthService_Login_AccessTokenGenerationError(t *testing.T) {
 	}
 
 	email := cred.Email
-	mockRepo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
 		return f.Email != nil && *f.Email == email
 	})).Return(expectedUser, nil)
 
-	mockJWT.On("GenerateAccessToken", uint64(123), []string{"user"}).Return("", errors.New("jwt error"))
+	mocks.jwt.On("GenerateAccessToken", claimsForUser(123, []string{"user"})).Return("", errors.New("jwt error"))
 
-	token, err := service.Login(ctx, cred)
+	result, err := service.Login(ctx, cred)
 
 	assert.Error(t, err)
 	assert.Equal(t, model.ErrJwt, err)
-	assert.Empty(t, token.AccessToken)
-	mockRepo.AssertExpectations(t)
-	mockJWT.AssertExpectations(t)
+	assert.Empty(t, result.Token.AccessToken)
+	mocks.repo.AssertExpectations(t)
+	mocks.jwt.AssertExpectations(t)
 }
 
 func TestAuthService_Login_RefreshTokenGenerationError(t *testing.T) {
-	service, mockRepo, mockJWT := setupAuthService()
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
 	ctx := context.Background()
 
 	cred := model.Credentials{
@@ -355,55 +1143,136 @@ func TestAuthService_Login_RefreshTokenGenerationError(t *testing.T) {
 	}
 
 	email := cred.Email
-	mockRepo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+	mocks.repo.On("FindOne", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
 		return f.Email != nil && *f.Email == email
 	})).Return(expectedUser, nil)
 
-	mockJWT.On("GenerateAccessToken", uint64(123), []string{"user"}).Return("access_token_123", nil)
-	mockJWT.On("GenerateRefreshToken", uint64(123), []string{"user"}).Return("", errors.New("jwt error"))
+	mocks.jwt.On("GenerateAccessToken", claimsForUser(123, []string{"user"})).Return("access_token_123", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForUser(123, []string{"user"})).Return("", errors.New("jwt error"))
 
-	token, err := service.Login(ctx, cred)
+	result, err := service.Login(ctx, cred)
 
 	assert.Error(t, err)
 	assert.Equal(t, model.ErrJwt, err)
-	assert.Empty(t, token.RefreshToken)
-	mockRepo.AssertExpectations(t)
-	mockJWT.AssertExpectations(t)
+	assert.Empty(t, result.Token.RefreshToken)
+	mocks.repo.AssertExpectations(t)
+	mocks.jwt.AssertExpectations(t)
 }
 
 func TestAuthService_RefreshToken_Success(t *testing.T) {
-	service, _, mockJWT := setupAuthService()
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
 	ctx := context.Background()
 
 	refreshToken := "valid.refresh.token"
-	userID := uint64(123)
-	roles := []string{"user", "admin"}
+	claims := refreshTokenClaims()
+	claims.Roles = []string{"user", "admin"}
 
-	mockJWT.On("VerifyAndParseClaims", refreshToken).Return(userID, roles, nil)
-	mockJWT.On("GenerateAccessToken", userID, roles).Return("new_access_token", nil)
-	mockJWT.On("GenerateRefreshToken", userID, roles).Return("new_refresh_token", nil)
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.jwt.On("GenerateAccessToken", claimsForSession(claims.UserID, claims.SessionID)).Return("new_access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForSession(claims.UserID, claims.SessionID)).Return("new_refresh_token", nil)
+	mocks.sessions.On("Rotate", ctx, claims.UserID, claims.SessionID, claims.TokenID, mock.AnythingOfType("string")).Return(nil)
 
 	token, err := service.RefreshToken(ctx, refreshToken)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "new_access_token", token.AccessToken)
 	assert.Equal(t, "new_refresh_token", token.RefreshToken)
-	mockJWT.AssertExpectations(t)
+	mocks.jwt.AssertExpectations(t)
+	mocks.sessions.AssertExpectations(t)
+}
+
+func TestAuthService_RefreshToken_RotatesToIssuedToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
+	ctx := context.Background()
+
+	refreshToken := "valid.refresh.token"
+	claims := refreshTokenClaims()
+
+	var issuedTokenID string
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("new_access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).
+		Run(func(args mock.Arguments) {
+			issuedTokenID = args.Get(0).(model.TokenClaims).TokenID
+		}).
+		Return("new_refresh_token", nil)
+
+	var rotatedTokenID string
+	mocks.sessions.On("Rotate", ctx, claims.UserID, claims.SessionID, claims.TokenID, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) {
+			rotatedTokenID = args.String(4)
+		}).
+		Return(nil)
+
+	_, err := service.RefreshToken(ctx, refreshToken)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, issuedTokenID)
+	assert.NotEqual(t, claims.TokenID, issuedTokenID)
+	assert.Equal(t, issuedTokenID, rotatedTokenID)
+}
+
+func TestAuthService_RefreshToken_ReuseRevokesSession(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	mocks.revocations.On("UserTokensValidAfter", mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	ctx := context.Background()
+
+	refreshToken := "valid.refresh.token"
+	claims := refreshTokenClaims()
+
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("new_access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("new_refresh_token", nil)
+	mocks.sessions.On("Rotate", ctx, claims.UserID, claims.SessionID, claims.TokenID, mock.AnythingOfType("string")).
+		Return(model.ErrRefreshTokenReused)
+	mocks.sessions.On("Delete", ctx, claims.UserID, claims.SessionID).Return(nil)
+	mocks.revocations.On("RevokeSession", ctx, claims.SessionID).Return(nil)
+
+	token, err := service.RefreshToken(ctx, refreshToken)
+
+	assert.Error(t, err)
+	assert.Equal(t, model.ErrInvalidToken, err)
+	assert.Empty(t, token.AccessToken)
+	assert.Empty(t, token.RefreshToken)
+	mocks.sessions.AssertExpectations(t)
+	mocks.revocations.AssertExpectations(t)
+}
+
+func TestAuthService_RefreshToken_AccessTokenRejected(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	accessToken := "valid.access.token"
+	claims := refreshTokenClaims()
+	claims.Type = model.TokenTypeAccess
+
+	mocks.jwt.On("VerifyAndParseClaims", accessToken).Return(claims, nil)
+
+	token, err := service.RefreshToken(ctx, accessToken)
+
+	assert.Error(t, err)
+	assert.Equal(t, model.ErrInvalidToken, err)
+	assert.Empty(t, token.AccessToken)
+	mocks.jwt.AssertNotCalled(t, "GenerateAccessToken", mock.Anything)
+	mocks.sessions.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mocks.sessions.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestAuthService_RefreshToken_EmptyToken(t *testing.T) {
-	service, _, _ := setupAuthService()
+	service, _ := newAuthServiceWithMocks()
 	ctx := context.Background()
 
 	token, err := service.RefreshToken(ctx, "")
 
 	assert.Error(t, err)
-	assert.Equal(t, model.ErrRequiredField, err)
+	assert.Equal(t, model.ValidationErrors{"refreshToken": model.ErrRequiredField}, err)
 	assert.Empty(t, token.AccessToken)
 }
 
 func TestAuthService_RefreshToken_InvalidToken(t *testing.T) {
-	service, _, _ := setupAuthService()
+	service, _ := newAuthServiceWithMocks()
 	ctx := context.Background()
 
 	refreshToken := "invalid_token_format"
@@ -411,61 +1280,441 @@ func TestAuthService_RefreshToken_InvalidToken(t *testing.T) {
 	token, err := service.RefreshToken(ctx, refreshToken)
 
 	assert.Error(t, err)
-	assert.Equal(t, model.ErrInvalidJwtToken, err)
+	assert.Equal(t, model.ValidationErrors{"refreshToken": model.ErrInvalidJwtToken}, err)
 	assert.Empty(t, token.AccessToken)
 }
 
 func TestAuthService_RefreshToken_VerificationError(t *testing.T) {
-	service, _, mockJWT := setupAuthService()
+	service, mocks := newAuthServiceWithMocks()
 	ctx := context.Background()
 
 	refreshToken := "valid.format.token"
 
-	mockJWT.On("VerifyAndParseClaims", refreshToken).Return(uint64(0), []string(nil), errors.New("verification failed"))
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(model.TokenClaims{}, errors.New("verification failed"))
 
 	token, err := service.RefreshToken(ctx, refreshToken)
 
 	assert.Error(t, err)
-	assert.Equal(t, model.ErrJwt, err)
+	assert.Equal(t, model.ErrInvalidToken, err)
+	assert.Empty(t, token.AccessToken)
+	mocks.jwt.AssertExpectations(t)
+}
+
+func TestAuthService_RefreshToken_SessionLoggedOut(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
+	ctx := context.Background()
+
+	refreshToken := "valid.refresh.token"
+	claims := refreshTokenClaims()
+
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("new_access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("new_refresh_token", nil)
+	mocks.sessions.On("Rotate", ctx, claims.UserID, claims.SessionID, claims.TokenID, mock.AnythingOfType("string")).
+		Return(model.ErrNotFound)
+
+	token, err := service.RefreshToken(ctx, refreshToken)
+
+	assert.Error(t, err)
+	assert.Equal(t, model.ErrInvalidToken, err)
 	assert.Empty(t, token.AccessToken)
-	mockJWT.AssertExpectations(t)
+	mocks.sessions.AssertExpectations(t)
+	mocks.sessions.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestAuthService_RefreshToken_NewAccessTokenError(t *testing.T) {
-	service, _, mockJWT := setupAuthService()
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
 	ctx := context.Background()
 
 	refreshToken := "valid.refresh.token"
-	userID := uint64(123)
-	roles := []string{"user"}
+	claims := refreshTokenClaims()
 
-	mockJWT.On("VerifyAndParseClaims", refreshToken).Return(userID, roles, nil)
-	mockJWT.On("GenerateAccessToken", userID, roles).Return("", errors.New("generation failed"))
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.jwt.On("GenerateAccessToken", claimsForSession(claims.UserID, claims.SessionID)).Return("", errors.New("generation failed"))
 
 	token, err := service.RefreshToken(ctx, refreshToken)
 
 	assert.Error(t, err)
 	assert.Equal(t, model.ErrJwt, err)
 	assert.Empty(t, token.AccessToken)
-	mockJWT.AssertExpectations(t)
+	mocks.jwt.AssertExpectations(t)
+	mocks.sessions.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestAuthService_RefreshToken_NewRefreshTokenError(t *testing.T) {
-	service, _, mockJWT := setupAuthService()
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
 	ctx := context.Background()
 
 	refreshToken := "valid.refresh.token"
-	userID := uint64(123)
-	roles := []string{"user"}
+	claims := refreshTokenClaims()
 
-	mockJWT.On("VerifyAndParseClaims", refreshToken).Return(userID, roles, nil)
-	mockJWT.On("GenerateAccessToken", userID, roles).Return("new_access_token", nil)
-	mockJWT.On("GenerateRefreshToken", userID, roles).Return("", errors.New("generation failed"))
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.jwt.On("GenerateAccessToken", claimsForSession(claims.UserID, claims.SessionID)).Return("new_access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForSession(claims.UserID, claims.SessionID)).Return("", errors.New("generation failed"))
 
 	token, err := service.RefreshToken(ctx, refreshToken)
 
 	assert.Error(t, err)
 	assert.Equal(t, model.ErrJwt, err)
 	assert.Empty(t, token.RefreshToken)
-	mockJWT.AssertExpectations(t)
+	mocks.jwt.AssertExpectations(t)
+	mocks.sessions.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Logout_DeletesOnlyCurrentSession(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	refreshToken := "valid.refresh.token"
+	claims := refreshTokenClaims()
+
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.sessions.On("Delete", ctx, claims.UserID, claims.SessionID).Return(nil)
+
+	err := service.Logout(ctx, refreshToken, "")
+
+	assert.NoError(t, err)
+	mocks.sessions.AssertExpectations(t)
+}
+
+func TestAuthService_Logout_RevokesAccessTokenOfSession(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	refreshToken := "valid.refresh.token"
+	accessToken := "valid.access.token"
+	claims := refreshTokenClaims()
+	accessClaims := claims
+	accessClaims.TokenID = "access_123"
+	accessClaims.Type = model.TokenTypeAccess
+
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.jwt.On("VerifyAndParseClaims", accessToken).Return(accessClaims, nil)
+	mocks.sessions.On("Delete", ctx, claims.UserID, claims.SessionID).Return(nil)
+	mocks.revocations.On("RevokeToken", ctx, accessClaims.TokenID, accessClaims.ExpiresAt).Return(nil)
+
+	err := service.Logout(ctx, refreshToken, accessToken)
+
+	assert.NoError(t, err)
+	mocks.sessions.AssertExpectations(t)
+	mocks.revocations.AssertExpectations(t)
+}
+
+func TestAuthService_RefreshToken_IssuedBeforeUserRevocation(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	refreshToken := "valid.refresh.token"
+	claims := refreshTokenClaims()
+
+	mocks.jwt.On("VerifyAndParseClaims", refreshToken).Return(claims, nil)
+	mocks.revocations.On("UserTokensValidAfter", ctx, claims.UserID).Return(time.Now(), nil)
+
+	token, err := service.RefreshToken(ctx, refreshToken)
+
+	assert.Error(t, err)
+	assert.Equal(t, model.ErrInvalidToken, err)
+	assert.Empty(t, token.AccessToken)
+	mocks.jwt.AssertNotCalled(t, "GenerateAccessToken", mock.Anything)
+	mocks.sessions.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RequestPasswordReset_SendsToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	user := model.User{ID: 123, Email: "test@example.com"}
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(user, nil)
+	mocks.passwordReset.On("Save", ctx, uint64(123)).Return("reset_token", nil)
+	mocks.mailer.On("SendPasswordResetToken", ctx, "test@example.com", "reset_token").Return(nil)
+
+	err := service.RequestPasswordReset(ctx, "test@example.com")
+
+	assert.NoError(t, err)
+	mocks.mailer.AssertExpectations(t)
+}
+
+func TestAuthService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(model.User{}, model.ErrNotFound)
+
+	err := service.RequestPasswordReset(ctx, "unknown@example.com")
+
+	assert.NoError(t, err)
+	mocks.passwordReset.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	mocks.mailer.AssertNotCalled(t, "SendPasswordResetToken", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ResetPassword_EndsAllSessions(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.passwordReset.On("Find", ctx, "reset_token").Return(uint64(123), nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{
+		ID:           123,
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}, nil)
+	withoutPasswordHistory(mocks)
+	mocks.passwordReset.On("Consume", ctx, "reset_token").Return(uint64(123), nil)
+	mocks.repo.On("Update", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.ID != nil && *f.ID == 123
+	}), mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.PasswordHash != nil && security.CheckStringHash("NewPass123!", *u.PasswordHash) == nil
+	})).Return(nil)
+	mocks.revocations.On("RevokeUserTokens", ctx, uint64(123), mock.Anything).Return(nil)
+	mocks.sessions.On("DeleteAll", ctx, uint64(123)).Return(nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil)
+
+	err := service.ResetPassword(ctx, "reset_token", "NewPass123!")
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+	mocks.revocations.AssertExpectations(t)
+	mocks.sessions.AssertExpectations(t)
+}
+
+func TestAuthService_ResetPassword_UsedToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.passwordReset.On("Find", ctx, "reset_token").Return(uint64(0), model.ErrNotFound)
+
+	err := service.ResetPassword(ctx, "reset_token", "NewPass123!")
+
+	assert.ErrorIs(t, err, model.ErrInvalidResetToken)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ResetPassword_RejectsRecentPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	recentHash, err := testPasswordHasher.Hash("NewPass123!")
+	assert.NoError(t, err)
+
+	mocks.passwordReset.On("Find", ctx, "reset_token").Return(uint64(123), nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{
+		ID:           123,
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleAdmin},
+	}, nil)
+	mocks.roles.On("PasswordHistorySize", ctx, []model.Role{model.RoleAdmin}).Return(5, nil)
+	mocks.passwordHistory.On("FindRecent", ctx, uint64(123), 5).Return([][]byte{recentHash}, nil)
+
+	err = service.ResetPassword(ctx, "reset_token", "NewPass123!")
+
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrPasswordReused}, err)
+	mocks.passwordReset.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ResetPassword_RejectsCurrentPasswordWithoutHistory(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.passwordReset.On("Find", ctx, "reset_token").Return(uint64(123), nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{
+		ID:           123,
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}, nil)
+	mocks.roles.On("PasswordHistorySize", ctx, []model.Role{model.RoleUser}).Return(0, nil)
+
+	err := service.ResetPassword(ctx, "reset_token", "StrongPass123!")
+
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrPasswordReused}, err)
+	mocks.passwordHistory.AssertNotCalled(t, "FindRecent", mock.Anything, mock.Anything, mock.Anything)
+	mocks.passwordReset.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ResetPassword_WeakPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	err := service.ResetPassword(ctx, "reset_token", "weak")
+
+	var ve model.ValidationErrors
+	assert.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "password")
+	mocks.passwordReset.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ResetPassword_BreachedPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	err := service.ResetPassword(ctx, "reset_token", "Password1!")
+
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrBreachedPassword}, err)
+	mocks.passwordReset.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func changePasswordCtx() context.Context {
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	return context.WithValue(ctx, "sessionID", "session_123")
+}
+
+func TestAuthService_ChangePassword_KeepsCurrentSession(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	user := model.User{
+		ID:           123,
+		Email:        "test@example.com",
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(user, nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	withoutPasswordHistory(mocks)
+	mocks.repo.On("Update", ctx, mock.Anything, mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.PasswordHash != nil && security.CheckStringHash("NewPass123!", *u.PasswordHash) == nil
+	})).Return(nil)
+	mocks.revocations.On("RevokeUserTokens", ctx, uint64(123), mock.Anything).Return(nil)
+	mocks.sessions.On("DeleteAll", ctx, uint64(123)).Return(nil)
+	mocks.jwt.On("GenerateAccessToken", claimsForSession(123, "session_123")).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForSession(123, "session_123")).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionWithID(123, "session_123"), mock.Anything).Return(nil)
+	mocks.mailer.On("SendPasswordChangedNotification", ctx, "test@example.com").Return(nil)
+
+	token, err := service.ChangePassword(ctx, model.PasswordChangeData{
+		CurrentPassword:      "StrongPass123!",
+		Password:             "NewPass123!",
+		PasswordConfirmation: "NewPass123!",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token", token.AccessToken)
+	mocks.repo.AssertExpectations(t)
+	mocks.sessions.AssertExpectations(t)
+	mocks.mailer.AssertExpectations(t)
+}
+
+func TestAuthService_ChangePassword_WrongCurrentPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	user := model.User{
+		ID:           123,
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(user, nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("AddFailure", ctx, "account:123").Return(int64(1), nil)
+
+	_, err := service.ChangePassword(ctx, model.PasswordChangeData{
+		CurrentPassword:      "WrongPass123!",
+		Password:             "NewPass123!",
+		PasswordConfirmation: "NewPass123!",
+	})
+
+	assert.Equal(t, model.ValidationErrors{"currentPassword": model.ErrPasswordsDoNotMatch}, err)
+	mocks.loginAttempts.AssertExpectations(t)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ChangePassword_RejectsRecentPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	user := model.User{
+		ID:           123,
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleAdmin},
+	}
+	recentHash, err := testPasswordHasher.Hash("NewPass123!")
+	assert.NoError(t, err)
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(user, nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil).Maybe()
+	mocks.roles.On("PasswordHistorySize", ctx, []model.Role{model.RoleAdmin}).Return(5, nil)
+	mocks.passwordHistory.On("FindRecent", ctx, uint64(123), 5).Return([][]byte{recentHash}, nil)
+
+	_, err = service.ChangePassword(ctx, model.PasswordChangeData{
+		CurrentPassword:      "StrongPass123!",
+		Password:             "NewPass123!",
+		PasswordConfirmation: "NewPass123!",
+	})
+
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrPasswordReused}, err)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ChangePassword_RejectsCurrentPasswordReuse(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	_, err := service.ChangePassword(ctx, model.PasswordChangeData{
+		CurrentPassword:      "StrongPass123!",
+		Password:             "StrongPass123!",
+		PasswordConfirmation: "StrongPass123!",
+	})
+
+	var ve model.ValidationErrors
+	assert.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "password")
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_UpgradesOutdatedPasswordHash(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	argon2idHasher, err := password.NewHasher(password.Config{
+		Algorithm:         password.AlgorithmArgon2id,
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	})
+	assert.NoError(t, err)
+	service.userService.passwordHasher = argon2idHasher
+
+	bcryptHash := []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG")
+	user := model.User{
+		ID:           123,
+		Email:        "test@example.com",
+		PasswordHash: bcryptHash,
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	mocks.loginAttempts.On("LockedFor", ctx, mock.Anything).Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("Reset", ctx, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	mocks.repo.On("FindOne", ctx, emailFilter("test@example.com")).Return(user, nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.AnythingOfType("string")).Return(nil)
+
+	var upgradedHash []byte
+	mocks.repo.On(
+		"Update",
+		ctx,
+		mock.MatchedBy(func(f model.UserFilter) bool {
+			return f.ID != nil && *f.ID == 123 && f.PasswordHash != nil && string(*f.PasswordHash) == string(bcryptHash)
+		}),
+		mock.MatchedBy(func(u model.UserUpdate) bool {
+			return u.PasswordHash != nil
+		}),
+	).Run(func(args mock.Arguments) {
+		upgradedHash = *args.Get(2).(model.UserUpdate).PasswordHash
+	}).Return(nil)
+
+	_, err = service.Login(ctx, model.Credentials{Email: "test@example.com", Password: "StrongPass123!"})
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+	assert.NoError(t, argon2idHasher.Verify("StrongPass123!", upgradedHash))
+	assert.False(t, argon2idHasher.NeedsRehash(upgradedHash))
 }
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"time"
+)
+
+const (
+	ipv4RangeBits = 24
+	ipv6RangeBits = 48
+)
+
+// ListKnownDevices returns the devices the authenticated user has logged in on, most recently seen first
+func (s *AuthService) ListKnownDevices(ctx context.Context) ([]model.KnownDevice, error) {
+	userID, err := userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := s.knownDeviceRepo.Find(ctx, userID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"sql: finding known devices",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// RemoveKnownDevice forgets a device of the authenticated user,
+// the next login on it is alerted about again
+func (s *AuthService) RemoveKnownDevice(ctx context.Context, deviceID uint64) error {
+	userID, err := userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validateInput(s.validate, knownDeviceIDValidation{DeviceID: deviceID})
+	if err != nil {
+		return err
+	}
+
+	err = s.knownDeviceRepo.Delete(ctx, userID, deviceID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrNotFound
+		}
+		s.log.ErrorContext(
+			ctx,
+			"sql: deleting known device",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+			slog.Uint64("deviceId", deviceID),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// ReportUnrecognizedLogin handles the "this wasn't me" link of a new device alert.
+// The reported session is ended, all tokens of the user are revoked, the user has to
+// change the password on the next login and is emailed a password reset token
+func (s *AuthService) ReportUnrecognizedLogin(ctx context.Context, token string) error {
+	err := validateInput(s.validate, loginAlertTokenValidation{Token: token})
+	if err != nil {
+		return err
+	}
+
+	claims, err := s.jwtProvider.VerifyAndParseClaims(token)
+	if err != nil || claims.Type != model.TokenTypeLoginAlert {
+		return model.ErrInvalidToken
+	}
+
+	revoked, err := s.tokenRevocationStorage.IsTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: checking login alert token",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return err
+	}
+	if revoked {
+		return model.ErrInvalidToken
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: &claims.UserID})
+	if err != nil {
+		return err
+	}
+
+	err = s.tokenRevocationStorage.RevokeToken(ctx, claims.TokenID, claims.ExpiresAt)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: revoking login alert token",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return err
+	}
+
+	// The session may already be over, it is revoked either way
+	err = s.sessionStorage.Delete(ctx, user.ID, claims.SessionID)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		s.log.ErrorContext(
+			ctx,
+			"token storage: deleting session",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+			slog.String("sessionId", claims.SessionID),
+		)
+
+		return err
+	}
+
+	err = s.tokenRevocationStorage.RevokeSession(ctx, claims.SessionID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: revoking session",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+			slog.String("sessionId", claims.SessionID),
+		)
+
+		return err
+	}
+
+	// Whoever logged in knows the password, so it stops working instead of having to be changed
+	err = s.userService.discardPassword(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.sendPasswordReset(ctx, user)
+}
+
+// recordLoginDevice remembers the device a login of the user completed on and emails
+// the user an alert when the device or its IP range was not seen before.
+// The first device of a user is not alerted about. Failures are logged, the login is already done
+func (s *AuthService) recordLoginDevice(ctx context.Context, user model.User, sessionID string) {
+	now := time.Now()
+	device := model.KnownDevice{
+		UserID:      user.ID,
+		Fingerprint: deviceFingerprint(userAgentFromCtx(ctx), deviceNameFromCtx(ctx)),
+		DeviceName:  deviceNameFromCtx(ctx),
+		UserAgent:   userAgentFromCtx(ctx),
+		IPRange:     ipRange(clientIPFromCtx(ctx)),
+		CreatedAt:   now,
+		LastSeenAt:  now,
+	}
+
+	devices, err := s.knownDeviceRepo.Find(ctx, user.ID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"sql: finding known devices",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return
+	}
+
+	var known *model.KnownDevice
+	for i := range devices {
+		if bytes.Equal(devices[i].Fingerprint, device.Fingerprint) {
+			known = &devices[i]
+
+			break
+		}
+	}
+
+	alert := false
+	if known == nil {
+		_, err = s.knownDeviceRepo.Insert(ctx, device)
+		alert = len(devices) > 0
+	} else {
+		err = s.knownDeviceRepo.UpdateLastSeen(ctx, known.ID, device.IPRange, now)
+		alert = device.IPRange != "" && device.IPRange != known.IPRange
+	}
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"sql: saving known device",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return
+	}
+
+	if alert {
+		s.sendNewDeviceAlert(ctx, user, device, sessionID)
+	}
+}
+
+func (s *AuthService) sendNewDeviceAlert(ctx context.Context, user model.User, device model.KnownDevice, sessionID string) {
+	alertToken, _, err := s.jwtProvider.GenerateLoginAlertToken(model.TokenClaims{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenID:   security.RandomString(tokenIDLength),
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating login alert token",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return
+	}
+
+	link := s.deviceAlert.ReportURL + "?token=" + url.QueryEscape(alertToken)
+
+	err = s.mailer.SendNewDeviceAlert(ctx, user.Email, device, link)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"mailer: sending new device alert",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+	}
+}
+
+// deviceFingerprint identifies a device by what its client tells about it
+func deviceFingerprint(userAgent, deviceName string) []byte {
+	return security.SHA256(userAgent + "\n" + deviceName)
+}
+
+// ipRange returns the network a client IP belongs to, a /24 for IPv4 and a /48 for IPv6.
+// Logins from the same network are not alerted about when the address changes
+func ipRange(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+
+	bits := ipv6RangeBits
+	if addr.Is4In6() {
+		addr = addr.Unmap()
+	}
+	if addr.Is4() {
+		bits = ipv4RangeBits
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+
+	return prefix.String()
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func deviceCtx(userAgent, clientIP string) context.Context {
+	ctx := context.WithValue(context.Background(), "user-agent", userAgent)
+	ctx = context.WithValue(ctx, "device-name", "Pixel 8")
+
+	return context.WithValue(ctx, "client-ip", clientIP)
+}
+
+func knownDevice(userAgent, ipRange string) model.KnownDevice {
+	return model.KnownDevice{
+		ID:          7,
+		UserID:      123,
+		Fingerprint: deviceFingerprint(userAgent, "Pixel 8"),
+		DeviceName:  "Pixel 8",
+		UserAgent:   userAgent,
+		IPRange:     ipRange,
+	}
+}
+
+// withKnownDevices gives the user the devices. The devices mock of newAuthServiceWithMocks
+// treats every login as the first one, it is replaced so tests can expect calls on it
+func withKnownDevices(service *AuthService, mocks *authServiceMocks, devices []model.KnownDevice) {
+	mocks.knownDevices = new(MockKnownDeviceRepository)
+	service.knownDeviceRepo = mocks.knownDevices
+	mocks.knownDevices.On("Find", mock.Anything, uint64(123)).Return(devices, nil)
+}
+
+func loginCodeUserWithPassword() model.User {
+	user := loginCodeUser()
+	user.PasswordHash = []byte(testPasswordHash)
+
+	return user
+}
+
+func testCredentials() model.Credentials {
+	return model.Credentials{Email: "test@example.com", Password: "StrongPass123!"}
+}
+
+func TestAuthService_Login_UnknownDeviceSendsAlert(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withKnownDevices(service, &mocks, []model.KnownDevice{knownDevice("Firefox", "203.0.113.0/24")})
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(loginCodeUserWithPassword(), nil)
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", mock.Anything, sessionOf(123), mock.Anything).Return(nil)
+	ctx := deviceCtx("Chrome", "198.51.100.23")
+
+	mocks.knownDevices.On("Insert", ctx, mock.MatchedBy(func(d model.KnownDevice) bool {
+		return d.UserID == 123 && d.UserAgent == "Chrome" && d.IPRange == "198.51.100.0/24"
+	})).Return(uint64(8), nil)
+	mocks.jwt.On("GenerateLoginAlertToken", mock.MatchedBy(func(c model.TokenClaims) bool {
+		return c.UserID == 123 && c.SessionID != "" && c.TokenID != ""
+	})).Return("alert.token.value", nil)
+	mocks.mailer.On("SendNewDeviceAlert", ctx, "test@example.com", mock.Anything, mock.MatchedBy(func(link string) bool {
+		return strings.HasPrefix(link, "https://rental.example.com/not-me?token=")
+	})).Return(nil)
+
+	result, err := service.Login(ctx, testCredentials())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token", result.Token.AccessToken)
+	mocks.knownDevices.AssertExpectations(t)
+	mocks.mailer.AssertExpectations(t)
+}
+
+func TestAuthService_Login_KnownDeviceNoAlert(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withKnownDevices(service, &mocks, []model.KnownDevice{knownDevice("Chrome", "198.51.100.0/24")})
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(loginCodeUserWithPassword(), nil)
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", mock.Anything, sessionOf(123), mock.Anything).Return(nil)
+	ctx := deviceCtx("Chrome", "198.51.100.99")
+
+	mocks.knownDevices.On("UpdateLastSeen", ctx, uint64(7), "198.51.100.0/24", mock.Anything).Return(nil)
+
+	_, err := service.Login(ctx, testCredentials())
+
+	assert.NoError(t, err)
+	mocks.knownDevices.AssertExpectations(t)
+	mocks.mailer.AssertNotCalled(t, "SendNewDeviceAlert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_KnownDeviceNewIPRangeSendsAlert(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withKnownDevices(service, &mocks, []model.KnownDevice{knownDevice("Chrome", "198.51.100.0/24")})
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(loginCodeUserWithPassword(), nil)
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", mock.Anything, sessionOf(123), mock.Anything).Return(nil)
+	ctx := deviceCtx("Chrome", "203.0.113.5")
+
+	mocks.knownDevices.On("UpdateLastSeen", ctx, uint64(7), "203.0.113.0/24", mock.Anything).Return(nil)
+	mocks.jwt.On("GenerateLoginAlertToken", mock.Anything).Return("alert.token.value", nil)
+	mocks.mailer.On("SendNewDeviceAlert", ctx, "test@example.com", mock.Anything, mock.Anything).Return(nil)
+
+	_, err := service.Login(ctx, testCredentials())
+
+	assert.NoError(t, err)
+	mocks.mailer.AssertExpectations(t)
+}
+
+func TestAuthService_Login_FirstDeviceNoAlert(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withKnownDevices(service, &mocks, nil)
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(loginCodeUserWithPassword(), nil)
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", mock.Anything, sessionOf(123), mock.Anything).Return(nil)
+	ctx := deviceCtx("Chrome", "198.51.100.23")
+
+	mocks.knownDevices.On("Insert", ctx, mock.Anything).Return(uint64(1), nil)
+
+	_, err := service.Login(ctx, testCredentials())
+
+	assert.NoError(t, err)
+	mocks.knownDevices.AssertExpectations(t)
+	mocks.mailer.AssertNotCalled(t, "SendNewDeviceAlert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ReportUnrecognizedLogin(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	claims := model.TokenClaims{
+		UserID:    123,
+		SessionID: "session_123",
+		TokenID:   "alert_123",
+		Type:      model.TokenTypeLoginAlert,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mocks.jwt.On("VerifyAndParseClaims", "alert.token.value").Return(claims, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "alert_123").Return(false, nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(loginCodeUser(), nil)
+	mocks.revocations.On("RevokeToken", ctx, "alert_123", claims.ExpiresAt).Return(nil)
+	mocks.sessions.On("Delete", ctx, uint64(123), "session_123").Return(nil)
+	mocks.revocations.On("RevokeSession", ctx, "session_123").Return(nil)
+	mocks.repo.On("Update", ctx, idFilter(123), mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.PasswordHash != nil && len(*u.PasswordHash) == 0 &&
+			u.MustChangePassword != nil && *u.MustChangePassword
+	})).Return(nil)
+	mocks.revocations.On("RevokeUserTokens", ctx, uint64(123), mock.Anything).Return(nil)
+	mocks.passwordReset.On("Save", ctx, uint64(123)).Return("reset_token", nil)
+	mocks.mailer.On("SendPasswordResetToken", ctx, "test@example.com", "reset_token").Return(nil)
+
+	err := service.ReportUnrecognizedLogin(ctx, "alert.token.value")
+
+	assert.NoError(t, err)
+	mocks.revocations.AssertExpectations(t)
+	mocks.repo.AssertExpectations(t)
+	mocks.mailer.AssertExpectations(t)
+}
+
+func TestAuthService_ReportUnrecognizedLogin_OldPasswordStopsWorking(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	claims := model.TokenClaims{
+		UserID:    123,
+		SessionID: "session_456",
+		TokenID:   "alert_123",
+		Type:      model.TokenTypeLoginAlert,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	user := loginCodeUserWithPassword()
+
+	mocks.jwt.On("VerifyAndParseClaims", "alert.token.value").Return(claims, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "alert_123").Return(false, nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(user, nil).Once()
+	mocks.revocations.On("RevokeToken", ctx, "alert_123", claims.ExpiresAt).Return(nil)
+	mocks.sessions.On("Delete", ctx, uint64(123), "session_456").Return(nil)
+	mocks.revocations.On("RevokeSession", ctx, "session_456").Return(nil)
+	mocks.repo.On("Update", ctx, idFilter(123), mock.Anything).Run(func(args mock.Arguments) {
+		update := args.Get(2).(model.UserUpdate)
+		user.PasswordHash = *update.PasswordHash
+		user.MustChangePassword = *update.MustChangePassword
+	}).Return(nil)
+	mocks.revocations.On("RevokeUserTokens", ctx, uint64(123), mock.Anything).Return(nil)
+	mocks.passwordReset.On("Save", ctx, uint64(123)).Return("reset_token", nil)
+	mocks.mailer.On("SendPasswordResetToken", ctx, "test@example.com", "reset_token").Return(nil)
+
+	err := service.ReportUnrecognizedLogin(ctx, "alert.token.value")
+	assert.NoError(t, err)
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(user, nil)
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("AddFailure", ctx, "account:123").Return(int64(1), nil)
+
+	_, err = service.ChangePassword(ctx, model.PasswordChangeData{
+		CurrentPassword:      "StrongPass123!",
+		Password:             "NewPass123!",
+		PasswordConfirmation: "NewPass123!",
+	})
+
+	assert.Equal(t, model.ValidationErrors{"currentPassword": model.ErrPasswordsDoNotMatch}, err)
+	mocks.repo.AssertNumberOfCalls(t, "Update", 1)
+}
+
+func TestAuthService_ReportUnrecognizedLogin_RejectsOtherTokens(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+
+	mocks.jwt.On("VerifyAndParseClaims", "access.token.value").Return(model.TokenClaims{
+		UserID: 123,
+		Type:   model.TokenTypeAccess,
+	}, nil)
+
+	err := service.ReportUnrecognizedLogin(context.Background(), "access.token.value")
+
+	assert.ErrorIs(t, err, model.ErrInvalidToken)
+	mocks.sessions.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RemoveKnownDevice(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	mocks.knownDevices.On("Delete", ctx, uint64(123), uint64(7)).Return(nil)
+
+	err := service.RemoveKnownDevice(ctx, 7)
+
+	assert.NoError(t, err)
+	mocks.knownDevices.AssertExpectations(t)
+}
+
+func TestIPRange(t *testing.T) {
+	assert.Equal(t, "198.51.100.0/24", ipRange("198.51.100.23"))
+	assert.Equal(t, "198.51.100.0/24", ipRange("::ffff:198.51.100.23"))
+	assert.Equal(t, "2001:db8:1::/48", ipRange("2001:db8:1:2::1"))
+	assert.Equal(t, "", ipRange("unknown"))
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// maxEmailCodeAttempts is how many wrong codes drop a pending email change
+const maxEmailCodeAttempts = 5
+
+// RequestEmailChange stages a new email of the authenticated user. A confirmation code
+// is sent to the new address and the current one is told how to cancel the change.
+// The current email stays in use until the change is confirmed
+func (s *UserService) RequestEmailChange(ctx context.Context, email string) error {
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validateInput(s.validate, emailChangeValidation{Email: email})
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindOne(ctx, model.UserFilter{ID: &id})
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(email, user.Email) {
+		return model.ValidationErrors{
+			"email": model.ErrSameEmail,
+		}
+	}
+
+	_, err = s.userRepo.FindOne(ctx, model.UserFilter{Email: &email})
+	if err == nil {
+		return model.ValidationErrors{
+			"email": model.ErrDuplicateEmail,
+		}
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+
+	code, cancelToken, err := s.emailChangeStorage.Save(ctx, id, email)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"email change storage: saving pending email",
+			logger.Err(err),
+			slog.Uint64("userId", id),
+		)
+
+		return err
+	}
+
+	err = s.mailer.SendEmailChangeCode(ctx, email, code)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"mailer: sending email change code",
+			logger.Err(err),
+			slog.Uint64("userId", id),
+		)
+
+		return err
+	}
+
+	err = s.mailer.SendEmailChangeNotice(ctx, user.Email, email, cancelToken)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"mailer: sending email change notice",
+			logger.Err(err),
+			slog.Uint64("userId", id),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange switches the authenticated user to the pending email
+func (s *UserService) ConfirmEmailChange(ctx context.Context, code string) error {
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validateInput(s.validate, emailChangeCodeValidation{Code: code})
+	if err != nil {
+		return err
+	}
+
+	pending, err := s.emailChangeStorage.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrNoPendingEmailChange
+		}
+
+		return err
+	}
+
+	err = security.CheckStringHash(code, pending.CodeHash)
+	if err != nil {
+		err = s.addEmailChangeFailure(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return model.ValidationErrors{
+			"code": model.ErrInvalidEmailCode,
+		}
+	}
+
+	err = s.userRepo.Update(ctx, model.UserFilter{ID: &id}, model.UserUpdate{
+		Email:     &pending.Email,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrDuplicateEmail) {
+			return model.ValidationErrors{
+				"email": model.ErrDuplicateEmail,
+			}
+		}
+
+		return err
+	}
+
+	err = s.emailChangeStorage.Delete(ctx, id)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"email change storage: deleting pending email",
+			logger.Err(err),
+			slog.Uint64("userId", id),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// CancelEmailChange drops a pending email change with the token sent to the current address
+func (s *UserService) CancelEmailChange(ctx context.Context, cancelToken string) error {
+	err := validateInput(s.validate, emailChangeCancelValidation{CancelToken: cancelToken})
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.emailChangeStorage.Cancel(ctx, cancelToken)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrNoPendingEmailChange
+		}
+
+		s.log.ErrorContext(ctx, "email change storage: canceling pending email", logger.Err(err))
+
+		return err
+	}
+
+	s.log.InfoContext(ctx, "email change canceled", slog.Uint64("userId", userID))
+
+	return nil
+}
+
+// addEmailChangeFailure counts a wrong code and drops the pending change
+// once its code was guessed wrong too many times
+func (s *UserService) addEmailChangeFailure(ctx context.Context, userID uint64) error {
+	failures, err := s.emailChangeStorage.AddFailure(ctx, userID)
+	if err != nil {
+		// The change expired in the meantime
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+
+		s.log.ErrorContext(
+			ctx,
+			"email change storage: adding failure",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	if failures < maxEmailCodeAttempts {
+		return nil
+	}
+
+	err = s.emailChangeStorage.Delete(ctx, userID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"email change storage: deleting pending email",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type MockEmailChangeStorage struct {
+	mock.Mock
+}
+
+func (m *MockEmailChangeStorage) Save(ctx context.Context, userID uint64, email string) (string, string, error) {
+	args := m.Called(ctx, userID, email)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockEmailChangeStorage) Get(ctx context.Context, userID uint64) (model.PendingEmailChange, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.PendingEmailChange), args.Error(1)
+}
+
+func (m *MockEmailChangeStorage) Delete(ctx context.Context, userID uint64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockEmailChangeStorage) Cancel(ctx context.Context, cancelToken string) (uint64, error) {
+	args := m.Called(ctx, cancelToken)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockEmailChangeStorage) AddFailure(ctx context.Context, userID uint64) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func emailFilter(email string) any {
+	return mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.Email != nil && *f.Email == email
+	})
+}
+
+func idFilter(id uint64) any {
+	return mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.ID != nil && *f.ID == id
+	})
+}
+
+func TestUserService_RequestEmailChange(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	user := model.User{ID: 123, Email: "old@example.com"}
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(user, nil)
+	mocks.repo.On("FindOne", ctx, emailFilter("new@example.com")).Return(model.User{}, model.ErrNotFound)
+	mocks.emailChange.On("Save", ctx, uint64(123), "new@example.com").Return("ABC123", "cancel_token", nil)
+	mocks.mailer.On("SendEmailChangeCode", ctx, "new@example.com", "ABC123").Return(nil)
+	mocks.mailer.On("SendEmailChangeNotice", ctx, "old@example.com", "new@example.com", "cancel_token").Return(nil)
+
+	err := service.userService.RequestEmailChange(ctx, "new@example.com")
+
+	assert.NoError(t, err)
+	mocks.mailer.AssertExpectations(t)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_RequestEmailChange_TakenEmail(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{ID: 123, Email: "old@example.com"}, nil)
+	mocks.repo.On("FindOne", ctx, emailFilter("taken@example.com")).Return(model.User{ID: 456}, nil)
+
+	err := service.userService.RequestEmailChange(ctx, "taken@example.com")
+
+	assert.Equal(t, model.ValidationErrors{"email": model.ErrDuplicateEmail}, err)
+	mocks.emailChange.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_ConfirmEmailChange(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	codeHash, err := security.HashString("ABC123")
+	require.NoError(t, err)
+
+	mocks.emailChange.On("Get", ctx, uint64(123)).Return(model.PendingEmailChange{
+		UserID:   123,
+		Email:    "new@example.com",
+		CodeHash: codeHash,
+	}, nil)
+	mocks.repo.On("Update", ctx, idFilter(123), mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.Email != nil && *u.Email == "new@example.com"
+	})).Return(nil)
+	mocks.emailChange.On("Delete", ctx, uint64(123)).Return(nil)
+
+	err = service.userService.ConfirmEmailChange(ctx, "ABC123")
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+	mocks.emailChange.AssertExpectations(t)
+}
+
+func TestUserService_ConfirmEmailChange_InvalidCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	codeHash, err := security.HashString("ABC123")
+	require.NoError(t, err)
+
+	mocks.emailChange.On("Get", ctx, uint64(123)).Return(model.PendingEmailChange{
+		UserID:   123,
+		Email:    "new@example.com",
+		CodeHash: codeHash,
+	}, nil)
+	mocks.emailChange.On("AddFailure", ctx, uint64(123)).Return(int64(1), nil)
+
+	err = service.userService.ConfirmEmailChange(ctx, "XYZ789")
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidEmailCode}, err)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	mocks.emailChange.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestUserService_ConfirmEmailChange_TooManyAttemptsDropsChange(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	codeHash, err := security.HashString("ABC123")
+	require.NoError(t, err)
+
+	mocks.emailChange.On("Get", ctx, uint64(123)).Return(model.PendingEmailChange{
+		UserID:   123,
+		Email:    "new@example.com",
+		CodeHash: codeHash,
+	}, nil)
+	mocks.emailChange.On("AddFailure", ctx, uint64(123)).Return(int64(maxEmailCodeAttempts), nil)
+	mocks.emailChange.On("Delete", ctx, uint64(123)).Return(nil)
+
+	err = service.userService.ConfirmEmailChange(ctx, "XYZ789")
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidEmailCode}, err)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	mocks.emailChange.AssertExpectations(t)
+}
+
+func TestUserService_Update_RejectsOwnEmail(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	newEmail := "new@example.com"
+	id := uint64(123)
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{ID: 123, Email: "old@example.com"}, nil)
+
+	err := service.userService.Update(ctx, model.UserFilter{ID: &id}, model.UserUpdateData{Email: &newEmail})
+
+	assert.Equal(t, model.ValidationErrors{"email": model.ErrOwnEmailUpdate}, err)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
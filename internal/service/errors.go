@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"strings"
 )
 
 func validationError(fieldErr validator.FieldError) error {
@@ -15,11 +16,27 @@ func validationError(fieldErr validator.FieldError) error {
 		param := uncapitalize(fieldErr.Param())
 
 		return fmt.Errorf("either %s is required or %s", field, param)
+	case "required_without_all":
+		field := uncapitalize(fieldErr.Field())
+		params := strings.Fields(fieldErr.Param())
+		for i, param := range params {
+			params[i] = uncapitalize(param)
+		}
+
+		return fmt.Errorf("either %s is required or one of %s", field, strings.Join(params, ", "))
 	case "required_with":
 		field := uncapitalize(fieldErr.Field())
 		param := uncapitalize(fieldErr.Param())
 
 		return fmt.Errorf("%s required with %s", field, param)
+	case "excluded_with":
+		param := uncapitalize(fieldErr.Param())
+
+		return fmt.Errorf("can not be set together with %s", param)
+	case "nefield":
+		param := uncapitalize(fieldErr.Param())
+
+		return fmt.Errorf("must be different from %s", param)
 	case "eqfield":
 		param := uncapitalize(fieldErr.Param())
 
@@ -36,6 +53,12 @@ func validationError(fieldErr validator.FieldError) error {
 		return fmt.Errorf("must be at most %s characters", fieldErr.Param())
 	case "min":
 		return fmt.Errorf("must be at least %s characters", fieldErr.Param())
+	case "gte":
+		return fmt.Errorf("must be at least %s", fieldErr.Param())
+	case "lte":
+		return fmt.Errorf("must be at most %s", fieldErr.Param())
+	case "startswith":
+		return fmt.Errorf("must start with %s", fieldErr.Param())
 	case "email":
 		return model.ErrInvalidEmail
 	case "e164":
@@ -44,6 +67,8 @@ func validationError(fieldErr validator.FieldError) error {
 		return model.ErrInvalidJwtToken
 	case "complex_password":
 		return model.ErrNotComplexPassword
+	case "not_breached":
+		return model.ErrBreachedPassword
 	case "min_age":
 		return fmt.Errorf("must be at least %s years", fieldErr.Param())
 	default:
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	validatecfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/validate"
 	"strings"
 )
 
@@ -22,9 +23,15 @@ func toRoleStrings(roles []model.Role) []string {
 func formatFilter(filter *model.UserFilter) {
 	if filter.ID != nil && *filter.ID > 0 {
 		filter.Email = nil
+		filter.PhoneNumber = nil
 	}
 	if filter.Email != nil && *filter.Email != "" {
 		filter.ID = nil
+		filter.PhoneNumber = nil
+	}
+	if filter.PhoneNumber != nil && *filter.PhoneNumber != "" {
+		normalized := validatecfg.NormalizePhoneNumber(*filter.PhoneNumber)
+		filter.PhoneNumber = &normalized
 	}
 }
 
@@ -36,3 +43,43 @@ func userIDFromCtx(ctx context.Context) (uint64, error) {
 
 	return id, nil
 }
+
+func sessionIDFromCtx(ctx context.Context) (string, error) {
+	id, ok := ctx.Value("sessionID").(string)
+	if !ok {
+		return id, model.ErrInvalidToken
+	}
+
+	return id, nil
+}
+
+// actorIDFromCtx returns the support agent impersonating the user of the request, or 0
+func actorIDFromCtx(ctx context.Context) uint64 {
+	id, _ := ctx.Value("actorID").(uint64)
+
+	return id
+}
+
+func requestIDFromCtx(ctx context.Context) string {
+	requestID, _ := ctx.Value("request-id").(string)
+
+	return requestID
+}
+
+func clientIPFromCtx(ctx context.Context) string {
+	ip, _ := ctx.Value("client-ip").(string)
+
+	return ip
+}
+
+func userAgentFromCtx(ctx context.Context) string {
+	userAgent, _ := ctx.Value("user-agent").(string)
+
+	return userAgent
+}
+
+func deviceNameFromCtx(ctx context.Context) string {
+	deviceName, _ := ctx.Value("device-name").(string)
+
+	return deviceName
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"time"
+)
+
+// Impersonate issues the authenticated support agent a short-lived access token of a customer,
+// so that they can reproduce problems of the customer. The token names the agent in its act claim.
+// Only customers can be impersonated, and no refresh token is issued
+func (s *AuthService) Impersonate(ctx context.Context, userID uint64, reason string) (model.Token, error) {
+	actorID, err := userIDFromCtx(ctx)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	if actorIDFromCtx(ctx) != 0 {
+		return model.Token{}, model.ErrImpersonating
+	}
+
+	err = validateInput(s.validate, impersonationValidation{UserID: userID, Reason: reason})
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	if userID == actorID {
+		return model.Token{}, model.ErrImpersonationNotAllowed
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: &userID})
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	for _, role := range user.Roles {
+		if role != model.RoleUser {
+			return model.Token{}, model.ErrImpersonationNotAllowed
+		}
+	}
+
+	err = s.auditRepo.Insert(ctx, model.AuditEvent{
+		ActorID:   &actorID,
+		UserID:    &userID,
+		Action:    model.AuditActionImpersonationStarted,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"sql: inserting audit event",
+			logger.Err(err),
+			slog.Uint64("actorId", actorID),
+			slog.Uint64("userId", userID),
+		)
+
+		return model.Token{}, err
+	}
+
+	accessToken, accessTokenExp, err := s.jwtProvider.GenerateImpersonationToken(model.TokenClaims{
+		UserID:    userID,
+		ActorID:   actorID,
+		Roles:     toRoleStrings(user.Roles),
+		SessionID: security.RandomString(sessionIDLength),
+		TokenID:   security.RandomString(tokenIDLength),
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating impersonation token",
+			logger.Err(err),
+			slog.Uint64("actorId", actorID),
+			slog.Uint64("userId", userID),
+		)
+
+		return model.Token{}, model.ErrJwt
+	}
+
+	s.log.InfoContext(
+		ctx,
+		"impersonation started",
+		slog.Uint64("actorId", actorID),
+		slog.Uint64("userId", userID),
+		slog.String("reason", reason),
+	)
+
+	return model.Token{
+		AccessToken:          accessToken,
+		AccessTokenExpiresIn: int64(time.Until(accessTokenExp).Seconds()),
+	}, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Insert(ctx context.Context, event model.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+const impersonationReason = "customer can not finish booking"
+
+func TestAuthService_Impersonate(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(7))
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{ID: 123, Roles: []model.Role{model.RoleUser}}, nil)
+	mocks.audit.On("Insert", ctx, mock.MatchedBy(func(e model.AuditEvent) bool {
+		return *e.ActorID == 7 && *e.UserID == 123 &&
+			e.Action == model.AuditActionImpersonationStarted &&
+			e.Reason == impersonationReason
+	})).Return(nil)
+	mocks.jwt.On("GenerateImpersonationToken", mock.MatchedBy(func(c model.TokenClaims) bool {
+		return c.UserID == 123 && c.ActorID == 7 && c.TokenID != "" && c.SessionID != ""
+	})).Return("impersonation_token", nil)
+
+	token, err := service.Impersonate(ctx, 123, impersonationReason)
+
+	require.NoError(t, err)
+	assert.Equal(t, "impersonation_token", token.AccessToken)
+	assert.Empty(t, token.RefreshToken)
+	mocks.audit.AssertExpectations(t)
+}
+
+func TestAuthService_Impersonate_StaffAccount(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(7))
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{
+		ID:    123,
+		Roles: []model.Role{model.RoleUser, model.RoleAdmin},
+	}, nil)
+
+	_, err := service.Impersonate(ctx, 123, impersonationReason)
+
+	assert.ErrorIs(t, err, model.ErrImpersonationNotAllowed)
+	mocks.jwt.AssertNotCalled(t, "GenerateImpersonationToken", mock.Anything)
+}
+
+func TestAuthService_Impersonate_Self(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(7))
+
+	_, err := service.Impersonate(ctx, 7, impersonationReason)
+
+	assert.ErrorIs(t, err, model.ErrImpersonationNotAllowed)
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Impersonate_WhileImpersonating(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+	ctx = context.WithValue(ctx, "actorID", uint64(7))
+
+	_, err := service.Impersonate(ctx, 456, impersonationReason)
+
+	assert.ErrorIs(t, err, model.ErrImpersonating)
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Impersonate_RequiresReason(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(7))
+
+	_, err := service.Impersonate(ctx, 123, "")
+
+	var ve model.ValidationErrors
+	require.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "reason")
+	mocks.audit.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Impersonate_AuditFailure(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(7))
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{ID: 123, Roles: []model.Role{model.RoleUser}}, nil)
+	mocks.audit.On("Insert", ctx, mock.Anything).Return(model.ErrSql)
+
+	_, err := service.Impersonate(ctx, 123, impersonationReason)
+
+	assert.ErrorIs(t, err, model.ErrSql)
+	mocks.jwt.AssertNotCalled(t, "GenerateImpersonationToken", mock.Anything)
+}
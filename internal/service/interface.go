@@ -15,15 +15,113 @@ type UserRepository interface {
 }
 
 type JwtProvider interface {
-	GenerateAccessToken(id uint64, roles []string) (string, time.Time, error)
-	GenerateRefreshToken(id uint64, roles []string) (string, time.Time, error)
-	VerifyAndParseClaims(token string) (uint64, []string, error)
+	GenerateAccessToken(claims model.TokenClaims) (string, time.Time, error)
+	GenerateRefreshToken(claims model.TokenClaims) (string, time.Time, error)
+	GenerateMFAToken(claims model.TokenClaims) (string, time.Time, error)
+	GenerateImpersonationToken(claims model.TokenClaims) (string, time.Time, error)
+	GeneratePasswordChangeToken(claims model.TokenClaims) (string, time.Time, error)
+	GenerateLoginLinkToken(claims model.TokenClaims) (string, time.Time, error)
+	GenerateElevatedToken(claims model.TokenClaims) (string, time.Time, error)
+	GenerateLoginAlertToken(claims model.TokenClaims) (string, time.Time, error)
+	VerifyAndParseClaims(token string) (model.TokenClaims, error)
+	JWKS() []model.JSONWebKey
+}
+
+type MFARepository interface {
+	FindTOTP(ctx context.Context, userID uint64) (model.TOTP, error)
+	SaveTOTP(ctx context.Context, totp model.TOTP) error
+	ConfirmTOTP(ctx context.Context, userID uint64, recoveryCodeHashes [][]byte, confirmedAt time.Time) error
+	UseTOTPStep(ctx context.Context, userID uint64, step int64) error
+	UseRecoveryCode(ctx context.Context, userID uint64, codeHash []byte) error
+}
+
+type RoleRepository interface {
+	IsMFARequired(ctx context.Context, roles []model.Role) (bool, error)
+	SetMFARequired(ctx context.Context, role model.Role, required bool) error
+	PasswordHistorySize(ctx context.Context, roles []model.Role) (int, error)
+	SetPasswordHistorySize(ctx context.Context, role model.Role, size int) error
+	PasswordMaxAge(ctx context.Context, roles []model.Role) (int, error)
+	SetPasswordMaxAge(ctx context.Context, role model.Role, days int) error
+}
+
+type RolePermissionRepository interface {
+	Create(ctx context.Context, role model.Role) error
+	GrantPermission(ctx context.Context, role model.Role, permission model.Permission) error
+	RevokePermission(ctx context.Context, role model.Role, permission model.Permission) error
+}
+
+// PermissionVersionStorage tells every instance that the roles or their permissions changed
+type PermissionVersionStorage interface {
+	Bump(ctx context.Context) error
+}
+
+type PasswordHistoryRepository interface {
+	Insert(ctx context.Context, userID uint64, passwordHash []byte, createdAt time.Time) error
+	FindRecent(ctx context.Context, userID uint64, limit int) ([][]byte, error)
+}
+
+type KnownDeviceRepository interface {
+	Insert(ctx context.Context, device model.KnownDevice) (uint64, error)
+	Find(ctx context.Context, userID uint64) ([]model.KnownDevice, error)
+	UpdateLastSeen(ctx context.Context, id uint64, ipRange string, lastSeenAt time.Time) error
+	Delete(ctx context.Context, userID, id uint64) error
+}
+
+type LoginEventRepository interface {
+	Insert(ctx context.Context, event model.LoginEvent) error
+	Find(ctx context.Context, userID, beforeID uint64, limit int) ([]model.LoginEvent, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type ServiceClientRepository interface {
+	Insert(ctx context.Context, client model.ServiceClient) (uint64, error)
+	FindByClientID(ctx context.Context, clientID string) (model.ServiceClient, error)
+	Deactivate(ctx context.Context, clientID string) error
+}
+
+type APIKeyRepository interface {
+	Insert(ctx context.Context, key model.APIKey) (uint64, error)
+	FindOne(ctx context.Context, id uint64) (model.APIKey, error)
+	FindByHash(ctx context.Context, keyHash []byte) (model.APIKey, error)
+	Find(ctx context.Context, filter model.APIKeyFilter) ([]model.APIKey, error)
+	Update(ctx context.Context, id uint64, update model.APIKeyUpdate) error
+}
+
+type AuditRepository interface {
+	Insert(ctx context.Context, event model.AuditEvent) error
+}
+
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	Verify(password string, hash []byte) error
+	NeedsRehash(hash []byte) bool
 }
 
 type SessionStorage interface {
-	Save(ctx context.Context, userID uint64) error
-	Exists(ctx context.Context, userID uint64) (bool, error)
-	Delete(ctx context.Context, userID uint64) error
+	Save(ctx context.Context, session model.Session, refreshTokenID string) error
+	Rotate(ctx context.Context, userID uint64, sessionID, refreshTokenID, newRefreshTokenID string) error
+	Exists(ctx context.Context, userID uint64, sessionID string) (bool, error)
+	Find(ctx context.Context, userID uint64) ([]model.Session, error)
+	Delete(ctx context.Context, userID uint64, sessionID string) error
+	DeleteAll(ctx context.Context, userID uint64) error
+}
+
+type TokenRevocationStorage interface {
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+	RevokeUserTokens(ctx context.Context, userID uint64, revokedAt time.Time) error
+	UserTokensValidAfter(ctx context.Context, userID uint64) (time.Time, error)
+	RevokeClientTokens(ctx context.Context, clientID string, revokedAt time.Time) error
+	ClientTokensValidAfter(ctx context.Context, clientID string) (time.Time, error)
+}
+
+type LoginAttemptStorage interface {
+	AddFailure(ctx context.Context, subject string) (int64, error)
+	Lock(ctx context.Context, subject string, duration time.Duration) error
+	LockedFor(ctx context.Context, subject string) (time.Duration, error)
+	Reset(ctx context.Context, subject string) error
 }
 
 type ActivationCodeStorage interface {
@@ -31,6 +129,44 @@ type ActivationCodeStorage interface {
 	Get(ctx context.Context, userID uint64) ([]byte, error)
 }
 
+type PhoneVerificationStorage interface {
+	Save(ctx context.Context, userID uint64, phoneNumber string) (string, error)
+	Get(ctx context.Context, userID uint64) (model.PendingPhoneVerification, error)
+	Delete(ctx context.Context, userID uint64) error
+	AddFailure(ctx context.Context, userID uint64) (int64, error)
+}
+
+type EmailChangeStorage interface {
+	Save(ctx context.Context, userID uint64, email string) (string, string, error)
+	Get(ctx context.Context, userID uint64) (model.PendingEmailChange, error)
+	Delete(ctx context.Context, userID uint64) error
+	Cancel(ctx context.Context, cancelToken string) (uint64, error)
+	AddFailure(ctx context.Context, userID uint64) (int64, error)
+}
+
+type PasswordResetStorage interface {
+	Save(ctx context.Context, userID uint64) (string, error)
+	Find(ctx context.Context, token string) (uint64, error)
+	Consume(ctx context.Context, token string) (uint64, error)
+}
+
+type LoginCodeStorage interface {
+	Save(ctx context.Context, userID uint64, linkTokenID string) (string, error)
+	Get(ctx context.Context, userID uint64) (model.PendingLoginCode, error)
+	AddFailure(ctx context.Context, userID uint64) (int64, error)
+	Consume(ctx context.Context, userID uint64) error
+}
+
 type Mailer interface {
 	SendActivationCode(ctx context.Context, receiver, code string) error
+	SendPasswordResetToken(ctx context.Context, receiver, token string) error
+	SendLoginCode(ctx context.Context, receiver, code, link string) error
+	SendPasswordChangedNotification(ctx context.Context, receiver string) error
+	SendNewDeviceAlert(ctx context.Context, receiver string, device model.KnownDevice, reportLink string) error
+	SendEmailChangeCode(ctx context.Context, receiver, code string) error
+	SendEmailChangeNotice(ctx context.Context, receiver, newEmail, cancelToken string) error
+}
+
+type SmsSender interface {
+	SendPhoneVerificationCode(ctx context.Context, receiver, code string) error
 }
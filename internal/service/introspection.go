@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"log/slog"
+	"time"
+)
+
+// introspectionCacheTTL bounds how long a revoked token can still be reported active by a cached result
+const introspectionCacheTTL = 30 * time.Second
+
+// Introspect tells other services whether a token is active and who it was issued to, like RFC 7662.
+// Unlike verifying the signature, it also rejects tokens which were revoked
+// or belong to a session that was logged out. Tokens which can not be verified are reported inactive
+func (s *AuthService) Introspect(ctx context.Context, token string) (model.TokenIntrospection, error) {
+	err := validateInput(s.validate, introspectionValidation{Token: token})
+	if err != nil {
+		return model.TokenIntrospection{}, err
+	}
+
+	inactive := model.TokenIntrospection{CacheTTL: introspectionCacheTTL}
+
+	claims, err := s.jwtProvider.VerifyAndParseClaims(token)
+	// Only access and refresh tokens are introspected, the tokens of unfinished logins are not
+	if err != nil || (claims.Type != model.TokenTypeAccess && claims.Type != model.TokenTypeRefresh) {
+		return inactive, nil
+	}
+
+	active, err := s.isTokenActive(ctx, claims)
+	if err != nil {
+		return model.TokenIntrospection{}, err
+	}
+	if !active {
+		return inactive, nil
+	}
+
+	introspection := model.TokenIntrospection{
+		Active:      true,
+		SubjectType: claims.SubjectType,
+		UserID:      claims.UserID,
+		ClientID:    claims.ClientID,
+		ActorID:     claims.ActorID,
+		Roles:       claims.Roles,
+		Scopes:      claims.Scopes,
+		SessionID:   claims.SessionID,
+		Type:        claims.Type,
+		IssuedAt:    claims.IssuedAt,
+		ExpiresAt:   claims.ExpiresAt,
+		CacheTTL:    min(introspectionCacheTTL, time.Until(claims.ExpiresAt)),
+	}
+
+	if claims.SubjectType == model.SubjectTypeClient {
+		return introspection, nil
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: &claims.UserID})
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return inactive, nil
+		}
+
+		return model.TokenIntrospection{}, err
+	}
+
+	introspection.IsUserActive = user.IsActive
+	introspection.IsUserConfirmed = user.IsConfirmed
+
+	return introspection, nil
+}
+
+// isTokenActive checks the verified claims of a token against every way the token can be revoked
+func (s *AuthService) isTokenActive(ctx context.Context, claims model.TokenClaims) (bool, error) {
+	revoked, err := s.tokenRevocationStorage.IsTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "token revocation storage: checking token", logger.Err(err))
+
+		return false, err
+	}
+	if revoked {
+		return false, nil
+	}
+
+	// Tokens of a service client are revoked when it is deactivated
+	if claims.SubjectType == model.SubjectTypeClient {
+		validAfter, err := s.tokenRevocationStorage.ClientTokensValidAfter(ctx, claims.ClientID)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"token revocation storage: getting client tokens revocation time",
+				logger.Err(err),
+				slog.String("clientId", claims.ClientID),
+			)
+
+			return false, err
+		}
+
+		return !claims.IssuedAt.Before(validAfter), nil
+	}
+
+	revoked, err = s.tokenRevocationStorage.IsSessionRevoked(ctx, claims.SessionID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: checking session",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return false, err
+	}
+	if revoked {
+		return false, nil
+	}
+
+	validAfter, err := s.tokenRevocationStorage.UserTokensValidAfter(ctx, claims.UserID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: getting user tokens revocation time",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+		)
+
+		return false, err
+	}
+	if claims.IssuedAt.Before(validAfter) {
+		return false, nil
+	}
+
+	// Impersonation tokens are not issued for a stored session
+	if claims.ActorID != 0 {
+		return true, nil
+	}
+
+	_, err = s.sessionStorage.Exists(ctx, claims.UserID, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return false, nil
+		}
+		s.log.ErrorContext(
+			ctx,
+			"token storage: checking session",
+			logger.Err(err),
+			slog.Uint64("userId", claims.UserID),
+			slog.String("sessionId", claims.SessionID),
+		)
+
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func userAccessClaims() model.TokenClaims {
+	return model.TokenClaims{
+		UserID:      123,
+		SubjectType: model.SubjectTypeUser,
+		Roles:       []string{"user"},
+		SessionID:   "session_123",
+		TokenID:     "token_123",
+		Type:        model.TokenTypeAccess,
+		IssuedAt:    time.Now().Add(-time.Minute),
+		ExpiresAt:   time.Now().Add(10 * time.Minute),
+	}
+}
+
+func TestAuthService_Introspect_ActiveUserToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	claims := userAccessClaims()
+	mocks.jwt.On("VerifyAndParseClaims", "access_token").Return(claims, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "token_123").Return(false, nil)
+	mocks.revocations.On("IsSessionRevoked", ctx, "session_123").Return(false, nil)
+	mocks.revocations.On("UserTokensValidAfter", ctx, uint64(123)).Return(time.Time{}, nil)
+	mocks.sessions.On("Exists", ctx, uint64(123), "session_123").Return(true, nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{ID: 123, IsActive: true}, nil)
+
+	introspection, err := service.Introspect(ctx, "access_token")
+
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+	assert.Equal(t, uint64(123), introspection.UserID)
+	assert.Equal(t, "session_123", introspection.SessionID)
+	assert.Equal(t, []string{"user"}, introspection.Roles)
+	assert.True(t, introspection.IsUserActive)
+	assert.False(t, introspection.IsUserConfirmed)
+	assert.Equal(t, introspectionCacheTTL, introspection.CacheTTL)
+}
+
+func TestAuthService_Introspect_LoggedOutSession(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.jwt.On("VerifyAndParseClaims", "access_token").Return(userAccessClaims(), nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "token_123").Return(false, nil)
+	mocks.revocations.On("IsSessionRevoked", ctx, "session_123").Return(false, nil)
+	mocks.revocations.On("UserTokensValidAfter", ctx, uint64(123)).Return(time.Time{}, nil)
+	mocks.sessions.On("Exists", ctx, uint64(123), "session_123").Return(false, model.ErrNotFound)
+
+	introspection, err := service.Introspect(ctx, "access_token")
+
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+	assert.Zero(t, introspection.UserID)
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Introspect_RevokedSession(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.jwt.On("VerifyAndParseClaims", "access_token").Return(userAccessClaims(), nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "token_123").Return(false, nil)
+	mocks.revocations.On("IsSessionRevoked", ctx, "session_123").Return(true, nil)
+
+	introspection, err := service.Introspect(ctx, "access_token")
+
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+}
+
+func TestAuthService_Introspect_InvalidToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.jwt.On("VerifyAndParseClaims", "garbage").Return(model.TokenClaims{}, errors.New("malformed token"))
+
+	introspection, err := service.Introspect(ctx, "garbage")
+
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+	mocks.revocations.AssertNotCalled(t, "IsTokenRevoked", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Introspect_ClientToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.jwt.On("VerifyAndParseClaims", "client_token").Return(model.TokenClaims{
+		ClientID:    "billing",
+		SubjectType: model.SubjectTypeClient,
+		Scopes:      []string{"/service.user.UserService/Get"},
+		TokenID:     "token_456",
+		Type:        model.TokenTypeAccess,
+		IssuedAt:    time.Now(),
+		ExpiresAt:   time.Now().Add(10 * time.Second),
+	}, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "token_456").Return(false, nil)
+	mocks.revocations.On("ClientTokensValidAfter", ctx, "billing").Return(time.Time{}, nil)
+
+	introspection, err := service.Introspect(ctx, "client_token")
+
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+	assert.Equal(t, "billing", introspection.ClientID)
+	assert.LessOrEqual(t, introspection.CacheTTL, 10*time.Second)
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Introspect_DeactivatedClientToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+	issuedAt := time.Now().Add(-time.Minute)
+
+	mocks.jwt.On("VerifyAndParseClaims", "client_token").Return(model.TokenClaims{
+		ClientID:    "billing",
+		SubjectType: model.SubjectTypeClient,
+		TokenID:     "token_456",
+		Type:        model.TokenTypeAccess,
+		IssuedAt:    issuedAt,
+		ExpiresAt:   time.Now().Add(10 * time.Second),
+	}, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "token_456").Return(false, nil)
+	mocks.revocations.On("ClientTokensValidAfter", ctx, "billing").Return(issuedAt.Add(time.Second), nil)
+
+	introspection, err := service.Introspect(ctx, "client_token")
+
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+}
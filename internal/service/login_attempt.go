@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"log/slog"
+	"time"
+)
+
+const (
+	accountFreeLoginAttempts = 5
+	clientFreeLoginAttempts  = 20
+	loginBackoffBase         = 30 * time.Second
+	maxLoginLockout          = 15 * time.Minute
+)
+
+// loginSubject is an account or a client address failed logins are counted for
+type loginSubject struct {
+	key          string
+	freeAttempts int64 // freeAttempts is the number of failures allowed before backing off
+}
+
+func accountLoginSubject(userID uint64) loginSubject {
+	return loginSubject{
+		key:          fmt.Sprintf("account:%d", userID),
+		freeAttempts: accountFreeLoginAttempts,
+	}
+}
+
+// clientLoginSubjects returns the subject of the calling client if its address is known
+func clientLoginSubjects(ctx context.Context) []loginSubject {
+	clientIP := clientIPFromCtx(ctx)
+	if clientIP == "" {
+		return nil
+	}
+
+	return []loginSubject{{
+		key:          fmt.Sprintf("ip:%s", clientIP),
+		freeAttempts: clientFreeLoginAttempts,
+	}}
+}
+
+// loginBackoff doubles the lockout with every failure after the free attempts
+// until it reaches maxLoginLockout
+func loginBackoff(failures, freeAttempts int64) time.Duration {
+	if failures <= freeAttempts {
+		return 0
+	}
+
+	backoff := loginBackoffBase
+	for i := freeAttempts + 1; i < failures && backoff < maxLoginLockout; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxLoginLockout)
+}
+
+// checkLoginLockout returns a model.LockoutError if any of the subjects is locked
+func (s *AuthService) checkLoginLockout(ctx context.Context, subjects ...loginSubject) error {
+	var retryAfter time.Duration
+
+	for _, subject := range subjects {
+		lockedFor, err := s.loginAttemptStorage.LockedFor(ctx, subject.key)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"login attempt storage: checking lockout",
+				logger.Err(err),
+				slog.String("subject", subject.key),
+			)
+
+			return err
+		}
+
+		retryAfter = max(retryAfter, lockedFor)
+	}
+
+	if retryAfter > 0 {
+		return model.LockoutError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// addLoginFailure counts a failed login for the subjects and locks the ones
+// which ran out of free attempts
+func (s *AuthService) addLoginFailure(ctx context.Context, subjects ...loginSubject) error {
+	for _, subject := range subjects {
+		failures, err := s.loginAttemptStorage.AddFailure(ctx, subject.key)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"login attempt storage: adding failure",
+				logger.Err(err),
+				slog.String("subject", subject.key),
+			)
+
+			return err
+		}
+
+		backoff := loginBackoff(failures, subject.freeAttempts)
+		if backoff == 0 {
+			continue
+		}
+
+		s.log.WarnContext(
+			ctx,
+			"login attempt storage: locking subject after failed logins",
+			slog.String("subject", subject.key),
+			slog.Int64("failures", failures),
+			slog.Duration("lockout", backoff),
+		)
+
+		err = s.loginAttemptStorage.Lock(ctx, subject.key, backoff)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"login attempt storage: locking subject",
+				logger.Err(err),
+				slog.String("subject", subject.key),
+			)
+
+			return err
+		}
+	}
+
+	return nil
+}
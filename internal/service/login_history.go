@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/loginhistory"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const defaultLoginHistoryPageSize = 20
+
+type LoginHistoryService struct {
+	log            *slog.Logger
+	validate       *validator.Validate
+	loginEventRepo LoginEventRepository
+	cfg            loginhistory.Config
+}
+
+func NewLoginHistoryService(
+	log *slog.Logger,
+	validate *validator.Validate,
+	loginEventRepo LoginEventRepository,
+	cfg loginhistory.Config,
+) *LoginHistoryService {
+	return &LoginHistoryService{
+		log:            log,
+		validate:       validate,
+		loginEventRepo: loginEventRepo,
+		cfg:            cfg,
+	}
+}
+
+// GetLoginHistory returns a page of the login attempts of a user, newest first.
+// Without a user ID the history of the authenticated user is returned
+func (s *LoginHistoryService) GetLoginHistory(ctx context.Context, filter model.LoginHistoryFilter) (model.LoginHistoryPage, error) {
+	err := validateInput(s.validate, loginHistoryValidation{
+		PageSize:  filter.PageSize,
+		PageToken: filter.PageToken,
+	})
+	if err != nil {
+		return model.LoginHistoryPage{}, err
+	}
+
+	if filter.UserID == 0 {
+		filter.UserID, err = userIDFromCtx(ctx)
+		if err != nil {
+			return model.LoginHistoryPage{}, err
+		}
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultLoginHistoryPageSize
+	}
+
+	var beforeID uint64
+	if filter.PageToken != "" {
+		beforeID, err = strconv.ParseUint(filter.PageToken, 10, 64)
+		if err != nil {
+			return model.LoginHistoryPage{}, model.ValidationErrors{"pageToken": model.ErrInvalidPageToken}
+		}
+	}
+
+	// One more event than asked for tells whether there is a next page
+	events, err := s.loginEventRepo.Find(ctx, filter.UserID, beforeID, filter.PageSize+1)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"sql: finding login events",
+			logger.Err(err),
+			slog.Uint64("userId", filter.UserID),
+		)
+
+		return model.LoginHistoryPage{}, err
+	}
+
+	page := model.LoginHistoryPage{Events: events}
+	if len(events) > filter.PageSize {
+		page.Events = events[:filter.PageSize]
+		page.NextPageToken = strconv.FormatUint(page.Events[filter.PageSize-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// Run purges the events past the retention every purge interval until the context is done.
+// Events are not purged without a retention or a purge interval
+func (s *LoginHistoryService) Run(ctx context.Context) {
+	if s.cfg.Retention <= 0 || s.cfg.PurgeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.Purge(ctx)
+			if err != nil {
+				s.log.ErrorContext(ctx, "sql: purging login events", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Purge removes the events older than the retention
+func (s *LoginHistoryService) Purge(ctx context.Context) error {
+	deleted, err := s.loginEventRepo.DeleteBefore(ctx, time.Now().Add(-s.cfg.Retention))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.log.InfoContext(ctx, "purged login events", slog.Int64("count", deleted))
+	}
+
+	return nil
+}
+
+// record saves a login attempt. Failures are logged, they do not change the result of the login
+func (s *LoginHistoryService) record(ctx context.Context, event model.LoginEvent) {
+	err := s.loginEventRepo.Insert(ctx, event)
+	if err != nil {
+		attrs := []any{logger.Err(err), slog.String("method", string(event.Method))}
+		if event.UserID != nil {
+			attrs = append(attrs, slog.Uint64("userId", *event.UserID))
+		}
+
+		s.log.ErrorContext(ctx, "sql: inserting login event", attrs...)
+	}
+}
+
+// newLoginEvent starts the record of a login attempt made with the method,
+// its user and outcome are filled in as the login goes
+func newLoginEvent(ctx context.Context, method model.LoginMethod) model.LoginEvent {
+	return model.LoginEvent{
+		Method:    method,
+		ClientIP:  clientIPFromCtx(ctx),
+		UserAgent: userAgentFromCtx(ctx),
+		RequestID: requestIDFromCtx(ctx),
+		CreatedAt: time.Now(),
+	}
+}
+
+// loginOutcome tells how a login ended from its result and error
+func loginOutcome(result model.LoginResult, err error) model.LoginOutcome {
+	var ve model.ValidationErrors
+	var le model.LockoutError
+
+	switch {
+	case err == nil && result.MFAChallenge != nil:
+		return model.LoginOutcomeMFARequired
+	case err == nil && result.PasswordChangeChallenge != nil:
+		return model.LoginOutcomePasswordChangeRequired
+	case err == nil:
+		return model.LoginOutcomeSucceeded
+	case errors.As(err, &le):
+		return model.LoginOutcomeLockedOut
+	case errors.Is(err, model.ErrNotFound):
+		return model.LoginOutcomeUnknownUser
+	case errors.Is(err, model.ErrInvalidToken):
+		return model.LoginOutcomeInvalidToken
+	case errors.As(err, &ve):
+		for _, fieldErr := range ve {
+			if errors.Is(fieldErr, model.ErrPasswordsDoNotMatch) ||
+				errors.Is(fieldErr, model.ErrInvalidMFACode) ||
+				errors.Is(fieldErr, model.ErrInvalidLoginCode) {
+				return model.LoginOutcomeInvalidCredentials
+			}
+		}
+
+		return model.LoginOutcomeInvalidInput
+	default:
+		return model.LoginOutcomeError
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// recordLoginEvents replaces the login events mock of newAuthServiceWithMocks,
+// which accepts any event, so tests can expect the recorded events
+func recordLoginEvents(service *AuthService, mocks *authServiceMocks) {
+	mocks.loginEvents = new(MockLoginEventRepository)
+	service.loginHistoryService.loginEventRepo = mocks.loginEvents
+}
+
+func loginHistoryCtx() context.Context {
+	ctx := context.WithValue(context.Background(), "client-ip", "198.51.100.23")
+	ctx = context.WithValue(ctx, "user-agent", "Chrome")
+
+	return context.WithValue(ctx, "request-id", "request_123")
+}
+
+func loginEventOf(method model.LoginMethod, outcome model.LoginOutcome) any {
+	return mock.MatchedBy(func(e model.LoginEvent) bool {
+		return e.UserID != nil && *e.UserID == 123 &&
+			e.Method == method && e.Outcome == outcome &&
+			e.ClientIP == "198.51.100.23" && e.UserAgent == "Chrome" && e.RequestID == "request_123"
+	})
+}
+
+func TestAuthService_Login_RecordsSuccess(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	recordLoginEvents(service, &mocks)
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(loginCodeUserWithPassword(), nil)
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil).Maybe()
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := loginHistoryCtx()
+
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.Anything).Return(nil)
+	mocks.loginEvents.On("Insert", ctx, loginEventOf(model.LoginMethodPassword, model.LoginOutcomeSucceeded)).Return(nil)
+
+	_, err := service.Login(ctx, testCredentials())
+
+	assert.NoError(t, err)
+	mocks.loginEvents.AssertExpectations(t)
+}
+
+func TestAuthService_Login_RecordsWrongPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	recordLoginEvents(service, &mocks)
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(loginCodeUserWithPassword(), nil)
+	withoutLockout(mocks)
+	ctx := loginHistoryCtx()
+
+	mocks.loginAttempts.On("AddFailure", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mocks.loginEvents.On("Insert", ctx, loginEventOf(model.LoginMethodPassword, model.LoginOutcomeInvalidCredentials)).Return(nil)
+
+	_, err := service.Login(ctx, model.Credentials{Email: "test@example.com", Password: "WrongPass123!"})
+
+	assert.Error(t, err)
+	mocks.loginEvents.AssertExpectations(t)
+}
+
+func TestAuthService_Login_RecordingFailureKeepsLogin(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	recordLoginEvents(service, &mocks)
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(loginCodeUserWithPassword(), nil)
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil).Maybe()
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := loginHistoryCtx()
+
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.Anything).Return(nil)
+	mocks.loginEvents.On("Insert", ctx, mock.Anything).Return(model.ErrSql)
+
+	result, err := service.Login(ctx, testCredentials())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token", result.Token.AccessToken)
+}
+
+func TestLoginHistoryService_GetLoginHistory_Paginates(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	mocks.loginEvents.On("Find", ctx, uint64(123), uint64(50), 3).Return([]model.LoginEvent{
+		{ID: 49}, {ID: 48}, {ID: 47},
+	}, nil)
+
+	page, err := service.loginHistoryService.GetLoginHistory(ctx, model.LoginHistoryFilter{
+		PageSize:  2,
+		PageToken: "50",
+	})
+
+	require.NoError(t, err)
+	assert.Len(t, page.Events, 2)
+	assert.Equal(t, "48", page.NextPageToken)
+}
+
+func TestLoginHistoryService_GetLoginHistory_LastPage(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	mocks.loginEvents.On("Find", ctx, uint64(77), uint64(0), defaultLoginHistoryPageSize+1).Return([]model.LoginEvent{
+		{ID: 2}, {ID: 1},
+	}, nil)
+
+	page, err := service.loginHistoryService.GetLoginHistory(ctx, model.LoginHistoryFilter{UserID: 77})
+
+	require.NoError(t, err)
+	assert.Len(t, page.Events, 2)
+	assert.Empty(t, page.NextPageToken)
+}
+
+func TestLoginHistoryService_GetLoginHistory_InvalidPageToken(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+
+	_, err := service.loginHistoryService.GetLoginHistory(changePasswordCtx(), model.LoginHistoryFilter{PageToken: "abc"})
+
+	assert.Equal(t, model.ValidationErrors{"pageToken": model.ErrInvalidPageToken}, err)
+	mocks.loginEvents.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginHistoryService_Purge(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.loginEvents.On("DeleteBefore", ctx, mock.MatchedBy(func(before time.Time) bool {
+		return before.Before(time.Now().Add(-89*24*time.Hour)) && before.After(time.Now().Add(-91*24*time.Hour))
+	})).Return(int64(5), nil)
+
+	err := service.loginHistoryService.Purge(ctx)
+
+	assert.NoError(t, err)
+	mocks.loginEvents.AssertExpectations(t)
+}
+
+func TestLoginHistoryService_Run_WithoutPurgeInterval(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+
+	service.loginHistoryService.Run(context.Background())
+
+	mocks.loginEvents.AssertNotCalled(t, "DeleteBefore", mock.Anything, mock.Anything)
+}
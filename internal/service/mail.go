@@ -84,7 +84,7 @@ func (s *UserService) SendActivationCode(ctx context.Context) error {
 
 	err = s.mailer.SendActivationCode(ctx, user.Email, code)
 	if err != nil {
-		s.log.Error("Mailer", logger.Err(err))
+		s.log.ErrorContext(ctx, "Mailer", logger.Err(err))
 
 		return err
 	}
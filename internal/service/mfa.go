@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/totp"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount      = 10
+	recoveryCodeHalfLength = 5
+)
+
+type MFAService struct {
+	log                 *slog.Logger
+	validate            *validator.Validate
+	userRepo            UserRepository
+	mfaRepo             MFARepository
+	roleRepo            RoleRepository
+	issuer              string
+	secretEncryptionKey []byte
+}
+
+func NewMFAService(
+	log *slog.Logger,
+	validate *validator.Validate,
+	userRepo UserRepository,
+	mfaRepo MFARepository,
+	roleRepo RoleRepository,
+	issuer string,
+	secretEncryptionKey []byte,
+) *MFAService {
+	return &MFAService{
+		log:                 log,
+		validate:            validate,
+		userRepo:            userRepo,
+		mfaRepo:             mfaRepo,
+		roleRepo:            roleRepo,
+		issuer:              issuer,
+		secretEncryptionKey: secretEncryptionKey,
+	}
+}
+
+// EnrollTOTP generates a new secret for the authenticated user. It has to be
+// confirmed with a code before it is used for logins
+func (s *MFAService) EnrollTOTP(ctx context.Context) (model.TOTPEnrollment, error) {
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+
+	user, err := s.userRepo.FindOne(ctx, model.UserFilter{ID: &id})
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+
+	existing, err := s.mfaRepo.FindTOTP(ctx, id)
+	if err == nil && existing.IsConfirmed {
+		return model.TOTPEnrollment{}, model.ErrMFAAlreadyEnabled
+	}
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		return model.TOTPEnrollment{}, err
+	}
+
+	secret := totp.GenerateSecret()
+
+	encryptedSecret, err := security.Encrypt(s.secretEncryptionKey, []byte(secret))
+	if err != nil {
+		s.log.ErrorContext(ctx, "aes: encrypting totp secret", logger.Err(err), slog.Uint64("userId", id))
+
+		return model.TOTPEnrollment{}, err
+	}
+
+	err = s.mfaRepo.SaveTOTP(ctx, model.TOTP{
+		UserID:    id,
+		Secret:    encryptedSecret,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+
+	return model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication of the authenticated user
+// and returns the recovery codes, which are shown only once
+func (s *MFAService) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	err := validateInput(s.validate, mfaCodeValidation{Code: code})
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.mfaRepo.FindTOTP(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, model.ErrMFANotEnabled
+		}
+
+		return nil, err
+	}
+	if t.IsConfirmed {
+		return nil, model.ErrMFAAlreadyEnabled
+	}
+
+	_, ok, err := s.checkTOTP(ctx, t, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, model.ValidationErrors{
+			"code": model.ErrInvalidMFACode,
+		}
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	codeHashes := make([][]byte, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		codeHashes[i] = security.SHA256(normalizeRecoveryCode(codes[i]))
+	}
+
+	err = s.mfaRepo.ConfirmTOTP(ctx, id, codeHashes, time.Now())
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, model.ErrMFAAlreadyEnabled
+		}
+
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// SetRoleMFARequirement makes two-factor authentication mandatory for the users of the role
+func (s *MFAService) SetRoleMFARequirement(ctx context.Context, role model.Role, required bool) error {
+	err := s.roleRepo.SetMFARequired(ctx, role, required)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"role repository: setting mfa requirement",
+			logger.Err(err),
+			slog.String("role", role.String()),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// status reports whether the user has two-factor authentication enabled
+// and, if not, whether one of the roles of the user requires it
+func (s *MFAService) status(ctx context.Context, user model.User) (enabled bool, required bool, err error) {
+	t, err := s.mfaRepo.FindTOTP(ctx, user.ID)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		return false, false, err
+	}
+	if err == nil && t.IsConfirmed {
+		return true, true, nil
+	}
+
+	required, err = s.roleRepo.IsMFARequired(ctx, user.Roles)
+	if err != nil {
+		return false, false, err
+	}
+
+	return false, required, nil
+}
+
+// verify accepts a current TOTP code or an unused recovery code of the user.
+// Both can be used only once, anything else is model.ErrInvalidMFACode
+func (s *MFAService) verify(ctx context.Context, userID uint64, code string) error {
+	err := validateInput(s.validate, mfaCodeValidation{Code: code})
+	if err != nil {
+		return err
+	}
+
+	t, err := s.mfaRepo.FindTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrMFANotEnabled
+		}
+
+		return err
+	}
+	if !t.IsConfirmed {
+		return model.ErrMFANotEnabled
+	}
+
+	step, ok, err := s.checkTOTP(ctx, t, code)
+	if err != nil {
+		return err
+	}
+	if ok {
+		err = s.mfaRepo.UseTOTPStep(ctx, userID, step)
+	} else {
+		err = s.mfaRepo.UseRecoveryCode(ctx, userID, security.SHA256(normalizeRecoveryCode(code)))
+	}
+
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrInvalidMFACode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *MFAService) checkTOTP(ctx context.Context, t model.TOTP, code string) (int64, bool, error) {
+	secret, err := security.Decrypt(s.secretEncryptionKey, t.Secret)
+	if err != nil {
+		s.log.ErrorContext(ctx, "aes: decrypting totp secret", logger.Err(err), slog.Uint64("userId", t.UserID))
+
+		return 0, false, err
+	}
+
+	step, ok := totp.Validate(string(secret), code, time.Now())
+
+	return step, ok, nil
+}
+
+// newRecoveryCode returns a random code formatted as XXXXX-XXXXX
+func newRecoveryCode() string {
+	code := base32.StdEncoding.EncodeToString(security.Bytes(recoveryCodeHalfLength * 2))[:recoveryCodeHalfLength*2]
+
+	return code[:recoveryCodeHalfLength] + "-" + code[recoveryCodeHalfLength:]
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
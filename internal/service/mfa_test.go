@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const testPasswordHash = "$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG" // hash of "StrongPass123!"
+
+func confirmedTOTP(t *testing.T, userID uint64) (model.TOTP, string) {
+	secret := totp.GenerateSecret()
+
+	encryptedSecret, err := security.Encrypt(testSecretEncryptionKey, []byte(secret))
+	require.NoError(t, err)
+
+	return model.TOTP{
+		UserID:      userID,
+		Secret:      encryptedSecret,
+		IsConfirmed: true,
+	}, secret
+}
+
+func mfaTokenClaims() model.TokenClaims {
+	return model.TokenClaims{
+		UserID:    123,
+		Roles:     []string{"admin"},
+		SessionID: "session_123",
+		TokenID:   "mfa_123",
+		Type:      model.TokenTypeMFA,
+		IssuedAt:  time.Now(),
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+}
+
+func mfaUser() model.User {
+	return model.User{
+		ID:                123,
+		Email:             "admin@example.com",
+		PasswordHash:      []byte(testPasswordHash),
+		Roles:             []model.Role{model.RoleAdmin},
+		PasswordChangedAt: time.Now(),
+	}
+}
+
+func TestAuthService_Login_WithTOTPReturnsChallenge(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	user := model.User{
+		ID:           123,
+		Email:        "admin@example.com",
+		PasswordHash: []byte(testPasswordHash),
+		Roles:        []model.Role{model.RoleAdmin},
+	}
+	userTOTP, _ := confirmedTOTP(t, 123)
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(user, nil)
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+	mocks.jwt.On("GenerateMFAToken", claimsForUser(123, []string{"admin"})).Return("mfa_token", nil)
+
+	result, err := service.Login(ctx, model.Credentials{Email: user.Email, Password: "StrongPass123!"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, result.Token.AccessToken)
+	require.NotNil(t, result.MFAChallenge)
+	assert.Equal(t, "mfa_token", result.MFAChallenge.Token)
+	assert.False(t, result.MFAChallenge.EnrollmentRequired)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	mocks.loginAttempts.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_RoleRequiresEnrollment(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	user := model.User{
+		ID:           123,
+		Email:        "finance@example.com",
+		PasswordHash: []byte(testPasswordHash),
+		Roles:        []model.Role{model.RoleFinanceManager},
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(user, nil)
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(model.TOTP{}, model.ErrNotFound)
+	mocks.roles.On("IsMFARequired", ctx, user.Roles).Return(true, nil)
+	mocks.jwt.On("GenerateMFAToken", mock.Anything).Return("mfa_token", nil)
+
+	result, err := service.Login(ctx, model.Credentials{Email: user.Email, Password: "StrongPass123!"})
+
+	assert.NoError(t, err)
+	require.NotNil(t, result.MFAChallenge)
+	assert.True(t, result.MFAChallenge.EnrollmentRequired)
+}
+
+func TestAuthService_VerifyMFA_WithTOTPCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	claims := mfaTokenClaims()
+	userTOTP, secret := confirmedTOTP(t, 123)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	mocks.jwt.On("VerifyAndParseClaims", "mfa.token.value").Return(claims, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "mfa_123").Return(false, nil)
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+	mocks.mfa.On("UseTOTPStep", ctx, uint64(123), step).Return(nil)
+	mocks.revocations.On("RevokeToken", ctx, "mfa_123", claims.ExpiresAt).Return(nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(mfaUser(), nil)
+	withoutPasswordExpiry(mocks)
+	mocks.jwt.On("GenerateAccessToken", claimsForSession(123, "session_123")).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForSession(123, "session_123")).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionWithID(123, "session_123"), mock.Anything).Return(nil)
+
+	result, err := service.VerifyMFA(ctx, "mfa.token.value", code)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token", result.Token.AccessToken)
+	mocks.revocations.AssertExpectations(t)
+	mocks.sessions.AssertExpectations(t)
+}
+
+func TestAuthService_VerifyMFA_WithRecoveryCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	claims := mfaTokenClaims()
+	userTOTP, _ := confirmedTOTP(t, 123)
+
+	mocks.jwt.On("VerifyAndParseClaims", "mfa.token.value").Return(claims, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "mfa_123").Return(false, nil)
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+	mocks.mfa.On("UseRecoveryCode", ctx, uint64(123), security.SHA256("ABCDEFGHIJ")).Return(nil)
+	mocks.revocations.On("RevokeToken", ctx, "mfa_123", claims.ExpiresAt).Return(nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(mfaUser(), nil)
+	withoutPasswordExpiry(mocks)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionWithID(123, "session_123"), mock.Anything).Return(nil)
+
+	_, err := service.VerifyMFA(ctx, "mfa.token.value", "abcde-fghij")
+
+	assert.NoError(t, err)
+	mocks.mfa.AssertExpectations(t)
+}
+
+func TestAuthService_VerifyMFA_ExpiredPasswordReturnsPasswordChange(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	claims := mfaTokenClaims()
+	userTOTP, _ := confirmedTOTP(t, 123)
+	user := mfaUser()
+	user.PasswordChangedAt = time.Now().AddDate(0, 0, -91)
+
+	mocks.jwt.On("VerifyAndParseClaims", "mfa.token.value").Return(claims, nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "mfa_123").Return(false, nil)
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+	mocks.mfa.On("UseRecoveryCode", ctx, uint64(123), security.SHA256("ABCDEFGHIJ")).Return(nil)
+	mocks.revocations.On("RevokeToken", ctx, "mfa_123", claims.ExpiresAt).Return(nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(user, nil)
+	mocks.roles.On("PasswordMaxAge", ctx, user.Roles).Return(90, nil)
+	mocks.jwt.On("GeneratePasswordChangeToken", claimsForSession(123, "session_123")).Return("password_change_token", nil)
+
+	result, err := service.VerifyMFA(ctx, "mfa.token.value", "abcde-fghij")
+
+	assert.NoError(t, err)
+	require.NotNil(t, result.PasswordChangeChallenge)
+	assert.Equal(t, "password_change_token", result.PasswordChangeChallenge.Token)
+	assert.Empty(t, result.Token.AccessToken)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_VerifyMFA_InvalidCodeCountsFailure(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutRevokedTokens(mocks)
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	userTOTP, _ := confirmedTOTP(t, 123)
+
+	mocks.jwt.On("VerifyAndParseClaims", "mfa.token.value").Return(mfaTokenClaims(), nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "mfa_123").Return(false, nil)
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+	mocks.mfa.On("UseRecoveryCode", ctx, uint64(123), mock.Anything).Return(model.ErrNotFound)
+	mocks.loginAttempts.On("AddFailure", ctx, "account:123").Return(int64(1), nil)
+
+	result, err := service.VerifyMFA(ctx, "mfa.token.value", "000000")
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidMFACode}, err)
+	assert.Empty(t, result.Token.AccessToken)
+	mocks.loginAttempts.AssertExpectations(t)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_VerifyMFA_UsedTokenRejected(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.jwt.On("VerifyAndParseClaims", "mfa.token.value").Return(mfaTokenClaims(), nil)
+	mocks.revocations.On("IsTokenRevoked", ctx, "mfa_123").Return(true, nil)
+
+	_, err := service.VerifyMFA(ctx, "mfa.token.value", "123456")
+
+	assert.ErrorIs(t, err, model.ErrInvalidToken)
+	mocks.mfa.AssertNotCalled(t, "FindTOTP", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_VerifyMFA_AccessTokenRejected(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	claims := mfaTokenClaims()
+	claims.Type = model.TokenTypeAccess
+	mocks.jwt.On("VerifyAndParseClaims", "access.token.value").Return(claims, nil)
+
+	_, err := service.VerifyMFA(ctx, "access.token.value", "123456")
+
+	assert.ErrorIs(t, err, model.ErrInvalidToken)
+}
+
+func TestMFAService_ConfirmTOTP(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	userTOTP, secret := confirmedTOTP(t, 123)
+	userTOTP.IsConfirmed = false
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+	mocks.mfa.On("ConfirmTOTP", ctx, uint64(123), mock.MatchedBy(func(hashes [][]byte) bool {
+		return len(hashes) == recoveryCodeCount
+	}), mock.Anything).Return(nil)
+
+	codes, err := service.mfaService.ConfirmTOTP(ctx, code)
+
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Regexp(t, `^[A-Z2-7]{5}-[A-Z2-7]{5}$`, codes[0])
+	mocks.mfa.AssertExpectations(t)
+}
+
+func TestMFAService_ConfirmTOTP_InvalidCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	userTOTP, _ := confirmedTOTP(t, 123)
+	userTOTP.IsConfirmed = false
+
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+
+	_, err := service.mfaService.ConfirmTOTP(ctx, "not-a-code")
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidMFACode}, err)
+	mocks.mfa.AssertNotCalled(t, "ConfirmTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
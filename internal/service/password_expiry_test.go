@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAuthService_Login_ExpiredPasswordReturnsPasswordChange(t *testing.T) {
+	user := model.User{
+		ID:                123,
+		Email:             "finance@example.com",
+		PasswordHash:      []byte(testPasswordHash),
+		Roles:             []model.Role{model.RoleFinanceManager},
+		PasswordChangedAt: time.Now().AddDate(0, 0, -91),
+	}
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(user, nil)
+	withoutMFA(mocks)
+	ctx := context.Background()
+
+	mocks.roles.On("PasswordMaxAge", ctx, user.Roles).Return(90, nil)
+	mocks.jwt.On("GeneratePasswordChangeToken", claimsForUser(123, []string{"finance_manager"})).Return("password_change_token", nil)
+
+	result, err := service.Login(ctx, model.Credentials{Email: user.Email, Password: "StrongPass123!"})
+
+	assert.NoError(t, err)
+	require.NotNil(t, result.PasswordChangeChallenge)
+	assert.Equal(t, "password_change_token", result.PasswordChangeChallenge.Token)
+	assert.Positive(t, result.PasswordChangeChallenge.ExpiresIn)
+	assert.Empty(t, result.Token.AccessToken)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_PasswordWithinMaxAge(t *testing.T) {
+	user := model.User{
+		ID:                123,
+		Email:             "finance@example.com",
+		PasswordHash:      []byte(testPasswordHash),
+		Roles:             []model.Role{model.RoleFinanceManager},
+		PasswordChangedAt: time.Now().AddDate(0, 0, -89),
+	}
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(user, nil)
+	withoutMFA(mocks)
+	ctx := context.Background()
+
+	mocks.roles.On("PasswordMaxAge", ctx, user.Roles).Return(90, nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.Anything).Return(nil)
+
+	result, err := service.Login(ctx, model.Credentials{Email: user.Email, Password: "StrongPass123!"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, result.PasswordChangeChallenge)
+	assert.Equal(t, "access_token", result.Token.AccessToken)
+	mocks.jwt.AssertNotCalled(t, "GeneratePasswordChangeToken", mock.Anything)
+}
+
+func TestAuthService_Login_FlaggedPasswordReturnsPasswordChange(t *testing.T) {
+	user := model.User{
+		ID:                 123,
+		Email:              "test@example.com",
+		PasswordHash:       []byte(testPasswordHash),
+		Roles:              []model.Role{model.RoleUser},
+		PasswordChangedAt:  time.Now(),
+		MustChangePassword: true,
+	}
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	mocks.repo.On("FindOne", mock.Anything, mock.Anything).Return(user, nil)
+	withoutMFA(mocks)
+	ctx := context.Background()
+
+	mocks.jwt.On("GeneratePasswordChangeToken", claimsForUser(123, []string{"user"})).Return("password_change_token", nil)
+
+	result, err := service.Login(ctx, model.Credentials{Email: user.Email, Password: "StrongPass123!"})
+
+	assert.NoError(t, err)
+	require.NotNil(t, result.PasswordChangeChallenge)
+	mocks.roles.AssertNotCalled(t, "PasswordMaxAge", mock.Anything, mock.Anything)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ChangePassword_ClearsPasswordChangeFlag(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	user := model.User{
+		ID:                 123,
+		Email:              "test@example.com",
+		PasswordHash:       []byte(testPasswordHash),
+		Roles:              []model.Role{model.RoleUser},
+		MustChangePassword: true,
+	}
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(user, nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	withoutPasswordHistory(mocks)
+	mocks.repo.On("Update", ctx, idFilter(123), mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.PasswordHash != nil &&
+			u.MustChangePassword != nil && !*u.MustChangePassword &&
+			u.PasswordChangedAt != nil && time.Since(*u.PasswordChangedAt) < time.Minute
+	})).Return(nil)
+	mocks.revocations.On("RevokeUserTokens", ctx, uint64(123), mock.Anything).Return(nil)
+	mocks.sessions.On("DeleteAll", ctx, uint64(123)).Return(nil)
+	mocks.jwt.On("GenerateAccessToken", claimsForSession(123, "session_123")).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", claimsForSession(123, "session_123")).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionWithID(123, "session_123"), mock.Anything).Return(nil)
+	mocks.mailer.On("SendPasswordChangedNotification", ctx, "test@example.com").Return(nil)
+
+	_, err := service.ChangePassword(ctx, model.PasswordChangeData{
+		CurrentPassword:      "StrongPass123!",
+		Password:             "NewPass123!",
+		PasswordConfirmation: "NewPass123!",
+	})
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+}
+
+func TestUserService_RequirePasswordChange(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	email := "test@example.com"
+	mocks.repo.On("FindOne", ctx, emailFilter(email)).Return(model.User{ID: 123, Email: email}, nil)
+	mocks.repo.On("Update", ctx, idFilter(123), mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.MustChangePassword != nil && *u.MustChangePassword
+	})).Return(nil)
+	mocks.revocations.On("RevokeUserTokens", ctx, uint64(123), mock.Anything).Return(nil)
+
+	err := service.userService.RequirePasswordChange(ctx, model.UserFilter{Email: &email})
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+	mocks.revocations.AssertExpectations(t)
+}
+
+func TestUserService_SetRolePasswordMaxAge_RejectsTooLong(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+
+	err := service.userService.SetRolePasswordMaxAge(context.Background(), model.RoleAdmin, 400)
+
+	var ve model.ValidationErrors
+	assert.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "days")
+	mocks.roles.AssertNotCalled(t, "SetPasswordMaxAge", mock.Anything, mock.Anything, mock.Anything)
+}
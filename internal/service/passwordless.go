@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"net/url"
+)
+
+// RequestLoginCode emails a one-time code and a magic link the user can log in with instead of the password.
+// Like a password reset, it does not tell whether a user with the email exists
+func (s *AuthService) RequestLoginCode(ctx context.Context, email string) error {
+	if !s.passwordless.Enabled {
+		return model.ErrPasswordlessDisabled
+	}
+
+	err := validateInput(s.validate, loginCodeRequestValidation{Email: email})
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{Email: &email})
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	linkClaims := model.TokenClaims{
+		UserID:  user.ID,
+		TokenID: security.RandomString(tokenIDLength),
+	}
+
+	linkToken, _, err := s.jwtProvider.GenerateLoginLinkToken(linkClaims)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating login link token",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return model.ErrJwt
+	}
+
+	code, err := s.loginCodeStorage.Save(ctx, user.ID, linkClaims.TokenID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"login code storage: saving code",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return err
+	}
+
+	link := s.passwordless.LinkURL + "?token=" + url.QueryEscape(linkToken)
+
+	err = s.mailer.SendLoginCode(ctx, user.Email, code, link)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"mailer: sending login code",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// LoginWithCode logs the user in with the code or the magic link sent by RequestLoginCode.
+// Both are used up by a successful login, and a code is invalidated after too many wrong attempts.
+// The second factor is still asked for if the user needs one
+func (s *AuthService) LoginWithCode(ctx context.Context, cred model.LoginCodeCredentials) (result model.LoginResult, err error) {
+	if !s.passwordless.Enabled {
+		return model.LoginResult{}, model.ErrPasswordlessDisabled
+	}
+
+	event := newLoginEvent(ctx, model.LoginMethodLoginCode)
+	if cred.LinkToken != "" {
+		event.Method = model.LoginMethodMagicLink
+	}
+	defer func() { s.recordLogin(ctx, event, result, err) }()
+
+	err = validateInput(s.validate, cred)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	clientSubjects := clientLoginSubjects(ctx)
+
+	err = s.checkLoginLockout(ctx, clientSubjects...)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	var filter model.UserFilter
+	var linkClaims model.TokenClaims
+
+	if cred.LinkToken != "" {
+		linkClaims, err = s.jwtProvider.VerifyAndParseClaims(cred.LinkToken)
+		if err != nil || linkClaims.Type != model.TokenTypeLoginLink {
+			return model.LoginResult{}, model.ErrInvalidToken
+		}
+
+		filter.ID = &linkClaims.UserID
+	} else {
+		filter.Email = &cred.Email
+	}
+
+	user, err := s.userService.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			failureErr := s.addLoginFailure(ctx, clientSubjects...)
+			if failureErr != nil {
+				return model.LoginResult{}, failureErr
+			}
+
+			return model.LoginResult{}, model.ValidationErrors{
+				"code": model.ErrInvalidLoginCode,
+			}
+		}
+
+		return model.LoginResult{}, err
+	}
+	event.UserID = &user.ID
+
+	accountSubject := accountLoginSubject(user.ID)
+
+	err = s.checkLoginLockout(ctx, accountSubject)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	pending, err := s.loginCodeStorage.Get(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.LoginResult{}, model.ValidationErrors{
+				"code": model.ErrInvalidLoginCode,
+			}
+		}
+
+		s.log.ErrorContext(
+			ctx,
+			"login code storage: getting code",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return model.LoginResult{}, err
+	}
+
+	if cred.LinkToken != "" {
+		// Only the link sent with the pending code is valid, older links were replaced
+		if linkClaims.TokenID != pending.LinkTokenID {
+			return model.LoginResult{}, model.ErrInvalidToken
+		}
+	} else if security.CheckStringHash(cred.Code, pending.CodeHash) != nil {
+		err = s.addLoginCodeFailure(ctx, user.ID)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+
+		err = s.addLoginFailure(ctx, append(clientSubjects, accountSubject)...)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+
+		return model.LoginResult{}, model.ValidationErrors{
+			"code": model.ErrInvalidLoginCode,
+		}
+	}
+
+	err = s.loginCodeStorage.Consume(ctx, user.ID)
+	if err != nil {
+		// A concurrent login used the code first
+		if errors.Is(err, model.ErrNotFound) {
+			return model.LoginResult{}, model.ValidationErrors{
+				"code": model.ErrInvalidLoginCode,
+			}
+		}
+
+		s.log.ErrorContext(
+			ctx,
+			"login code storage: consuming code",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return model.LoginResult{}, err
+	}
+
+	return s.continueLogin(ctx, user)
+}
+
+// addLoginCodeFailure counts a wrong code and invalidates the code
+// once it was guessed wrong too many times
+func (s *AuthService) addLoginCodeFailure(ctx context.Context, userID uint64) error {
+	failures, err := s.loginCodeStorage.AddFailure(ctx, userID)
+	if err != nil {
+		// The code expired in the meantime
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+
+		s.log.ErrorContext(
+			ctx,
+			"login code storage: adding failure",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	if failures < s.passwordless.MaxAttempts {
+		return nil
+	}
+
+	err = s.loginCodeStorage.Consume(ctx, userID)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		s.log.ErrorContext(
+			ctx,
+			"login code storage: invalidating code",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loginCodeUser() model.User {
+	return model.User{
+		ID:                123,
+		Email:             "test@example.com",
+		Roles:             []model.Role{model.RoleUser},
+		PasswordChangedAt: time.Now(),
+	}
+}
+
+func pendingLoginCode(t *testing.T, code string) model.PendingLoginCode {
+	codeHash, err := security.HashString(code)
+	require.NoError(t, err)
+
+	return model.PendingLoginCode{
+		UserID:      123,
+		CodeHash:    codeHash,
+		LinkTokenID: "link_123",
+	}
+}
+
+func TestAuthService_RequestLoginCode_SendsCodeAndLink(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.repo.On("FindOne", ctx, emailFilter("test@example.com")).Return(loginCodeUser(), nil)
+	mocks.jwt.On("GenerateLoginLinkToken", mock.MatchedBy(func(c model.TokenClaims) bool {
+		return c.UserID == 123 && c.TokenID != ""
+	})).Return("link.token.value", nil)
+	mocks.loginCodes.On("Save", ctx, uint64(123), mock.Anything).Return("ABC123", nil)
+	mocks.mailer.On("SendLoginCode", ctx, "test@example.com", "ABC123", mock.MatchedBy(func(link string) bool {
+		return strings.HasPrefix(link, "https://rental.example.com/login?token=") && strings.HasSuffix(link, "link.token.value")
+	})).Return(nil)
+
+	err := service.RequestLoginCode(ctx, "test@example.com")
+
+	assert.NoError(t, err)
+	mocks.loginCodes.AssertExpectations(t)
+	mocks.mailer.AssertExpectations(t)
+}
+
+func TestAuthService_RequestLoginCode_UnknownEmail(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.Background()
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(model.User{}, model.ErrNotFound)
+
+	err := service.RequestLoginCode(ctx, "unknown@example.com")
+
+	assert.NoError(t, err)
+	mocks.mailer.AssertNotCalled(t, "SendLoginCode", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RequestLoginCode_Disabled(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	service.passwordless.Enabled = false
+
+	err := service.RequestLoginCode(context.Background(), "test@example.com")
+
+	assert.ErrorIs(t, err, model.ErrPasswordlessDisabled)
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_LoginWithCode_Code(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil).Maybe()
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := context.Background()
+
+	mocks.repo.On("FindOne", ctx, emailFilter("test@example.com")).Return(loginCodeUser(), nil)
+	mocks.loginCodes.On("Get", ctx, uint64(123)).Return(pendingLoginCode(t, "ABC123"), nil)
+	mocks.loginCodes.On("Consume", ctx, uint64(123)).Return(nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.Anything).Return(nil)
+
+	result, err := service.LoginWithCode(ctx, model.LoginCodeCredentials{Email: "test@example.com", Code: "ABC123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token", result.Token.AccessToken)
+	mocks.loginCodes.AssertExpectations(t)
+}
+
+func TestAuthService_LoginWithCode_MagicLink(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	mocks.loginAttempts.On("Reset", mock.Anything, mock.Anything).Return(nil).Maybe()
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	ctx := context.Background()
+
+	mocks.jwt.On("VerifyAndParseClaims", "link.token.value").Return(model.TokenClaims{
+		UserID:  123,
+		TokenID: "link_123",
+		Type:    model.TokenTypeLoginLink,
+	}, nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(loginCodeUser(), nil)
+	mocks.loginCodes.On("Get", ctx, uint64(123)).Return(pendingLoginCode(t, "ABC123"), nil)
+	mocks.loginCodes.On("Consume", ctx, uint64(123)).Return(nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.Anything).Return(nil)
+
+	result, err := service.LoginWithCode(ctx, model.LoginCodeCredentials{LinkToken: "link.token.value"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token", result.Token.AccessToken)
+	mocks.loginCodes.AssertExpectations(t)
+}
+
+func TestAuthService_LoginWithCode_ReplacedLinkRejected(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	mocks.jwt.On("VerifyAndParseClaims", "link.token.value").Return(model.TokenClaims{
+		UserID:  123,
+		TokenID: "link_old",
+		Type:    model.TokenTypeLoginLink,
+	}, nil)
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(loginCodeUser(), nil)
+	mocks.loginCodes.On("Get", ctx, uint64(123)).Return(pendingLoginCode(t, "ABC123"), nil)
+
+	_, err := service.LoginWithCode(ctx, model.LoginCodeCredentials{LinkToken: "link.token.value"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidToken)
+	mocks.loginCodes.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_LoginWithCode_WrongCodeCountsFailure(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(loginCodeUser(), nil)
+	mocks.loginCodes.On("Get", ctx, uint64(123)).Return(pendingLoginCode(t, "ABC123"), nil)
+	mocks.loginCodes.On("AddFailure", ctx, uint64(123)).Return(int64(1), nil)
+	mocks.loginAttempts.On("AddFailure", ctx, "account:123").Return(int64(1), nil)
+
+	_, err := service.LoginWithCode(ctx, model.LoginCodeCredentials{Email: "test@example.com", Code: "XYZ789"})
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidLoginCode}, err)
+	mocks.loginAttempts.AssertExpectations(t)
+	mocks.loginCodes.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_LoginWithCode_TooManyAttemptsInvalidatesCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(loginCodeUser(), nil)
+	mocks.loginCodes.On("Get", ctx, uint64(123)).Return(pendingLoginCode(t, "ABC123"), nil)
+	mocks.loginCodes.On("AddFailure", ctx, uint64(123)).Return(int64(3), nil)
+	mocks.loginCodes.On("Consume", ctx, uint64(123)).Return(nil)
+	mocks.loginAttempts.On("AddFailure", ctx, "account:123").Return(int64(3), nil)
+
+	_, err := service.LoginWithCode(ctx, model.LoginCodeCredentials{Email: "test@example.com", Code: "XYZ789"})
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidLoginCode}, err)
+	mocks.loginCodes.AssertExpectations(t)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_LoginWithCode_UsedCodeRejected(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	withoutLockout(mocks)
+	ctx := context.Background()
+
+	mocks.repo.On("FindOne", ctx, mock.Anything).Return(loginCodeUser(), nil)
+	mocks.loginCodes.On("Get", ctx, uint64(123)).Return(model.PendingLoginCode{}, model.ErrNotFound)
+
+	_, err := service.LoginWithCode(ctx, model.LoginCodeCredentials{Email: "test@example.com", Code: "ABC123"})
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidLoginCode}, err)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_LoginWithCode_MissingCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+
+	_, err := service.LoginWithCode(context.Background(), model.LoginCodeCredentials{Email: "test@example.com"})
+
+	var ve model.ValidationErrors
+	assert.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "code")
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
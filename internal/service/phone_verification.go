@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"time"
+)
+
+// maxPhoneCodeAttempts is how many wrong codes invalidate a pending phone verification
+const maxPhoneCodeAttempts = 5
+
+// SendPhoneVerificationCode texts a verification code to the phone number of the authenticated user
+func (s *UserService) SendPhoneVerificationCode(ctx context.Context) error {
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindOne(ctx, model.UserFilter{ID: &id})
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneNumber == "" {
+		return model.ErrNoPhoneNumber
+	}
+	if user.IsPhoneVerified {
+		return model.ErrPhoneNumberVerified
+	}
+
+	code, err := s.phoneVerificationStorage.Save(ctx, id, user.PhoneNumber)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"phone verification storage: saving code",
+			logger.Err(err),
+			slog.Uint64("userId", id),
+		)
+
+		return err
+	}
+
+	err = s.smsSender.SendPhoneVerificationCode(ctx, user.PhoneNumber, code)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"sms sender: sending phone verification code",
+			logger.Err(err),
+			slog.Uint64("userId", id),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// CheckPhoneVerificationCode marks the phone number of the authenticated user as verified.
+// The code only verifies the number it was sent to, so changing the number in between
+// invalidates it
+func (s *UserService) CheckPhoneVerificationCode(ctx context.Context, code string) error {
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validateInput(s.validate, phoneVerificationCodeValidation{Code: code})
+	if err != nil {
+		return err
+	}
+
+	pending, err := s.phoneVerificationStorage.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrNoPendingPhoneCode
+		}
+
+		return err
+	}
+
+	err = security.CheckStringHash(code, pending.CodeHash)
+	if err != nil {
+		err = s.addPhoneCodeFailure(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return model.ValidationErrors{
+			"code": model.ErrInvalidPhoneCode,
+		}
+	}
+
+	isPhoneVerified := true
+	err = s.userRepo.Update(
+		ctx,
+		model.UserFilter{ID: &id, PhoneNumber: &pending.PhoneNumber},
+		model.UserUpdate{
+			UpdatedAt:       time.Now(),
+			IsPhoneVerified: &isPhoneVerified,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrNoPendingPhoneCode
+		}
+
+		return err
+	}
+
+	err = s.phoneVerificationStorage.Delete(ctx, id)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"phone verification storage: deleting code",
+			logger.Err(err),
+			slog.Uint64("userId", id),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// addPhoneCodeFailure counts a wrong code and invalidates the code
+// once it was guessed wrong too many times
+func (s *UserService) addPhoneCodeFailure(ctx context.Context, userID uint64) error {
+	failures, err := s.phoneVerificationStorage.AddFailure(ctx, userID)
+	if err != nil {
+		// The code expired in the meantime
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+
+		s.log.ErrorContext(
+			ctx,
+			"phone verification storage: adding failure",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	if failures < maxPhoneCodeAttempts {
+		return nil
+	}
+
+	err = s.phoneVerificationStorage.Delete(ctx, userID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"phone verification storage: deleting code",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type MockPhoneVerificationStorage struct {
+	mock.Mock
+}
+
+func (m *MockPhoneVerificationStorage) Save(ctx context.Context, userID uint64, phoneNumber string) (string, error) {
+	args := m.Called(ctx, userID, phoneNumber)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPhoneVerificationStorage) Get(ctx context.Context, userID uint64) (model.PendingPhoneVerification, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.PendingPhoneVerification), args.Error(1)
+}
+
+func (m *MockPhoneVerificationStorage) Delete(ctx context.Context, userID uint64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockPhoneVerificationStorage) AddFailure(ctx context.Context, userID uint64) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockSmsSender struct {
+	mock.Mock
+}
+
+func (m *MockSmsSender) SendPhoneVerificationCode(ctx context.Context, receiver, code string) error {
+	args := m.Called(ctx, receiver, code)
+	return args.Error(0)
+}
+
+func TestUserService_SendPhoneVerificationCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{ID: 123, PhoneNumber: "+77001234567"}, nil)
+	mocks.phoneCodes.On("Save", ctx, uint64(123), "+77001234567").Return("ABC123", nil)
+	mocks.sms.On("SendPhoneVerificationCode", ctx, "+77001234567", "ABC123").Return(nil)
+
+	err := service.userService.SendPhoneVerificationCode(ctx)
+
+	assert.NoError(t, err)
+	mocks.sms.AssertExpectations(t)
+}
+
+func TestUserService_SendPhoneVerificationCode_NoPhoneNumber(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{ID: 123}, nil)
+
+	err := service.userService.SendPhoneVerificationCode(ctx)
+
+	assert.ErrorIs(t, err, model.ErrNoPhoneNumber)
+	mocks.sms.AssertNotCalled(t, "SendPhoneVerificationCode", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_SendPhoneVerificationCode_AlreadyVerified(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{
+		ID:              123,
+		PhoneNumber:     "+77001234567",
+		IsPhoneVerified: true,
+	}, nil)
+
+	err := service.userService.SendPhoneVerificationCode(ctx)
+
+	assert.ErrorIs(t, err, model.ErrPhoneNumberVerified)
+	mocks.phoneCodes.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_CheckPhoneVerificationCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	codeHash, err := security.HashString("ABC123")
+	require.NoError(t, err)
+
+	mocks.phoneCodes.On("Get", ctx, uint64(123)).Return(model.PendingPhoneVerification{
+		UserID:      123,
+		PhoneNumber: "+77001234567",
+		CodeHash:    codeHash,
+	}, nil)
+	mocks.repo.On("Update", ctx, mock.MatchedBy(func(f model.UserFilter) bool {
+		return f.ID != nil && *f.ID == 123 && f.PhoneNumber != nil && *f.PhoneNumber == "+77001234567"
+	}), mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.IsPhoneVerified != nil && *u.IsPhoneVerified
+	})).Return(nil)
+	mocks.phoneCodes.On("Delete", ctx, uint64(123)).Return(nil)
+
+	err = service.userService.CheckPhoneVerificationCode(ctx, "ABC123")
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+	mocks.phoneCodes.AssertExpectations(t)
+}
+
+func TestUserService_CheckPhoneVerificationCode_WrongCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	codeHash, err := security.HashString("ABC123")
+	require.NoError(t, err)
+
+	mocks.phoneCodes.On("Get", ctx, uint64(123)).Return(model.PendingPhoneVerification{
+		UserID:      123,
+		PhoneNumber: "+77001234567",
+		CodeHash:    codeHash,
+	}, nil)
+	mocks.phoneCodes.On("AddFailure", ctx, uint64(123)).Return(int64(1), nil)
+
+	err = service.userService.CheckPhoneVerificationCode(ctx, "XYZ789")
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidPhoneCode}, err)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	mocks.phoneCodes.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestUserService_CheckPhoneVerificationCode_TooManyAttemptsInvalidatesCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	codeHash, err := security.HashString("ABC123")
+	require.NoError(t, err)
+
+	mocks.phoneCodes.On("Get", ctx, uint64(123)).Return(model.PendingPhoneVerification{
+		UserID:      123,
+		PhoneNumber: "+77001234567",
+		CodeHash:    codeHash,
+	}, nil)
+	mocks.phoneCodes.On("AddFailure", ctx, uint64(123)).Return(int64(maxPhoneCodeAttempts), nil)
+	mocks.phoneCodes.On("Delete", ctx, uint64(123)).Return(nil)
+
+	err = service.userService.CheckPhoneVerificationCode(ctx, "XYZ789")
+
+	assert.Equal(t, model.ValidationErrors{"code": model.ErrInvalidPhoneCode}, err)
+	mocks.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	mocks.phoneCodes.AssertExpectations(t)
+}
+
+func TestUserService_CheckPhoneVerificationCode_PhoneNumberChanged(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	codeHash, err := security.HashString("ABC123")
+	require.NoError(t, err)
+
+	mocks.phoneCodes.On("Get", ctx, uint64(123)).Return(model.PendingPhoneVerification{
+		UserID:      123,
+		PhoneNumber: "+77001234567",
+		CodeHash:    codeHash,
+	}, nil)
+	mocks.repo.On("Update", ctx, mock.Anything, mock.Anything).Return(model.ErrNotFound)
+
+	err = service.userService.CheckPhoneVerificationCode(ctx, "ABC123")
+
+	assert.ErrorIs(t, err, model.ErrNoPendingPhoneCode)
+	mocks.phoneCodes.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestUserService_Update_NewPhoneNumberResetsVerification(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "userID", uint64(123))
+
+	newPhoneNumber := "+77007654321"
+	id := uint64(123)
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(model.User{
+		ID:              123,
+		PhoneNumber:     "+77001234567",
+		IsPhoneVerified: true,
+	}, nil)
+	mocks.repo.On("Update", ctx, idFilter(123), mock.MatchedBy(func(u model.UserUpdate) bool {
+		return u.IsPhoneVerified != nil && !*u.IsPhoneVerified
+	})).Return(nil)
+
+	err := service.userService.Update(ctx, model.UserFilter{ID: &id}, model.UserUpdateData{PhoneNumber: &newPhoneNumber})
+
+	assert.NoError(t, err)
+	mocks.repo.AssertExpectations(t)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"time"
+)
+
+// Reauthenticate checks the password or a TOTP code of the authenticated user again and issues
+// a short-lived access token of the current session, which can call methods requiring a recent authentication.
+// Failures count towards the account lockout like failed logins
+func (s *AuthService) Reauthenticate(ctx context.Context, data model.ReauthenticationData) (model.Token, error) {
+	id, err := userIDFromCtx(ctx)
+	if err != nil {
+		return model.Token{}, err
+	}
+	sessionID, err := sessionIDFromCtx(ctx)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	err = validateInput(s.validate, data)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	user, err := s.userService.FindOne(ctx, model.UserFilter{ID: &id})
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	accountSubject := accountLoginSubject(user.ID)
+
+	err = s.checkLoginLockout(ctx, accountSubject)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	if data.Password != "" {
+		err = s.userService.checkPassword(user, data.Password)
+		if err != nil {
+			err = s.addLoginFailure(ctx, accountSubject)
+			if err != nil {
+				return model.Token{}, err
+			}
+
+			return model.Token{}, model.ValidationErrors{
+				"password": model.ErrPasswordsDoNotMatch,
+			}
+		}
+	} else {
+		err = s.mfaService.verify(ctx, user.ID, data.Code)
+		if err != nil {
+			if errors.Is(err, model.ErrInvalidMFACode) {
+				failureErr := s.addLoginFailure(ctx, accountSubject)
+				if failureErr != nil {
+					return model.Token{}, failureErr
+				}
+
+				return model.Token{}, model.ValidationErrors{
+					"code": model.ErrInvalidMFACode,
+				}
+			}
+
+			return model.Token{}, err
+		}
+	}
+
+	err = s.loginAttemptStorage.Reset(ctx, accountSubject.key)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"login attempt storage: resetting account failures",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return model.Token{}, err
+	}
+
+	elevatedToken, elevatedTokenExp, err := s.jwtProvider.GenerateElevatedToken(model.TokenClaims{
+		UserID:    user.ID,
+		Roles:     toRoleStrings(user.Roles),
+		SessionID: sessionID,
+		TokenID:   security.RandomString(tokenIDLength),
+		AuthTime:  time.Now(),
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"jwt: generating elevated token",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return model.Token{}, model.ErrJwt
+	}
+
+	return model.Token{
+		AccessToken:          elevatedToken,
+		AccessTokenExpiresIn: int64(time.Until(elevatedTokenExp).Seconds()),
+	}, nil
+}
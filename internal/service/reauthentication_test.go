@@ -0,0 +1,85 @@
+package service
+
+import (
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func elevatedClaims() any {
+	return mock.MatchedBy(func(c model.TokenClaims) bool {
+		return c.UserID == 123 && c.SessionID == "session_123" && c.TokenID != "" &&
+			time.Since(c.AuthTime) < time.Minute
+	})
+}
+
+func TestAuthService_Reauthenticate_WithPassword(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(mfaUser(), nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil)
+	mocks.jwt.On("GenerateElevatedToken", elevatedClaims()).Return("elevated_token", nil)
+
+	token, err := service.Reauthenticate(ctx, model.ReauthenticationData{Password: "StrongPass123!"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "elevated_token", token.AccessToken)
+	assert.Positive(t, token.AccessTokenExpiresIn)
+	assert.Empty(t, token.RefreshToken)
+	mocks.sessions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Reauthenticate_WithTOTPCode(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	userTOTP, secret := confirmedTOTP(t, 123)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(mfaUser(), nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	mocks.mfa.On("FindTOTP", ctx, uint64(123)).Return(userTOTP, nil)
+	mocks.mfa.On("UseTOTPStep", ctx, uint64(123), step).Return(nil)
+	mocks.loginAttempts.On("Reset", ctx, "account:123").Return(nil)
+	mocks.jwt.On("GenerateElevatedToken", elevatedClaims()).Return("elevated_token", nil)
+
+	token, err := service.Reauthenticate(ctx, model.ReauthenticationData{Code: code})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "elevated_token", token.AccessToken)
+	mocks.mfa.AssertExpectations(t)
+}
+
+func TestAuthService_Reauthenticate_WrongPasswordCountsFailure(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := changePasswordCtx()
+
+	mocks.repo.On("FindOne", ctx, idFilter(123)).Return(mfaUser(), nil)
+	mocks.loginAttempts.On("LockedFor", ctx, "account:123").Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("AddFailure", ctx, "account:123").Return(int64(1), nil)
+
+	_, err := service.Reauthenticate(ctx, model.ReauthenticationData{Password: "WrongPass123!"})
+
+	assert.Equal(t, model.ValidationErrors{"password": model.ErrPasswordsDoNotMatch}, err)
+	mocks.loginAttempts.AssertExpectations(t)
+	mocks.jwt.AssertNotCalled(t, "GenerateElevatedToken", mock.Anything)
+}
+
+func TestAuthService_Reauthenticate_MissingCredentials(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+
+	_, err := service.Reauthenticate(changePasswordCtx(), model.ReauthenticationData{})
+
+	var ve model.ValidationErrors
+	assert.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "password")
+	mocks.repo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+}
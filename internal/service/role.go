@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"log/slog"
+)
+
+// RoleService creates roles and changes which permissions they are granted at runtime
+type RoleService struct {
+	log                      *slog.Logger
+	validate                 *validator.Validate
+	rolePermissionRepo       RolePermissionRepository
+	permissionVersionStorage PermissionVersionStorage
+}
+
+func NewRoleService(
+	log *slog.Logger,
+	validate *validator.Validate,
+	rolePermissionRepo RolePermissionRepository,
+	permissionVersionStorage PermissionVersionStorage,
+) *RoleService {
+	return &RoleService{
+		log:                      log,
+		validate:                 validate,
+		rolePermissionRepo:       rolePermissionRepo,
+		permissionVersionStorage: permissionVersionStorage,
+	}
+}
+
+// CreateRole adds a role without permissions, users can be given it right away
+func (s *RoleService) CreateRole(ctx context.Context, role model.Role) error {
+	err := s.rolePermissionRepo.Create(ctx, role)
+	if err != nil {
+		if errors.Is(err, model.ErrDuplicateRole) {
+			return model.ErrDuplicateRole
+		}
+		s.log.ErrorContext(
+			ctx,
+			"role repository: creating role",
+			logger.Err(err),
+			slog.String("role", role.String()),
+		)
+
+		return err
+	}
+
+	adminID, _ := userIDFromCtx(ctx)
+	s.log.InfoContext(
+		ctx,
+		"created role",
+		slog.String("role", role.String()),
+		slog.Uint64("adminId", adminID),
+	)
+
+	return nil
+}
+
+// GrantPermission grants the permission to the role. Every instance applies the change
+// on the next request, tokens issued before it do not have to be renewed
+func (s *RoleService) GrantPermission(ctx context.Context, role model.Role, permission model.Permission) error {
+	err := validateInput(s.validate, rolePermissionValidation{Permission: string(permission)})
+	if err != nil {
+		return err
+	}
+
+	err = s.rolePermissionRepo.GrantPermission(ctx, role, permission)
+	if err != nil {
+		return s.permissionChangeError(ctx, err, "granting permission", role, permission)
+	}
+
+	return s.permissionsChanged(ctx, "granted permission", role, permission)
+}
+
+// RevokePermission takes the permission away from the role, see GrantPermission
+func (s *RoleService) RevokePermission(ctx context.Context, role model.Role, permission model.Permission) error {
+	err := validateInput(s.validate, rolePermissionValidation{Permission: string(permission)})
+	if err != nil {
+		return err
+	}
+
+	err = s.rolePermissionRepo.RevokePermission(ctx, role, permission)
+	if err != nil {
+		return s.permissionChangeError(ctx, err, "revoking permission", role, permission)
+	}
+
+	return s.permissionsChanged(ctx, "revoked permission", role, permission)
+}
+
+func (s *RoleService) permissionChangeError(ctx context.Context, err error, action string, role model.Role, permission model.Permission) error {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return model.ErrNotFound
+	case errors.Is(err, model.ErrInvalidPermission):
+		return model.ValidationErrors{
+			"permission": model.ErrInvalidPermission,
+		}
+	}
+
+	s.log.ErrorContext(
+		ctx,
+		"role repository: "+action,
+		logger.Err(err),
+		slog.String("role", role.String()),
+		slog.String("permission", string(permission)),
+	)
+
+	return err
+}
+
+// permissionsChanged makes the instances reload the permissions of the roles
+func (s *RoleService) permissionsChanged(ctx context.Context, action string, role model.Role, permission model.Permission) error {
+	err := s.permissionVersionStorage.Bump(ctx)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"permission version storage: bumping version",
+			logger.Err(err),
+			slog.String("role", role.String()),
+		)
+
+		return err
+	}
+
+	adminID, _ := userIDFromCtx(ctx)
+	s.log.InfoContext(
+		ctx,
+		action,
+		slog.String("role", role.String()),
+		slog.String("permission", string(permission)),
+		slog.Uint64("adminId", adminID),
+	)
+
+	return nil
+}
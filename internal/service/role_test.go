@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+type MockRolePermissionRepository struct {
+	mock.Mock
+}
+
+func (m *MockRolePermissionRepository) Create(ctx context.Context, role model.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *MockRolePermissionRepository) GrantPermission(ctx context.Context, role model.Role, permission model.Permission) error {
+	args := m.Called(ctx, role, permission)
+	return args.Error(0)
+}
+
+func (m *MockRolePermissionRepository) RevokePermission(ctx context.Context, role model.Role, permission model.Permission) error {
+	args := m.Called(ctx, role, permission)
+	return args.Error(0)
+}
+
+type MockPermissionVersionStorage struct {
+	mock.Mock
+}
+
+func (m *MockPermissionVersionStorage) Bump(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func setupRoleService() (*RoleService, *MockRolePermissionRepository, *MockPermissionVersionStorage) {
+	repo := new(MockRolePermissionRepository)
+	versions := new(MockPermissionVersionStorage)
+
+	service := NewRoleService(
+		slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		validator.New(),
+		repo,
+		versions,
+	)
+
+	return service, repo, versions
+}
+
+func TestRoleService_CreateRole_Duplicate(t *testing.T) {
+	service, repo, _ := setupRoleService()
+	ctx := context.Background()
+
+	repo.On("Create", ctx, model.Role("fleet_manager")).Return(model.ErrDuplicateRole)
+
+	err := service.CreateRole(ctx, "fleet_manager")
+
+	assert.ErrorIs(t, err, model.ErrDuplicateRole)
+}
+
+func TestRoleService_GrantPermission_BumpsVersion(t *testing.T) {
+	service, repo, versions := setupRoleService()
+	ctx := changePasswordCtx()
+
+	repo.On("GrantPermission", ctx, model.Role("fleet_manager"), model.PermissionUsersList).Return(nil)
+	versions.On("Bump", ctx).Return(nil)
+
+	err := service.GrantPermission(ctx, "fleet_manager", model.PermissionUsersList)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	versions.AssertExpectations(t)
+}
+
+func TestRoleService_GrantPermission_UnknownPermission(t *testing.T) {
+	service, repo, versions := setupRoleService()
+	ctx := context.Background()
+
+	repo.On("GrantPermission", ctx, model.RoleTechSupport, model.Permission("cars.fly")).Return(model.ErrInvalidPermission)
+
+	err := service.GrantPermission(ctx, model.RoleTechSupport, "cars.fly")
+
+	assert.Equal(t, model.ValidationErrors{"permission": model.ErrInvalidPermission}, err)
+	versions.AssertNotCalled(t, "Bump", mock.Anything)
+}
+
+func TestRoleService_RevokePermission_UnknownRole(t *testing.T) {
+	service, repo, versions := setupRoleService()
+	ctx := context.Background()
+
+	repo.On("RevokePermission", ctx, model.Role("fleet_manager"), model.PermissionUsersList).Return(model.ErrNotFound)
+
+	err := service.RevokePermission(ctx, "fleet_manager", model.PermissionUsersList)
+
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	versions.AssertNotCalled(t, "Bump", mock.Anything)
+}
+
+func TestRoleService_RevokePermission_RequiresPermission(t *testing.T) {
+	service, repo, _ := setupRoleService()
+
+	err := service.RevokePermission(context.Background(), model.RoleUser, "")
+
+	assert.Equal(t, model.ValidationErrors{"permission": model.ErrRequiredField}, err)
+	repo.AssertNotCalled(t, "RevokePermission", mock.Anything, mock.Anything, mock.Anything)
+}
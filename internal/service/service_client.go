@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"log/slog"
+	"time"
+)
+
+const (
+	clientIDLength     = 12
+	clientSecretLength = 32
+)
+
+// ServiceClientService registers the platform services which call this one
+// and issues them access tokens for the client credentials flow
+type ServiceClientService struct {
+	log                    *slog.Logger
+	validate               *validator.Validate
+	jwtProvider            JwtProvider
+	clientRepo             ServiceClientRepository
+	tokenRevocationStorage TokenRevocationStorage
+}
+
+func NewServiceClientService(
+	log *slog.Logger,
+	validate *validator.Validate,
+	jwtProvider JwtProvider,
+	clientRepo ServiceClientRepository,
+	tokenRevocationStorage TokenRevocationStorage,
+) *ServiceClientService {
+	return &ServiceClientService{
+		log:                    log,
+		validate:               validate,
+		jwtProvider:            jwtProvider,
+		clientRepo:             clientRepo,
+		tokenRevocationStorage: tokenRevocationStorage,
+	}
+}
+
+// Register creates a service client and returns its credentials.
+// The secret can not be recovered later, a lost secret means registering a new client
+func (s *ServiceClientService) Register(ctx context.Context, data model.ServiceClientCreateData) (model.ServiceClientCredentials, error) {
+	err := validateInput(s.validate, data)
+	if err != nil {
+		return model.ServiceClientCredentials{}, err
+	}
+
+	credentials := model.ServiceClientCredentials{
+		ClientID:     security.RandomString(clientIDLength),
+		ClientSecret: security.RandomString(clientSecretLength),
+	}
+
+	_, err = s.clientRepo.Insert(ctx, model.ServiceClient{
+		ClientID:       credentials.ClientID,
+		Name:           data.Name,
+		SecretHash:     security.SHA256(credentials.ClientSecret),
+		AllowedMethods: data.AllowedMethods,
+		CreatedAt:      time.Now(),
+		IsActive:       true,
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "sql: inserting service client", logger.Err(err))
+
+		return model.ServiceClientCredentials{}, err
+	}
+
+	s.log.InfoContext(
+		ctx,
+		"service client registered",
+		slog.String("clientId", credentials.ClientID),
+		slog.String("name", data.Name),
+	)
+
+	return credentials, nil
+}
+
+// Deactivate stops issuing tokens to the client and revokes the tokens issued before
+func (s *ServiceClientService) Deactivate(ctx context.Context, clientID string) error {
+	err := s.clientRepo.Deactivate(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrNotFound
+		}
+		s.log.ErrorContext(ctx, "sql: deactivating service client", logger.Err(err), slog.String("clientId", clientID))
+
+		return err
+	}
+
+	err = s.tokenRevocationStorage.RevokeClientTokens(ctx, clientID, time.Now())
+	if err != nil {
+		s.log.ErrorContext(ctx, "token revocation storage: revoking client tokens", logger.Err(err), slog.String("clientId", clientID))
+
+		return err
+	}
+
+	return nil
+}
+
+// IssueToken exchanges client credentials for an access token which can call
+// the allowed methods of the client. No refresh token is issued,
+// clients request a new token with their credentials instead
+func (s *ServiceClientService) IssueToken(ctx context.Context, clientID, clientSecret string) (model.Token, error) {
+	err := validateInput(s.validate, clientCredentialsValidation{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	client, err := s.clientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.Token{}, model.ErrInvalidClientCredentials
+		}
+		s.log.ErrorContext(ctx, "sql: finding service client", logger.Err(err), slog.String("clientId", clientID))
+
+		return model.Token{}, err
+	}
+
+	if subtle.ConstantTimeCompare(security.SHA256(clientSecret), client.SecretHash) != 1 || !client.IsActive {
+		return model.Token{}, model.ErrInvalidClientCredentials
+	}
+
+	accessToken, accessTokenExp, err := s.jwtProvider.GenerateAccessToken(model.TokenClaims{
+		ClientID:    client.ClientID,
+		SubjectType: model.SubjectTypeClient,
+		Scopes:      client.AllowedMethods,
+		TokenID:     security.RandomString(tokenIDLength),
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "jwt: generating client access token", logger.Err(err), slog.String("clientId", clientID))
+
+		return model.Token{}, model.ErrJwt
+	}
+
+	return model.Token{
+		AccessToken:          accessToken,
+		AccessTokenExpiresIn: int64(time.Until(accessTokenExp).Seconds()),
+	}, nil
+}
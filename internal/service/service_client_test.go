@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+type MockServiceClientRepository struct {
+	mock.Mock
+}
+
+func (m *MockServiceClientRepository) Insert(ctx context.Context, client model.ServiceClient) (uint64, error) {
+	args := m.Called(ctx, client)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockServiceClientRepository) FindByClientID(ctx context.Context, clientID string) (model.ServiceClient, error) {
+	args := m.Called(ctx, clientID)
+	return args.Get(0).(model.ServiceClient), args.Error(1)
+}
+
+func (m *MockServiceClientRepository) Deactivate(ctx context.Context, clientID string) error {
+	args := m.Called(ctx, clientID)
+	return args.Error(0)
+}
+
+func setupServiceClientService() (*ServiceClientService, *MockServiceClientRepository, *MockJWTProvider, *MockTokenRevocationStorage) {
+	repo := new(MockServiceClientRepository)
+	jwtProvider := new(MockJWTProvider)
+	revocations := new(MockTokenRevocationStorage)
+
+	service := NewServiceClientService(
+		slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		validator.New(),
+		jwtProvider,
+		repo,
+		revocations,
+	)
+
+	return service, repo, jwtProvider, revocations
+}
+
+func TestServiceClientService_Register(t *testing.T) {
+	service, repo, _, _ := setupServiceClientService()
+	ctx := context.Background()
+
+	var stored model.ServiceClient
+	repo.On("Insert", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(model.ServiceClient)
+	}).Return(uint64(1), nil)
+
+	credentials, err := service.Register(ctx, model.ServiceClientCreateData{
+		Name:           "booking",
+		AllowedMethods: []string{"/service.user.UserService/Get"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, credentials.ClientID, stored.ClientID)
+	assert.Equal(t, security.SHA256(credentials.ClientSecret), stored.SecretHash)
+	assert.True(t, stored.IsActive)
+}
+
+func TestServiceClientService_Register_InvalidMethod(t *testing.T) {
+	service, repo, _, _ := setupServiceClientService()
+
+	_, err := service.Register(context.Background(), model.ServiceClientCreateData{
+		Name:           "booking",
+		AllowedMethods: []string{"UserService/Get"},
+	})
+
+	var ve model.ValidationErrors
+	require.ErrorAs(t, err, &ve)
+	assert.Contains(t, ve, "allowedMethods[0]")
+	repo.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+}
+
+func TestServiceClientService_IssueToken(t *testing.T) {
+	service, repo, jwtProvider, _ := setupServiceClientService()
+	ctx := context.Background()
+
+	repo.On("FindByClientID", ctx, "client").Return(model.ServiceClient{
+		ClientID:       "client",
+		SecretHash:     security.SHA256("secret"),
+		AllowedMethods: []string{"/service.user.UserService/Get"},
+		IsActive:       true,
+	}, nil)
+	jwtProvider.On("GenerateAccessToken", mock.MatchedBy(func(c model.TokenClaims) bool {
+		return c.SubjectType == model.SubjectTypeClient &&
+			c.ClientID == "client" &&
+			c.UserID == 0 &&
+			len(c.Scopes) == 1 && c.Scopes[0] == "/service.user.UserService/Get"
+	})).Return("access_token", nil)
+
+	token, err := service.IssueToken(ctx, "client", "secret")
+
+	require.NoError(t, err)
+	assert.Equal(t, "access_token", token.AccessToken)
+	assert.Empty(t, token.RefreshToken)
+}
+
+func TestServiceClientService_IssueToken_WrongSecret(t *testing.T) {
+	service, repo, jwtProvider, _ := setupServiceClientService()
+	ctx := context.Background()
+
+	repo.On("FindByClientID", ctx, "client").Return(model.ServiceClient{
+		ClientID:   "client",
+		SecretHash: security.SHA256("secret"),
+		IsActive:   true,
+	}, nil)
+
+	_, err := service.IssueToken(ctx, "client", "wrong")
+
+	assert.ErrorIs(t, err, model.ErrInvalidClientCredentials)
+	jwtProvider.AssertNotCalled(t, "GenerateAccessToken", mock.Anything)
+}
+
+func TestServiceClientService_IssueToken_Inactive(t *testing.T) {
+	service, repo, jwtProvider, _ := setupServiceClientService()
+	ctx := context.Background()
+
+	repo.On("FindByClientID", ctx, "client").Return(model.ServiceClient{
+		ClientID:   "client",
+		SecretHash: security.SHA256("secret"),
+	}, nil)
+
+	_, err := service.IssueToken(ctx, "client", "secret")
+
+	assert.ErrorIs(t, err, model.ErrInvalidClientCredentials)
+	jwtProvider.AssertNotCalled(t, "GenerateAccessToken", mock.Anything)
+}
+
+func TestServiceClientService_IssueToken_UnknownClient(t *testing.T) {
+	service, repo, _, _ := setupServiceClientService()
+	ctx := context.Background()
+
+	repo.On("FindByClientID", ctx, "unknown").Return(model.ServiceClient{}, model.ErrNotFound)
+
+	_, err := service.IssueToken(ctx, "unknown", "secret")
+
+	assert.ErrorIs(t, err, model.ErrInvalidClientCredentials)
+}
+
+func TestServiceClientService_Deactivate_RevokesTokens(t *testing.T) {
+	service, repo, _, revocations := setupServiceClientService()
+	ctx := context.Background()
+
+	repo.On("Deactivate", ctx, "client").Return(nil)
+	revocations.On("RevokeClientTokens", ctx, "client", mock.Anything).Return(nil)
+
+	err := service.Deactivate(ctx, "client")
+
+	require.NoError(t, err)
+	revocations.AssertExpectations(t)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// ListSessions returns the sessions of the authenticated user, most recently used first
+func (s *AuthService) ListSessions(ctx context.Context) ([]model.Session, error) {
+	userID, err := userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := sessionIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessionStorage.Find(ctx, userID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token storage: finding sessions",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].IsCurrent = sessions[i].ID == sessionID
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession ends a session of the authenticated user.
+// Access tokens already issued for the session are rejected right away
+func (s *AuthService) RevokeSession(ctx context.Context, sessionID string) error {
+	userID, err := userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validateInput(s.validate, sessionIDValidation{SessionID: sessionID})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sessionStorage.Exists(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrNotFound
+		}
+		s.log.ErrorContext(
+			ctx,
+			"token storage: checking session",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+			slog.String("sessionId", sessionID),
+		)
+
+		return err
+	}
+
+	err = s.sessionStorage.Delete(ctx, userID, sessionID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token storage: deleting session",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+			slog.String("sessionId", sessionID),
+		)
+
+		return err
+	}
+
+	err = s.tokenRevocationStorage.RevokeSession(ctx, sessionID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: revoking session",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+			slog.String("sessionId", sessionID),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// RevokeAllSessions ends every session of the authenticated user, the current one included
+func (s *AuthService) RevokeAllSessions(ctx context.Context) error {
+	userID, err := userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.sessionStorage.DeleteAll(ctx, userID)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token storage: deleting all sessions",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	err = s.tokenRevocationStorage.RevokeUserTokens(ctx, userID, time.Now())
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: revoking user tokens",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	return nil
+}
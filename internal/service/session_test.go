@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"github.com/sorawaslocked/car-rental-user-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func sessionOf(userID uint64) any {
+	return mock.MatchedBy(func(s model.Session) bool {
+		return s.UserID == userID
+	})
+}
+
+func sessionWithID(userID uint64, sessionID string) any {
+	return mock.MatchedBy(func(s model.Session) bool {
+		return s.UserID == userID && s.ID == sessionID
+	})
+}
+
+func sessionCtx(userID uint64, sessionID string) context.Context {
+	ctx := context.WithValue(context.Background(), "userID", userID)
+
+	return context.WithValue(ctx, "sessionID", sessionID)
+}
+
+func TestAuthService_Login_SavesSessionDevice(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := context.WithValue(context.Background(), "client-ip", "203.0.113.7")
+	ctx = context.WithValue(ctx, "user-agent", "car-rental-ios/2.3")
+	ctx = context.WithValue(ctx, "device-name", "iPhone 15")
+
+	user := model.User{
+		ID:           123,
+		Email:        "test@example.com",
+		PasswordHash: []byte("$2a$10$SCON6tCMIKvGcItvCo5zsOjeUjlzSjEfdQs2yonQsVWDO3mzmfuzG"),
+		Roles:        []model.Role{model.RoleUser},
+	}
+
+	mocks.loginAttempts.On("LockedFor", ctx, mock.Anything).Return(time.Duration(0), nil)
+	mocks.loginAttempts.On("Reset", ctx, mock.Anything).Return(nil)
+	withoutMFA(mocks)
+	withoutPasswordExpiry(mocks)
+	mocks.repo.On("FindOne", ctx, emailFilter("test@example.com")).Return(user, nil)
+	mocks.jwt.On("GenerateAccessToken", mock.Anything).Return("access_token", nil)
+	mocks.jwt.On("GenerateRefreshToken", mock.Anything).Return("refresh_token", nil)
+
+	var saved model.Session
+	mocks.sessions.On("Save", ctx, sessionOf(123), mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) {
+			saved = args.Get(1).(model.Session)
+		}).
+		Return(nil)
+
+	_, err := service.Login(ctx, model.Credentials{Email: "test@example.com", Password: "StrongPass123!"})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, saved.ID)
+	assert.Equal(t, "iPhone 15", saved.DeviceName)
+	assert.Equal(t, "car-rental-ios/2.3", saved.UserAgent)
+	assert.Equal(t, "203.0.113.7", saved.ClientIP)
+	assert.False(t, saved.CreatedAt.IsZero())
+}
+
+func TestAuthService_ListSessions(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := sessionCtx(123, "current")
+
+	now := time.Now()
+	mocks.sessions.On("Find", ctx, uint64(123)).Return([]model.Session{
+		{ID: "old", UserID: 123, LastUsedAt: now.Add(-time.Hour)},
+		{ID: "current", UserID: 123, LastUsedAt: now},
+	}, nil)
+
+	sessions, err := service.ListSessions(ctx)
+
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "current", sessions[0].ID)
+	assert.True(t, sessions[0].IsCurrent)
+	assert.False(t, sessions[1].IsCurrent)
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := sessionCtx(123, "current")
+
+	mocks.sessions.On("Exists", ctx, uint64(123), "lost_phone").Return(true, nil)
+	mocks.sessions.On("Delete", ctx, uint64(123), "lost_phone").Return(nil)
+	mocks.revocations.On("RevokeSession", ctx, "lost_phone").Return(nil)
+
+	err := service.RevokeSession(ctx, "lost_phone")
+
+	assert.NoError(t, err)
+	mocks.sessions.AssertExpectations(t)
+	mocks.revocations.AssertExpectations(t)
+}
+
+func TestAuthService_RevokeSession_NotFound(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := sessionCtx(123, "current")
+
+	mocks.sessions.On("Exists", ctx, uint64(123), "other_users").Return(false, model.ErrNotFound)
+
+	err := service.RevokeSession(ctx, "other_users")
+
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	mocks.sessions.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	mocks.revocations.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RevokeAllSessions(t *testing.T) {
+	service, mocks := newAuthServiceWithMocks()
+	ctx := sessionCtx(123, "current")
+
+	mocks.sessions.On("DeleteAll", ctx, uint64(123)).Return(nil)
+	mocks.revocations.On("RevokeUserTokens", ctx, uint64(123), mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := service.RevokeAllSessions(ctx)
+
+	assert.NoError(t, err)
+	mocks.sessions.AssertExpectations(t)
+	mocks.revocations.AssertExpectations(t)
+}
@@ -6,39 +6,65 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/sorawaslocked/car-rental-user-service/internal/model"
 	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/logger"
-	"github.com/sorawaslocked/car-rental-user-service/internal/pkg/security"
+	validatecfg "github.com/sorawaslocked/car-rental-user-service/internal/pkg/validate"
 	"log/slog"
 	"time"
 )
 
 type UserService struct {
-	log                   *slog.Logger
-	validate              *validator.Validate
-	jwtProvider           JwtProvider
-	userRepo              UserRepository
-	activationCodeStorage ActivationCodeStorage
-	mailer                Mailer
+	log                      *slog.Logger
+	validate                 *validator.Validate
+	jwtProvider              JwtProvider
+	passwordHasher           PasswordHasher
+	userRepo                 UserRepository
+	roleRepo                 RoleRepository
+	passwordHistoryRepo      PasswordHistoryRepository
+	activationCodeStorage    ActivationCodeStorage
+	phoneVerificationStorage PhoneVerificationStorage
+	emailChangeStorage       EmailChangeStorage
+	tokenRevocationStorage   TokenRevocationStorage
+	loginAttemptStorage      LoginAttemptStorage
+	mailer                   Mailer
+	smsSender                SmsSender
 }
 
 func NewUserService(
 	log *slog.Logger,
 	validate *validator.Validate,
 	jwtProvider JwtProvider,
+	passwordHasher PasswordHasher,
 	userRepo UserRepository,
+	roleRepo RoleRepository,
+	passwordHistoryRepo PasswordHistoryRepository,
 	activationCodeStorage ActivationCodeStorage,
+	phoneVerificationStorage PhoneVerificationStorage,
+	emailChangeStorage EmailChangeStorage,
+	tokenRevocationStorage TokenRevocationStorage,
+	loginAttemptStorage LoginAttemptStorage,
 	mailer Mailer,
+	smsSender SmsSender,
 ) *UserService {
 	return &UserService{
-		log:                   log,
-		validate:              validate,
-		jwtProvider:           jwtProvider,
-		userRepo:              userRepo,
-		activationCodeStorage: activationCodeStorage,
-		mailer:                mailer,
+		log:                      log,
+		validate:                 validate,
+		jwtProvider:              jwtProvider,
+		passwordHasher:           passwordHasher,
+		userRepo:                 userRepo,
+		roleRepo:                 roleRepo,
+		passwordHistoryRepo:      passwordHistoryRepo,
+		activationCodeStorage:    activationCodeStorage,
+		phoneVerificationStorage: phoneVerificationStorage,
+		emailChangeStorage:       emailChangeStorage,
+		tokenRevocationStorage:   tokenRevocationStorage,
+		loginAttemptStorage:      loginAttemptStorage,
+		mailer:                   mailer,
+		smsSender:                smsSender,
 	}
 }
 
 func (s *UserService) Insert(ctx context.Context, data model.UserCreateData) (uint64, error) {
+	data.PhoneNumber = validatecfg.NormalizePhoneNumber(data.PhoneNumber)
+
 	err := validateInput(s.validate, data)
 	if err != nil {
 		return 0, err
@@ -49,11 +75,18 @@ func (s *UserService) Insert(ctx context.Context, data model.UserCreateData) (ui
 		return 0, model.ErrDuplicateEmail
 	}
 
-	passwordHash, err := security.HashString(data.Password)
+	if data.PhoneNumber != "" {
+		_, err = s.userRepo.FindOne(ctx, model.UserFilter{PhoneNumber: &data.PhoneNumber})
+		if err == nil {
+			return 0, model.ErrDuplicatePhoneNumber
+		}
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(data.Password)
 	if err != nil {
-		s.log.Error("bcrypt: hashing password", logger.Err(err))
+		s.log.ErrorContext(ctx, "password hasher: hashing password", logger.Err(err))
 
-		return 0, model.ErrBcrypt
+		return 0, model.ErrPasswordHash
 	}
 
 	user := model.User{
@@ -65,6 +98,8 @@ func (s *UserService) Insert(ctx context.Context, data model.UserCreateData) (ui
 		PasswordHash: passwordHash,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
+
+		PasswordChangedAt: time.Now(),
 	}
 
 	// TODO: add permission checks
@@ -78,7 +113,20 @@ func (s *UserService) Insert(ctx context.Context, data model.UserCreateData) (ui
 		user.IsConfirmed = *data.IsConfirmed
 	}
 
-	return s.userRepo.Insert(ctx, user)
+	id, err := s.userRepo.Insert(ctx, user)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidRole) {
+			return 0, model.ValidationErrors{
+				"role": model.ErrInvalidRole,
+			}
+		}
+
+		return 0, err
+	}
+
+	s.recordPassword(ctx, id, passwordHash)
+
+	return id, nil
 }
 
 func (s *UserService) FindOne(ctx context.Context, filter model.UserFilter) (model.User, error) {
@@ -93,7 +141,7 @@ func (s *UserService) FindOne(ctx context.Context, filter model.UserFilter) (mod
 		if errors.Is(err, model.ErrNotFound) {
 			return model.User{}, model.ErrNotFound
 		}
-		s.log.Error("sql: finding user", logger.Err(err))
+		s.log.ErrorContext(ctx, "sql: finding user", logger.Err(err))
 
 		return model.User{}, model.ErrSql
 	}
@@ -104,7 +152,7 @@ func (s *UserService) FindOne(ctx context.Context, filter model.UserFilter) (mod
 func (s *UserService) Find(ctx context.Context, filter model.UserFilter) ([]model.User, error) {
 	users, err := s.userRepo.Find(ctx, filter)
 	if err != nil {
-		s.log.Error("sql: finding users", logger.Err(err))
+		s.log.ErrorContext(ctx, "sql: finding users", logger.Err(err))
 
 		return []model.User{}, model.ErrSql
 	}
@@ -119,16 +167,37 @@ func (s *UserService) Update(ctx context.Context, filter model.UserFilter, data
 	}
 	formatFilter(&filter)
 
-	_, err = s.FindOne(ctx, filter)
+	user, err := s.FindOne(ctx, filter)
 	if err != nil {
 		return err
 	}
 
+	if data.PhoneNumber != nil {
+		phoneNumber := validatecfg.NormalizePhoneNumber(*data.PhoneNumber)
+		data.PhoneNumber = &phoneNumber
+	}
+
 	err = validateInput(s.validate, data)
 	if err != nil {
 		return err
 	}
 
+	// Users change their own password with ChangePassword, which checks the current one,
+	// and their own email with RequestEmailChange, which checks the new address
+	callerID, err := userIDFromCtx(ctx)
+	if err == nil && callerID == user.ID {
+		if data.Password != nil {
+			return model.ValidationErrors{
+				"password": model.ErrOwnPasswordUpdate,
+			}
+		}
+		if data.Email != nil && *data.Email != user.Email {
+			return model.ValidationErrors{
+				"email": model.ErrOwnEmailUpdate,
+			}
+		}
+	}
+
 	update := model.UserUpdate{
 		Email:       data.Email,
 		PhoneNumber: data.PhoneNumber,
@@ -141,14 +210,32 @@ func (s *UserService) Update(ctx context.Context, filter model.UserFilter, data
 		IsConfirmed: data.IsConfirmed,
 	}
 
+	// A new phone number has to be verified again
+	if data.PhoneNumber != nil && *data.PhoneNumber != user.PhoneNumber {
+		isPhoneVerified := false
+		update.IsPhoneVerified = &isPhoneVerified
+	}
+
 	if data.Password != nil {
-		passwordHash, err := security.HashString(*data.Password)
+		// The roles the user is given decide how many passwords can not be reused
+		historyUser := user
+		if data.Roles != nil {
+			historyUser.Roles = *data.Roles
+		}
+
+		err = s.checkPasswordHistory(ctx, historyUser, *data.Password)
 		if err != nil {
-			s.log.Error("bcrypt: hashing password", logger.Err(err))
+			return err
+		}
 
-			return model.ErrBcrypt
+		passwordHash, err := s.passwordHasher.Hash(*data.Password)
+		if err != nil {
+			s.log.ErrorContext(ctx, "password hasher: hashing password", logger.Err(err))
+
+			return model.ErrPasswordHash
 		}
 		update.PasswordHash = &passwordHash
+		update.PasswordChangedAt = &update.UpdatedAt
 	}
 
 	err = s.userRepo.Update(ctx, filter, update)
@@ -159,10 +246,30 @@ func (s *UserService) Update(ctx context.Context, filter model.UserFilter, data
 				"email": model.ErrDuplicateEmail,
 			}
 		}
+		if errors.Is(err, model.ErrDuplicatePhoneNumber) {
+			return model.ValidationErrors{
+				"phoneNumber": model.ErrDuplicatePhoneNumber,
+			}
+		}
+		if errors.Is(err, model.ErrInvalidRole) {
+			return model.ValidationErrors{
+				"role": model.ErrInvalidRole,
+			}
+		}
 
 		return err
 	}
 
+	if update.PasswordHash != nil {
+		s.recordPassword(ctx, user.ID, *update.PasswordHash)
+	}
+
+	// Tokens issued before a change of status, roles or password
+	// no longer describe the user correctly
+	if data.Password != nil || data.Roles != nil || data.IsActive != nil || data.IsConfirmed != nil {
+		return s.revokeUserTokens(ctx, user.ID)
+	}
+
 	return nil
 }
 
@@ -173,7 +280,90 @@ func (s *UserService) Delete(ctx context.Context, filter model.UserFilter) error
 	}
 	formatFilter(&filter)
 
-	return s.userRepo.Delete(ctx, filter)
+	user, err := s.FindOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.Delete(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	return s.revokeUserTokens(ctx, user.ID)
+}
+
+// UnlockAccount lifts the lockout of the user after failed logins and forgets the failures
+func (s *UserService) UnlockAccount(ctx context.Context, filter model.UserFilter) error {
+	err := checkQueryParams(s.validate, filter)
+	if err != nil {
+		return err
+	}
+	formatFilter(&filter)
+
+	user, err := s.FindOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	err = s.loginAttemptStorage.Reset(ctx, accountLoginSubject(user.ID).key)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"login attempt storage: resetting account failures",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// RequirePasswordChange makes the user change the password on the next login
+// and ends the sessions of the user, e.g. after an incident
+func (s *UserService) RequirePasswordChange(ctx context.Context, filter model.UserFilter) error {
+	err := checkQueryParams(s.validate, filter)
+	if err != nil {
+		return err
+	}
+	formatFilter(&filter)
+
+	user, err := s.FindOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	mustChangePassword := true
+
+	err = s.userRepo.Update(ctx, model.UserFilter{ID: &user.ID}, model.UserUpdate{
+		MustChangePassword: &mustChangePassword,
+		UpdatedAt:          time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.revokeUserTokens(ctx, user.ID)
+}
+
+// discardPassword clears the password hash of the user, which no password matches,
+// so only a password reset lets the user log in with a password again
+func (s *UserService) discardPassword(ctx context.Context, userID uint64) error {
+	passwordHash := []byte{}
+	mustChangePassword := true
+
+	err := s.userRepo.Update(ctx, model.UserFilter{ID: &userID}, model.UserUpdate{
+		PasswordHash:       &passwordHash,
+		MustChangePassword: &mustChangePassword,
+		UpdatedAt:          time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.revokeUserTokens(ctx, userID)
 }
 
 func (s *UserService) Me(ctx context.Context) (model.User, error) {
@@ -188,3 +378,211 @@ func (s *UserService) Me(ctx context.Context) (model.User, error) {
 
 	return s.userRepo.FindOne(ctx, filter)
 }
+
+// setPassword replaces the password of the user and revokes the tokens issued with the old one
+func (s *UserService) setPassword(ctx context.Context, userID uint64, password string) error {
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		s.log.ErrorContext(ctx, "password hasher: hashing password", logger.Err(err))
+
+		return model.ErrPasswordHash
+	}
+
+	now := time.Now()
+	mustChangePassword := false
+
+	err = s.userRepo.Update(ctx, model.UserFilter{ID: &userID}, model.UserUpdate{
+		PasswordHash:       &passwordHash,
+		UpdatedAt:          now,
+		PasswordChangedAt:  &now,
+		MustChangePassword: &mustChangePassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordPassword(ctx, userID, passwordHash)
+
+	return s.revokeUserTokens(ctx, userID)
+}
+
+// SetRolePasswordHistorySize sets how many recent passwords the users of the role can not reuse
+func (s *UserService) SetRolePasswordHistorySize(ctx context.Context, role model.Role, size int) error {
+	err := validateInput(s.validate, passwordHistorySizeValidation{Size: size})
+	if err != nil {
+		return err
+	}
+
+	err = s.roleRepo.SetPasswordHistorySize(ctx, role, size)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"role repository: setting password history size",
+			logger.Err(err),
+			slog.String("role", role.String()),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// SetRolePasswordMaxAge sets after how many days the passwords of the users of the role expire.
+// Zero days turn the expiry off
+func (s *UserService) SetRolePasswordMaxAge(ctx context.Context, role model.Role, days int) error {
+	err := validateInput(s.validate, passwordMaxAgeValidation{Days: days})
+	if err != nil {
+		return err
+	}
+
+	err = s.roleRepo.SetPasswordMaxAge(ctx, role, days)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"role repository: setting password max age",
+			logger.Err(err),
+			slog.String("role", role.String()),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// passwordChangeRequired reports whether the user was flagged to change the password
+// or the password is older than the shortest max age of the roles of the user
+func (s *UserService) passwordChangeRequired(ctx context.Context, user model.User) (bool, error) {
+	if user.MustChangePassword {
+		return true, nil
+	}
+
+	days, err := s.roleRepo.PasswordMaxAge(ctx, user.Roles)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"role repository: getting password max age",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return false, err
+	}
+	if days == 0 {
+		return false, nil
+	}
+
+	return time.Now().After(user.PasswordChangedAt.AddDate(0, 0, days)), nil
+}
+
+// checkPasswordHistory rejects the current password of the user and the recent ones,
+// as many as the largest password history size of the roles of the user
+func (s *UserService) checkPasswordHistory(ctx context.Context, user model.User, password string) error {
+	size, err := s.roleRepo.PasswordHistorySize(ctx, user.Roles)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"role repository: getting password history size",
+			logger.Err(err),
+			slog.Uint64("userId", user.ID),
+		)
+
+		return err
+	}
+
+	// The current password is rejected even when no history is kept,
+	// passwords set before the history was kept are only known by it too
+	hashes := [][]byte{user.PasswordHash}
+
+	if size > 0 {
+		recent, err := s.passwordHistoryRepo.FindRecent(ctx, user.ID, size)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"password history repository: finding recent passwords",
+				logger.Err(err),
+				slog.Uint64("userId", user.ID),
+			)
+
+			return err
+		}
+
+		hashes = append(hashes, recent...)
+	}
+
+	for _, hash := range hashes {
+		if s.passwordHasher.Verify(password, hash) == nil {
+			return model.ValidationErrors{
+				"password": model.ErrPasswordReused,
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordPassword adds a new password hash of the user to the password history.
+// The password is already set, a failure is only logged
+func (s *UserService) recordPassword(ctx context.Context, userID uint64, passwordHash []byte) {
+	err := s.passwordHistoryRepo.Insert(ctx, userID, passwordHash, time.Now())
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"password history repository: inserting password",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+	}
+}
+
+// checkPassword compares the password with the password hash of the user
+func (s *UserService) checkPassword(user model.User, password string) error {
+	return s.passwordHasher.Verify(password, user.PasswordHash)
+}
+
+// upgradePasswordHash rehashes a verified password of the user when its hash was made
+// with an outdated algorithm or parameters. The hash is only replaced if the password
+// was not changed in the meantime. Failures are logged, the old hash keeps working
+func (s *UserService) upgradePasswordHash(ctx context.Context, user model.User, password string) {
+	if !s.passwordHasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		s.log.ErrorContext(ctx, "password hasher: rehashing password", logger.Err(err), slog.Uint64("userId", user.ID))
+
+		return
+	}
+
+	err = s.userRepo.Update(
+		ctx,
+		model.UserFilter{ID: &user.ID, PasswordHash: &user.PasswordHash},
+		model.UserUpdate{
+			PasswordHash: &passwordHash,
+			UpdatedAt:    time.Now(),
+		},
+	)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		s.log.ErrorContext(ctx, "sql: upgrading password hash", logger.Err(err), slog.Uint64("userId", user.ID))
+
+		return
+	}
+}
+
+func (s *UserService) revokeUserTokens(ctx context.Context, userID uint64) error {
+	err := s.tokenRevocationStorage.RevokeUserTokens(ctx, userID, time.Now())
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"token revocation storage: revoking user tokens",
+			logger.Err(err),
+			slog.Uint64("userId", userID),
+		)
+
+		return err
+	}
+
+	return nil
+}
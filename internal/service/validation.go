@@ -11,14 +11,95 @@ type refreshTokenValidation struct {
 }
 
 type queryParamsValidation struct {
-	ID    uint64 `validate:"required_without=Email"`
-	Email string `validate:"required_without=ID"`
+	ID          uint64 `validate:"required_without_all=Email PhoneNumber"`
+	Email       string `validate:"required_without_all=ID PhoneNumber"`
+	PhoneNumber string `validate:"required_without_all=ID Email"`
 }
 
 type activationCodeValidation struct {
 	Code string `validate:"required,len=6,alphanum,uppercase"`
 }
 
+type emailChangeValidation struct {
+	Email string `validate:"required,email"`
+}
+
+type emailChangeCodeValidation struct {
+	Code string `validate:"required,len=6,alphanum,uppercase"`
+}
+
+type emailChangeCancelValidation struct {
+	CancelToken string `validate:"required"`
+}
+
+type phoneVerificationCodeValidation struct {
+	Code string `validate:"required,len=6,alphanum,uppercase"`
+}
+
+type mfaTokenValidation struct {
+	MfaToken string `validate:"required,jwt"`
+}
+
+type mfaCodeValidation struct {
+	Code string `validate:"required,max=16"`
+}
+
+type clientCredentialsValidation struct {
+	ClientID     string `validate:"required,max=64"`
+	ClientSecret string `validate:"required"`
+}
+
+type impersonationValidation struct {
+	UserID uint64 `validate:"required"`
+	Reason string `validate:"required,min=10,max=500"`
+}
+
+type sessionIDValidation struct {
+	SessionID string `validate:"required,max=64"`
+}
+
+type knownDeviceIDValidation struct {
+	DeviceID uint64 `validate:"required"`
+}
+
+type loginAlertTokenValidation struct {
+	Token string `validate:"required,jwt"`
+}
+
+type loginHistoryValidation struct {
+	PageSize  int    `validate:"gte=0,lte=100"`
+	PageToken string `validate:"omitempty,max=20"`
+}
+
+type introspectionValidation struct {
+	Token string `validate:"required"`
+}
+
+type passwordHistorySizeValidation struct {
+	Size int `validate:"gte=0,lte=24"`
+}
+
+type passwordMaxAgeValidation struct {
+	Days int `validate:"gte=0,lte=365"`
+}
+
+type rolePermissionValidation struct {
+	Permission string `validate:"required,max=100"`
+}
+
+type loginCodeRequestValidation struct {
+	Email string `validate:"required,email"`
+}
+
+type passwordResetRequestValidation struct {
+	Email string `validate:"required,email"`
+}
+
+type passwordResetValidation struct {
+	Token    string `validate:"required"`
+	Password string `validate:"required,min=8,max=20,complex_password,not_breached"`
+}
+
 func validateInput(v *validator.Validate, input any) error {
 	err := v.Struct(input)
 	if err == nil {
@@ -52,6 +133,9 @@ func checkQueryParams(v *validator.Validate, filter model.UserFilter) error {
 	if filter.Email != nil {
 		queryParams.Email = *filter.Email
 	}
+	if filter.PhoneNumber != nil {
+		queryParams.PhoneNumber = *filter.PhoneNumber
+	}
 
 	return validateInput(v, queryParams)
 }
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,
+    private_key bytea NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
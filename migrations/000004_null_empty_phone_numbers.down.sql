@@ -0,0 +1 @@
+-- Empty phone numbers can not be restored without violating users_phone_number_key
@@ -0,0 +1 @@
+UPDATE users SET phone_number = NULL WHERE phone_number = '';
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS is_phone_verified;
\ No newline at end of file
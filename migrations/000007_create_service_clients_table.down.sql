@@ -0,0 +1 @@
+DROP TABLE IF EXISTS service_clients;
\ No newline at end of file
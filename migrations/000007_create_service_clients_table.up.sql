@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS service_clients (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    secret_hash bytea NOT NULL,
+    allowed_methods TEXT[] NOT NULL,
+    is_active BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
\ No newline at end of file
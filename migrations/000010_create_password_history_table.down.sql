@@ -0,0 +1,3 @@
+ALTER TABLE roles DROP COLUMN IF EXISTS password_history_size;
+
+DROP TABLE IF EXISTS password_history;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS password_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    password_hash bytea NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id_created_at ON password_history(user_id, created_at DESC);
+
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS password_history_size INTEGER NOT NULL DEFAULT 0;
+
+UPDATE roles SET password_history_size = 5 WHERE name <> 'user';
\ No newline at end of file
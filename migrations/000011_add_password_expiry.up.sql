@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS password_max_age_days INTEGER NOT NULL DEFAULT 0;
+
+UPDATE roles SET password_max_age_days = 90 WHERE name IN ('admin', 'finance_manager');
\ No newline at end of file
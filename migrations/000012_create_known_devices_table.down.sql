@@ -0,0 +1 @@
+DROP TABLE IF EXISTS known_devices;
\ No newline at end of file
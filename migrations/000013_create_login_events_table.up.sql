@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS login_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    method VARCHAR(32) NOT NULL,
+    outcome VARCHAR(32) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user_id_id ON login_events(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_login_events_created_at ON login_events(created_at);
\ No newline at end of file
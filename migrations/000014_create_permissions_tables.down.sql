@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_role_permissions_permission_id;
+
+DROP TABLE IF EXISTS role_permissions;
+
+DROP TABLE IF EXISTS permissions;
+
+ALTER TABLE roles ALTER COLUMN id DROP DEFAULT;
+
+DROP SEQUENCE IF EXISTS roles_id_seq;
\ No newline at end of file
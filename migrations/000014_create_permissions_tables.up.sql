@@ -0,0 +1,81 @@
+CREATE SEQUENCE IF NOT EXISTS roles_id_seq OWNED BY roles.id;
+
+SELECT setval('roles_id_seq', (SELECT MAX(id) FROM roles));
+
+ALTER TABLE roles ALTER COLUMN id SET DEFAULT nextval('roles_id_seq');
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL,
+    permission_id INTEGER NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (name)
+VALUES ('account.manage'),
+       ('account.activate'),
+       ('profile.read'),
+       ('login_history.read'),
+       ('login_history.read_any'),
+       ('tokens.introspect'),
+       ('users.impersonate'),
+       ('users.create'),
+       ('users.read'),
+       ('users.read_any'),
+       ('users.list'),
+       ('users.update'),
+       ('users.update_any'),
+       ('users.delete'),
+       ('users.unlock'),
+       ('users.require_password_change'),
+       ('roles.manage'),
+       ('service_clients.manage'),
+       ('api_keys.manage');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON (r.name, p.name) IN (
+    ('user', 'account.manage'),
+    ('user', 'account.activate'),
+    ('user', 'profile.read'),
+    ('user', 'login_history.read'),
+    ('user', 'users.read'),
+    ('user', 'users.update'),
+    ('admin', 'account.manage'),
+    ('admin', 'account.activate'),
+    ('admin', 'profile.read'),
+    ('admin', 'login_history.read'),
+    ('admin', 'login_history.read_any'),
+    ('admin', 'users.impersonate'),
+    ('admin', 'users.create'),
+    ('admin', 'users.read'),
+    ('admin', 'users.read_any'),
+    ('admin', 'users.list'),
+    ('admin', 'users.update'),
+    ('admin', 'users.update_any'),
+    ('admin', 'users.delete'),
+    ('admin', 'users.unlock'),
+    ('admin', 'users.require_password_change'),
+    ('admin', 'roles.manage'),
+    ('admin', 'service_clients.manage'),
+    ('admin', 'api_keys.manage'),
+    ('tech_support', 'account.manage'),
+    ('tech_support', 'login_history.read'),
+    ('tech_support', 'login_history.read_any'),
+    ('tech_support', 'users.impersonate'),
+    ('tech_support', 'users.read'),
+    ('tech_support', 'users.read_any'),
+    ('finance_manager', 'account.manage'),
+    ('finance_manager', 'login_history.read'),
+    ('maintenance_specialist', 'account.manage'),
+    ('maintenance_specialist', 'login_history.read')
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_permissions_permission_id ON role_permissions(permission_id);
\ No newline at end of file